@@ -0,0 +1,104 @@
+package betterpem
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+var ErrNoMoreObjects = errors.New("no parsed pems remain to be consumed")
+
+// Returned when the next object isn't of the type asked for
+type WrongTypeError struct {
+	// The type asked for, such as "*x509.Certificate"
+	Want string
+	// The object which was next
+	Got interface{}
+}
+
+func (e *WrongTypeError) Error() string {
+	return fmt.Sprintf("%T is not an %s", e.Got, e.Want)
+}
+
+// These accessors mirror Interface and the Must functions, but return an
+// error rather than panicking so input can't crash the caller.  When the
+// next object is of the wrong type, it is not consumed.  One which fails
+// to parse is consumed, as it is by Next, so the rest can be reached.
+
+// Give the next object back in its typeless form, or ErrNoMoreObjects
+func (p *ParsedPEMs) Next() (interface{}, error) {
+	if len(p.objs) == 0 {
+		return nil, ErrNoMoreObjects
+	}
+	obj, err := p.resolve(0)
+	// a block which won't parse is consumed so the rest can be reached
+	p.advance()
+	return obj, err
+}
+
+// Return the PEM headers of the next object, or nil if it had none.
+//
+// The object is not consumed.
+func (p *ParsedPEMs) NextHeaders() (map[string]string, error) {
+	if len(p.objs) == 0 {
+		return nil, ErrNoMoreObjects
+	}
+	return p.Headers(), nil
+}
+
+func (p *ParsedPEMs) peek(want string, is func(interface{}) bool) error {
+	if len(p.objs) == 0 {
+		return ErrNoMoreObjects
+	}
+	obj, err := p.resolve(0)
+	if err != nil {
+		p.advance()
+		return err
+	}
+	if !is(obj) {
+		return &WrongTypeError{Want: want, Got: obj}
+	}
+	return nil
+}
+
+// Return the next object as a *x509.Certificate
+func (p *ParsedPEMs) Certificate() (*x509.Certificate, error) {
+	if err := p.peek("*x509.Certificate", func(obj interface{}) bool { _, ok := obj.(*x509.Certificate); return ok }); err != nil {
+		return nil, err
+	}
+	return p.MustCertificate(), nil
+}
+
+// Return the next object as a *rsa.PrivateKey
+func (p *ParsedPEMs) RSAPrivateKey() (*rsa.PrivateKey, error) {
+	if err := p.peek("*rsa.PrivateKey", func(obj interface{}) bool { _, ok := obj.(*rsa.PrivateKey); return ok }); err != nil {
+		return nil, err
+	}
+	return p.MustRSAPrivateKey(), nil
+}
+
+// Return the next object as a *ecdsa.PrivateKey
+func (p *ParsedPEMs) ECPrivateKey() (*ecdsa.PrivateKey, error) {
+	if err := p.peek("*ecdsa.PrivateKey", func(obj interface{}) bool { _, ok := obj.(*ecdsa.PrivateKey); return ok }); err != nil {
+		return nil, err
+	}
+	return p.MustECPrivateKey(), nil
+}
+
+// Return the next object as a *x509.CertificateRequest
+func (p *ParsedPEMs) CertificateRequest() (*x509.CertificateRequest, error) {
+	if err := p.peek("*x509.CertificateRequest", func(obj interface{}) bool { _, ok := obj.(*x509.CertificateRequest); return ok }); err != nil {
+		return nil, err
+	}
+	return p.MustCertificateRequest(), nil
+}
+
+// Return the next object as a *x509.RevocationList
+func (p *ParsedPEMs) RevocationList() (*x509.RevocationList, error) {
+	if err := p.peek("*x509.RevocationList", func(obj interface{}) bool { _, ok := obj.(*x509.RevocationList); return ok }); err != nil {
+		return nil, err
+	}
+	return p.MustRevocationList(), nil
+}
@@ -0,0 +1,74 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func TestAccessors(t *testing.T) {
+	objs, err := ParsePEMs(bytes.Join([][]byte{test_ca, test_rsakey, test_eckey, test_rsareq}, []byte{'\n'}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if _, err := objs.RSAPrivateKey(); err == nil {
+		t.Error("expected an error asking for the wrong type")
+	} else {
+		var werr *WrongTypeError
+		if !errors.As(err, &werr) || werr.Want != "*rsa.PrivateKey" {
+			t.Errorf("unexpected error %#v", err)
+		}
+	}
+	if objs.Length() != 4 {
+		t.Error("asking for the wrong type consumed the object")
+	}
+	if _, err := objs.Certificate(); err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+	if _, err := objs.RSAPrivateKey(); err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+	if _, err := objs.ECPrivateKey(); err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+	if _, err := objs.CertificateRequest(); err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+	if _, err := objs.Next(); err != ErrNoMoreObjects {
+		t.Errorf("expected ErrNoMoreObjects, got %#v", err)
+	}
+	if _, err := objs.NextHeaders(); err != ErrNoMoreObjects {
+		t.Errorf("expected ErrNoMoreObjects, got %#v", err)
+	}
+	if _, err := objs.RevocationList(); err != ErrNoMoreObjects {
+		t.Errorf("expected ErrNoMoreObjects, got %#v", err)
+	}
+}
+
+func TestNextHeadersNil(t *testing.T) {
+	objs, err := ParsePEMs(test_ca)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if headers := objs.Headers(); headers != nil {
+		t.Errorf("expected nil headers, got %#v", headers)
+	}
+	if headers, err := objs.NextHeaders(); err != nil || headers != nil {
+		t.Errorf("expected nil headers, got %#v, %#v", headers, err)
+	}
+}
+
+func TestAccessorsConsumeLazyFailures(t *testing.T) {
+	bad := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("not a key")})
+	objs, err := ParsePEMs(bytes.Join([][]byte{bad, test_rsakey}, nil), WithLazy())
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if _, err := objs.RSAPrivateKey(); !errors.Is(err, ErrBadBlock) || objs.Length() != 1 {
+		t.Errorf("expected the bad block's error and for it to be consumed, got %#v", err)
+	}
+	if _, err := objs.RSAPrivateKey(); err != nil {
+		t.Errorf("unexpected error for the key after the bad block %#v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package betterpem
+
+import (
+	"crypto"
+	"errors"
+)
+
+// Load a certificate exported from AWS Certificate Manager: the three
+// files, or the Certificate, CertificateChain, and PrivateKey members
+// of aws acm export-certificate's output, and the passphrase given to
+// the export.
+//
+// Each input may be anything ParsePEMs accepts.  The key is the PKCS #8
+// "ENCRYPTED PRIVATE KEY" ACM writes, which is decrypted with
+// passphrase.  The certificate must be for the key, or
+// ErrNoMatchingCertificate is returned.  The chain may be empty or nil,
+// and as with ExtractIdentity, its root is left out of the
+// intermediates.
+func LoadACMExport(cert, chain, encryptedKey interface{}, passphrase []byte) (Identity, error) {
+	certs, err := ParsePEMs(cert)
+	if err != nil {
+		return Identity{}, err
+	}
+	leaves := certs.certificates()
+	if len(leaves) == 0 {
+		return Identity{}, ErrNoCertificates
+	}
+	chainPEMs := ParsedPEMs{}
+	if chain != nil {
+		chainPEMs, err = ParsePEMs(chain)
+		if err != nil && !errors.Is(err, ErrNoPEMFound) {
+			return Identity{}, err
+		}
+	}
+	keys, err := ParsePEMs(encryptedKey, WithPassphraseFunc(func(BlockInfo) ([]byte, error) {
+		return passphrase, nil
+	}))
+	if err != nil {
+		return Identity{}, err
+	}
+	var key crypto.Signer
+	for _, obj := range keys.privateKeys() {
+		if signer, ok := obj.(crypto.Signer); ok {
+			key = signer
+			break
+		}
+	}
+	if key == nil {
+		return Identity{}, ErrNoPrivateKey
+	}
+	leaf := leaves[0]
+	if !certMatchesKey(leaf, key) {
+		return Identity{}, ErrNoMatchingCertificate
+	}
+	all := append(leaves, chainPEMs.certificates()...)
+	return Identity{Key: key, Leaf: leaf, Intermediates: chainFrom(leaf, all)}, nil
+}
@@ -0,0 +1,36 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+)
+
+func TestLoadACMExport(t *testing.T) {
+	keys, err := ParsePEMs(test_pbes2_sha256, WithPassphraseFunc(func(BlockInfo) ([]byte, error) { return []byte("hunter2"), nil }))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	key := keys.MustECPrivateKey()
+	c := newTestChain(t)
+	leaf := issueTestCertForKey(t, &x509.Certificate{Subject: pkix.Name{CommonName: "acm.example.com"}}, c.intermediate, c.intermediateKey, key)
+
+	identity, err := LoadACMExport(c.pem(t, leaf), c.pem(t, c.intermediate, c.root), test_pbes2_sha256, []byte("hunter2"))
+	if err != nil {
+		t.Fatalf("unexpected error loading export %#v", err)
+	}
+	if !identity.Leaf.Equal(leaf) || !key.Equal(identity.Key) {
+		t.Error("expected the exported certificate and key")
+	}
+	if len(identity.Intermediates) != 1 || !identity.Intermediates[0].Equal(c.intermediate) {
+		t.Errorf("expected the intermediate without the root, got %d certificates", len(identity.Intermediates))
+	}
+
+	if _, err := LoadACMExport(c.pem(t, leaf), nil, test_pbes2_sha256, []byte("wrong")); !errors.Is(err, x509.IncorrectPasswordError) {
+		t.Errorf("expected an incorrect password, got %#v", err)
+	}
+	if _, err := LoadACMExport(c.pem(t, c.leaf), nil, test_pbes2_sha256, []byte("hunter2")); err != ErrNoMatchingCertificate {
+		t.Errorf("expected ErrNoMatchingCertificate, got %#v", err)
+	}
+}
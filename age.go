@@ -0,0 +1,236 @@
+package betterpem
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidAgeKey = errors.New("invalid age identity or recipient")
+
+const (
+	ageRecipientPrefix = "age"
+	ageIdentityPrefix  = "AGE-SECRET-KEY-"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	ret := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		ret = append(ret, hrp[i]>>5)
+	}
+	ret = append(ret, 0)
+	for i := 0; i < len(hrp); i++ {
+		ret = append(ret, hrp[i]&31)
+	}
+	return ret
+}
+
+// Regroup bits, such as 8 bit bytes into 5 bit bech32 characters
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, bool) {
+	ret := []byte{}
+	acc, bits := uint32(0), uint(0)
+	for _, b := range data {
+		acc = acc<<from | uint32(b)
+		bits += from
+		for bits >= to {
+			bits -= to
+			ret = append(ret, byte(acc>>bits)&(1<<to-1))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			ret = append(ret, byte(acc<<(to-bits))&(1<<to-1))
+		}
+	} else if bits >= from || (acc<<(to-bits))&(1<<to-1) != 0 {
+		return nil, false
+	}
+	return ret, true
+}
+
+// Encode data as BIP 173 bech32, without its 90 character limit as age
+// does
+func bech32Encode(hrp string, data []byte) string {
+	values, _ := convertBits(data, 8, 5, true)
+	lower := strings.ToLower(hrp)
+	polymod := bech32Polymod(append(append(bech32HRPExpand(lower), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	var b strings.Builder
+	b.WriteString(lower + "1")
+	for _, v := range values {
+		b.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[(polymod>>(5*(5-i)))&31])
+	}
+	if hrp != lower {
+		return strings.ToUpper(b.String())
+	}
+	return b.String()
+}
+
+// Decode BIP 173 bech32, giving the human readable part in lower case
+func bech32Decode(s string) (string, []byte, bool) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, false
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, false
+	}
+	hrp := s[:sep]
+	values := []byte{}
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, false
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, false
+	}
+	data, ok := convertBits(values[:len(values)-6], 5, 8, false)
+	return hrp, data, ok
+}
+
+// The X25519 form of an Ed25519 public key: the Montgomery u coordinate
+// (1+y)/(1-y) of its point
+func ed25519PublicToX25519(pub ed25519.PublicKey) (*ecdh.PublicKey, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, ErrInvalidAgeKey
+	}
+	p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	le := make([]byte, len(pub))
+	for i, b := range pub {
+		le[len(pub)-1-i] = b
+	}
+	// the top bit is the sign of x
+	le[0] &= 0x7f
+	y := new(big.Int).SetBytes(le)
+	one := big.NewInt(1)
+	den := new(big.Int).Sub(one, y)
+	den.Mod(den, p)
+	if den.Sign() == 0 {
+		return nil, ErrInvalidAgeKey
+	}
+	u := new(big.Int).Add(one, y)
+	u.Mul(u, den.ModInverse(den, p))
+	u.Mod(u, p)
+	out := make([]byte, 32)
+	for i, b := range u.FillBytes(make([]byte, 32)) {
+		out[31-i] = b
+	}
+	return ecdh.X25519().NewPublicKey(out)
+}
+
+// The X25519 form of an Ed25519 private key: the scalar Ed25519 derives
+// from its seed
+func ed25519PrivateToX25519(key ed25519.PrivateKey) (*ecdh.PrivateKey, error) {
+	h := sha512.Sum512(key.Seed())
+	// X25519 clamps the scalar itself
+	return ecdh.X25519().NewPrivateKey(h[:32])
+}
+
+// Return the age recipient ("age1...") for a key, so files can be
+// encrypted to the holder of an existing PEM key with age.
+//
+// The key may be an X25519 *ecdh.PublicKey or *ecdh.PrivateKey, or an
+// Ed25519 public or private key, which is converted to X25519 the usual
+// way.  The age identity for the matching private key comes from
+// AgeIdentity.  An Ed25519 key converted this way is a native age
+// recipient, unlike the same key given to age as an ssh-ed25519 one.
+func AgeRecipient(key interface{}) (string, error) {
+	var pub *ecdh.PublicKey
+	switch k := key.(type) {
+	case *ecdh.PublicKey:
+		pub = k
+	case *ecdh.PrivateKey:
+		pub = k.PublicKey()
+	case ed25519.PublicKey:
+		var err error
+		if pub, err = ed25519PublicToX25519(k); err != nil {
+			return "", err
+		}
+	case ed25519.PrivateKey:
+		return AgeRecipient(k.Public())
+	case *ed25519.PrivateKey:
+		return AgeRecipient(k.Public())
+	default:
+		return "", ErrCannotEncode
+	}
+	if pub.Curve() != ecdh.X25519() {
+		return "", ErrCannotEncode
+	}
+	return bech32Encode(ageRecipientPrefix, pub.Bytes()), nil
+}
+
+// Return the age identity ("AGE-SECRET-KEY-1...") for an X25519
+// *ecdh.PrivateKey or an Ed25519 private key, converted to X25519 as
+// AgeRecipient converts its public key.
+func AgeIdentity(key interface{}) (string, error) {
+	var priv *ecdh.PrivateKey
+	switch k := key.(type) {
+	case *ecdh.PrivateKey:
+		priv = k
+	case ed25519.PrivateKey:
+		var err error
+		if priv, err = ed25519PrivateToX25519(k); err != nil {
+			return "", err
+		}
+	case *ed25519.PrivateKey:
+		return AgeIdentity(*k)
+	default:
+		return "", ErrCannotEncode
+	}
+	if priv.Curve() != ecdh.X25519() {
+		return "", ErrCannotEncode
+	}
+	return bech32Encode(ageIdentityPrefix, priv.Bytes()), nil
+}
+
+// Parse an age identity ("AGE-SECRET-KEY-1...") into its X25519 key,
+// which EncodePEM writes as PKCS#8
+func ParseAgeIdentity(identity string) (*ecdh.PrivateKey, error) {
+	hrp, data, ok := bech32Decode(strings.TrimSpace(identity))
+	if !ok || hrp != strings.ToLower(ageIdentityPrefix) {
+		return nil, ErrInvalidAgeKey
+	}
+	key, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, ErrInvalidAgeKey
+	}
+	return key, nil
+}
+
+// Parse an age recipient ("age1...") into its X25519 key
+func ParseAgeRecipient(recipient string) (*ecdh.PublicKey, error) {
+	hrp, data, ok := bech32Decode(strings.TrimSpace(recipient))
+	if !ok || hrp != ageRecipientPrefix {
+		return nil, ErrInvalidAgeKey
+	}
+	key, err := ecdh.X25519().NewPublicKey(data)
+	if err != nil {
+		return nil, ErrInvalidAgeKey
+	}
+	return key, nil
+}
@@ -0,0 +1,81 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+)
+
+func TestAgeX25519(t *testing.T) {
+	// the key age's own test vectors use
+	key, err := ecdh.X25519().NewPrivateKey(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := AgeIdentity(key)
+	if err != nil {
+		t.Fatalf("unexpected error encoding identity %#v", err)
+	}
+	if identity != "AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX" {
+		t.Errorf("unexpected identity %s", identity)
+	}
+	recipient, err := AgeRecipient(key)
+	if err != nil {
+		t.Fatalf("unexpected error encoding recipient %#v", err)
+	}
+	if recipient != "age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj" {
+		t.Errorf("unexpected recipient %s", recipient)
+	}
+
+	parsed, err := ParseAgeIdentity(identity)
+	if err != nil || !parsed.Equal(key) {
+		t.Errorf("expected the identity to parse back to the key, got %#v", err)
+	}
+	pub, err := ParseAgeRecipient(recipient)
+	if err != nil || !pub.Equal(key.PublicKey()) {
+		t.Errorf("expected the recipient to parse back to the public key, got %#v", err)
+	}
+	if _, err := ParseAgeRecipient(identity); err != ErrInvalidAgeKey {
+		t.Errorf("expected ErrInvalidAgeKey for an identity given as a recipient, got %#v", err)
+	}
+	if _, err := ParseAgeIdentity(identity[:len(identity)-1] + "Q"); err != ErrInvalidAgeKey {
+		t.Errorf("expected ErrInvalidAgeKey for a bad checksum, got %#v", err)
+	}
+}
+
+func TestAgeEd25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := AgeIdentity(priv)
+	if err != nil {
+		t.Fatalf("unexpected error encoding identity %#v", err)
+	}
+	recipient, err := AgeRecipient(pub)
+	if err != nil {
+		t.Fatalf("unexpected error encoding recipient %#v", err)
+	}
+	key, err := ParseAgeIdentity(identity)
+	if err != nil {
+		t.Fatalf("unexpected error parsing identity %#v", err)
+	}
+	// the converted private key must be the private half of the
+	// converted public key
+	if derived, _ := AgeRecipient(key); derived != recipient {
+		t.Errorf("expected %s from the identity, got %s", recipient, derived)
+	}
+	if fromPriv, _ := AgeRecipient(priv); fromPriv != recipient {
+		t.Errorf("expected %s from the private key, got %s", recipient, fromPriv)
+	}
+
+	if _, err := AgeRecipient(newTestChain(t).leafKey); err != ErrCannotEncode {
+		t.Errorf("expected ErrCannotEncode for an ECDSA key, got %#v", err)
+	}
+	p256, _ := ecdh.P256().GenerateKey(rand.Reader)
+	if _, err := AgeIdentity(p256); err != ErrCannotEncode {
+		t.Errorf("expected ErrCannotEncode for a P-256 key, got %#v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var ErrNoAgentKey = errors.New("ssh-agent does not hold the key")
+var ErrAgentNeedsMessage = errors.New("ssh-agent signs messages, not digests; use SignMessage")
+
+// A key held in an ssh-agent
+type AgentKey struct {
+	// The key as betterpem parses it from PEM: an *rsa.PublicKey,
+	// *ecdsa.PublicKey, or ed25519.PublicKey.  It is nil for keys
+	// without a crypto equivalent.
+	PublicKey crypto.PublicKey
+	// The key as the agent gave it, which is an *ssh.Certificate for
+	// a certificate
+	SSHKey ssh.PublicKey
+	// The certificate, if the agent holds one for the key
+	Certificate *ssh.Certificate
+	Comment     string
+}
+
+// List the keys held by the ssh-agent on conn, usually a connection to
+// the socket named by SSH_AUTH_SOCK, in the agent's order.
+//
+// The public keys can be compared with those parsed from PEM, to find
+// which keys on disk are loaded into the agent.
+func ListAgentKeys(conn io.ReadWriter) ([]AgentKey, error) {
+	return agentKeys(agent.NewClient(conn))
+}
+
+func agentKeys(client agent.Agent) ([]AgentKey, error) {
+	keys, err := client.List()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]AgentKey, 0, len(keys))
+	for _, key := range keys {
+		pub, err := ssh.ParsePublicKey(key.Blob)
+		if err != nil {
+			return nil, err
+		}
+		found := AgentKey{SSHKey: pub, Comment: key.Comment}
+		if cert, ok := pub.(*ssh.Certificate); ok {
+			found.Certificate = cert
+			pub = cert.Key
+		}
+		found.PublicKey, _ = publicKeyOf(pub)
+		ret = append(ret, found)
+	}
+	return ret, nil
+}
+
+// A crypto.Signer for a key held in an ssh-agent, so keys which never
+// leave the agent can sign for TLS, JWTs, or anything else taking a
+// crypto.Signer
+type AgentSigner struct {
+	agent agent.ExtendedAgent
+	key   ssh.PublicKey
+	pub   crypto.PublicKey
+}
+
+// Make an AgentSigner for the key in the ssh-agent on conn matching pub,
+// which may be a public key, a private key, a certificate, or anything
+// else a public key can be derived from.  ErrNoAgentKey is returned if
+// the agent doesn't hold it.
+func NewAgentSigner(conn io.ReadWriter, pub interface{}) (*AgentSigner, error) {
+	want, err := publicKeyOf(pub)
+	if err != nil {
+		return nil, err
+	}
+	client := agent.NewClient(conn)
+	keys, err := agentKeys(client)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.Certificate == nil && key.PublicKey != nil && publicKeysEqual(key.PublicKey, want) {
+			return &AgentSigner{agent: client, key: key.SSHKey, pub: key.PublicKey}, nil
+		}
+	}
+	return nil, ErrNoAgentKey
+}
+
+func (s *AgentSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// Sign a digest, as crypto.Signer does.
+//
+// An agent only signs whole messages, which it hashes itself, so this
+// can only sign with Ed25519 keys, whose "digest" is the message.  Other
+// keys give ErrAgentNeedsMessage; use SignMessage for them.
+func (s *AgentSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if _, ok := s.pub.(ed25519.PublicKey); !ok || opts.HashFunc() != 0 {
+		return nil, ErrAgentNeedsMessage
+	}
+	return s.SignMessage(rand, digest, opts)
+}
+
+// Hash and sign a message, with the signature in the form crypto.Signer
+// gives: PKCS #1 v1.5 for RSA, ASN.1 for ECDSA, and raw for Ed25519.
+//
+// RSA keys can sign with SHA-1, SHA-256, or SHA-512, but not PSS.  ECDSA
+// keys can only sign with the hash SSH uses for their curve, such as
+// SHA-256 for P-256.
+func (s *AgentSigner) SignMessage(rand io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var flags agent.SignatureFlags
+	switch pub := s.pub.(type) {
+	case *rsa.PublicKey:
+		if _, pss := opts.(*rsa.PSSOptions); pss {
+			return nil, errors.New("ssh-agent cannot make RSA-PSS signatures")
+		}
+		switch opts.HashFunc() {
+		case crypto.SHA1:
+		case crypto.SHA256:
+			flags = agent.SignatureFlagRsaSha256
+		case crypto.SHA512:
+			flags = agent.SignatureFlagRsaSha512
+		default:
+			return nil, fmt.Errorf("ssh-agent cannot make RSA signatures with %v", opts.HashFunc())
+		}
+	case *ecdsa.PublicKey:
+		want := map[elliptic.Curve]crypto.Hash{
+			elliptic.P256(): crypto.SHA256,
+			elliptic.P384(): crypto.SHA384,
+			elliptic.P521(): crypto.SHA512,
+		}[pub.Curve]
+		if opts.HashFunc() != want {
+			return nil, fmt.Errorf("ssh-agent signs with %v for this curve, not %v", want, opts.HashFunc())
+		}
+	case ed25519.PublicKey:
+		if opts.HashFunc() != 0 {
+			return nil, errors.New("ssh-agent cannot make Ed25519ph signatures")
+		}
+	}
+	sig, err := s.agent.SignWithFlags(s.key, message, flags)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.pub.(*ecdsa.PublicKey); !ok {
+		return sig.Blob, nil
+	}
+	// SSH's ECDSA signatures are two mpints rather than ASN.1
+	var rs struct {
+		R, S *big.Int
+	}
+	if err := ssh.Unmarshal(sig.Blob, &rs); err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(rs)
+}
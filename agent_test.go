@@ -0,0 +1,113 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"net"
+	"testing"
+
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Connect to an in-memory agent holding keys
+func newTestAgent(t *testing.T, keys ...interface{}) net.Conn {
+	keyring := agent.NewKeyring()
+	for _, key := range keys {
+		if err := keyring.Add(agent.AddedKey{PrivateKey: key, Comment: "test key"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client, server := net.Pipe()
+	go agent.ServeAgent(keyring, server)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client
+}
+
+func TestListAgentKeys(t *testing.T) {
+	c := newTestChain(t)
+	pems, err := ParsePEMs(test_rsakey)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	rsaKey := pems.MustRSAPrivateKey()
+	conn := newTestAgent(t, c.leafKey, rsaKey)
+	keys, err := ListAgentKeys(conn)
+	if err != nil {
+		t.Fatalf("unexpected error listing keys %#v", err)
+	}
+	if len(keys) != 2 || keys[0].Comment != "test key" {
+		t.Fatalf("unexpected keys %#v", keys)
+	}
+	if !publicKeysEqual(keys[0].PublicKey, c.leafKey.Public()) || !publicKeysEqual(keys[1].PublicKey, &rsaKey.PublicKey) {
+		t.Error("expected the agent's keys to match the keys added")
+	}
+}
+
+func TestAgentSigner(t *testing.T) {
+	c := newTestChain(t)
+	ecKey := c.leafKey.(*ecdsa.PrivateKey)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pems, err := ParsePEMs(test_rsakey)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	rsaKey := pems.MustRSAPrivateKey()
+	conn := newTestAgent(t, ecKey, edKey, rsaKey)
+	message := []byte("signed by the agent")
+	digest := sha256.Sum256(message)
+
+	// certificates find their key too
+	signer, err := NewAgentSigner(conn, c.leaf)
+	if err != nil {
+		t.Fatalf("unexpected error finding key %#v", err)
+	}
+	sig, err := signer.SignMessage(rand.Reader, message, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("unexpected error signing %#v", err)
+	}
+	if !ecdsa.VerifyASN1(&ecKey.PublicKey, digest[:], sig) {
+		t.Error("expected a valid ECDSA signature")
+	}
+	if _, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256); err != ErrAgentNeedsMessage {
+		t.Errorf("expected ErrAgentNeedsMessage, got %#v", err)
+	}
+
+	signer, err = NewAgentSigner(conn, rsaKey)
+	if err != nil {
+		t.Fatalf("unexpected error finding key %#v", err)
+	}
+	sig, err = signer.SignMessage(rand.Reader, message, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("unexpected error signing %#v", err)
+	}
+	if err := rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("expected a valid RSA signature, got %#v", err)
+	}
+
+	signer, err = NewAgentSigner(conn, edKey.Public())
+	if err != nil {
+		t.Fatalf("unexpected error finding key %#v", err)
+	}
+	var _ crypto.Signer = signer
+	sig, err = signer.Sign(rand.Reader, message, crypto.Hash(0))
+	if err != nil {
+		t.Fatalf("unexpected error signing %#v", err)
+	}
+	if !ed25519.Verify(edKey.Public().(ed25519.PublicKey), message, sig) {
+		t.Error("expected a valid Ed25519 signature")
+	}
+
+	if _, err := NewAgentSigner(conn, c.root); err != ErrNoAgentKey {
+		t.Errorf("expected ErrNoAgentKey, got %#v", err)
+	}
+}
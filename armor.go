@@ -0,0 +1,46 @@
+package betterpem
+
+import (
+	"encoding/pem"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidTypeLabel = errors.New("pem type label is empty or contains invalid characters")
+var ErrNoMatchingBlock = errors.New("no pem block with the requested type was found")
+
+// Wrap arbitrary data in a PEM block with the given type label.
+//
+// This lets applications store their own binary payloads with PEM framing.
+// Headers are optional.  See EncodePEM for the rules on headers and
+// options.
+func Armor(typeLabel string, data []byte, headers map[string]string, opts ...EncodeOption) ([]byte, error) {
+	if typeLabel == "" || strings.Contains(typeLabel, "-----") || strings.ContainsAny(typeLabel, "\r\n") {
+		return nil, ErrInvalidTypeLabel
+	}
+	return encodeBlocks([]*pem.Block{{Type: typeLabel, Headers: headers, Bytes: data}}, opts)
+}
+
+// Find the first PEM block with the given type label and return its data
+// and headers.
+//
+// Input may be anything ParsePEMs accepts.  Blocks of other types, and
+// anything between blocks, are skipped.  If no block has the type label,
+// ErrNoMatchingBlock is returned.
+func Dearmor(pemInt interface{}, typeLabel string) ([]byte, map[string]string, error) {
+	input, err := intoBytes(pemInt)
+	if err != nil {
+		return nil, nil, err
+	}
+	scan := newScanner(input, false)
+	for {
+		found, ok := scan.next()
+		if !ok {
+			return nil, nil, ErrNoMatchingBlock
+		}
+		block := found.Block
+		if block.Type == typeLabel {
+			return block.Bytes, block.Headers, nil
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package betterpem
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestArmor(t *testing.T) {
+	payload := []byte{0, 1, 2, 3, 0xff, 'h', 'i'}
+	armored, err := Armor("MY APP TOKEN", payload, map[string]string{"Version": "1"})
+	if err != nil {
+		t.Fatalf("unexpected error armoring %#v", err)
+	}
+	input := bytes.Join([][]byte{test_eccert, []byte("some junk"), armored}, []byte{'\n'})
+
+	data, headers, err := Dearmor(input, "MY APP TOKEN")
+	if err != nil {
+		t.Fatalf("unexpected error dearmoring %#v", err)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Errorf("payload %x did not survive the round trip", data)
+	}
+	if headers["Version"] != "1" {
+		t.Errorf("headers did not survive the round trip: %#v", headers)
+	}
+
+	if _, _, err := Dearmor(input, "SOMETHING ELSE"); err != ErrNoMatchingBlock {
+		t.Errorf("expected ErrNoMatchingBlock, got %#v", err)
+	}
+	if _, err := Armor("BAD-----LABEL", payload, nil); err != ErrInvalidTypeLabel {
+		t.Errorf("expected ErrInvalidTypeLabel, got %#v", err)
+	}
+}
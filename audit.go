@@ -0,0 +1,140 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// What sort of problem an Audit finding is
+type FindingKind string
+
+const (
+	FindingWeakRSAKey        FindingKind = "weak-rsa-key"
+	FindingWeakSignature     FindingKind = "weak-signature"
+	FindingExpired           FindingKind = "expired"
+	FindingNotYetValid       FindingKind = "not-yet-valid"
+	FindingSerialReuse       FindingKind = "serial-reuse"
+	FindingWeakDHParams      FindingKind = "weak-dh-parameters"
+	FindingSubjectKeyIdReuse FindingKind = "subject-key-id-reuse"
+)
+
+const (
+	defaultMinRSABits = 2048
+	defaultMinDHBits  = 2048
+)
+
+// One problem Audit found with an object in a bundle
+type Finding struct {
+	Kind FindingKind
+	// The certificate, key, or parameters the finding is about
+	Object  interface{}
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Kind, f.Message)
+}
+
+// Thresholds for Audit.  The zero value audits against the defaults as of
+// the current time.
+type AuditOptions struct {
+	// RSA keys smaller than this are weak, 2048 bits when zero
+	MinRSABits int
+	// DH parameters smaller than this are weak, 2048 bits when zero
+	MinDHBits int
+	// The time validity is checked at, the parsed PEMs' clock when zero;
+	// see WithClock
+	Now time.Time
+}
+
+var weakSignatureAlgorithms = map[x509.SignatureAlgorithm]bool{
+	x509.MD2WithRSA:    true,
+	x509.MD5WithRSA:    true,
+	x509.SHA1WithRSA:   true,
+	x509.DSAWithSHA1:   true,
+	x509.ECDSAWithSHA1: true,
+}
+
+func describeObject(obj interface{}) string {
+	switch v := obj.(type) {
+	case *x509.Certificate:
+		return "certificate " + v.Subject.String()
+	case *x509.CertificateRequest:
+		return "certificate request " + v.Subject.String()
+	case *DHParameters:
+		return "DH parameters"
+	}
+	return "key"
+}
+
+// Check every parsed PEM remaining to be consumed for common hygiene
+// problems: RSA keys and DH parameters below the configured sizes,
+// MD5 and SHA-1 signatures, certificates which are expired or not yet
+// valid, and collisions between certificates; see Collisions.
+//
+// Signatures on self-issued certificates aren't checked since nothing
+// relies on them.  Findings are returned in the order of the objects
+// they are about, followed by collisions, and are empty when nothing
+// was found.  The parsed PEMs are not consumed.
+func Audit(pems ParsedPEMs, opts AuditOptions) []Finding {
+	if opts.MinRSABits == 0 {
+		opts.MinRSABits = defaultMinRSABits
+	}
+	if opts.MinDHBits == 0 {
+		opts.MinDHBits = defaultMinDHBits
+	}
+	if opts.Now.IsZero() {
+		opts.Now = pems.now()
+	}
+	findings := []Finding{}
+	add := func(kind FindingKind, obj interface{}, format string, args ...interface{}) {
+		findings = append(findings, Finding{
+			Kind:    kind,
+			Object:  obj,
+			Message: describeObject(obj) + " " + fmt.Sprintf(format, args...),
+		})
+	}
+	for _, obj := range pems.all() {
+		if dh, ok := obj.(*DHParameters); ok {
+			if dh.Bits() < opts.MinDHBits {
+				add(FindingWeakDHParams, obj, "has a %d bit prime, below %d bits", dh.Bits(), opts.MinDHBits)
+			}
+			continue
+		}
+		if pub, err := publicKeyOf(obj); err == nil {
+			if rsaPub, ok := pub.(*rsa.PublicKey); ok && rsaPub.N.BitLen() < opts.MinRSABits {
+				add(FindingWeakRSAKey, obj, "has a %d bit RSA key, below %d bits", rsaPub.N.BitLen(), opts.MinRSABits)
+			}
+		}
+		switch v := obj.(type) {
+		case *x509.CertificateRequest:
+			if weakSignatureAlgorithms[v.SignatureAlgorithm] {
+				add(FindingWeakSignature, obj, "is signed with %s", v.SignatureAlgorithm)
+			}
+		case *x509.Certificate:
+			if weakSignatureAlgorithms[v.SignatureAlgorithm] && !bytes.Equal(v.RawSubject, v.RawIssuer) {
+				add(FindingWeakSignature, obj, "is signed with %s", v.SignatureAlgorithm)
+			}
+			if opts.Now.After(v.NotAfter) {
+				add(FindingExpired, obj, "expired at %s", v.NotAfter.UTC().Format(time.RFC3339))
+			}
+			if opts.Now.Before(v.NotBefore) {
+				add(FindingNotYetValid, obj, "is not valid until %s", v.NotBefore.UTC().Format(time.RFC3339))
+			}
+		}
+	}
+	for _, c := range pems.Collisions() {
+		first := c.Certificates[0]
+		for _, cert := range c.Certificates[1:] {
+			if c.Kind == FindingSerialReuse {
+				add(c.Kind, cert, "reuses serial number %s from issuer %s", cert.SerialNumber, cert.Issuer)
+			} else {
+				add(c.Kind, cert, "reuses subject key identifier %s of %s", colonHex(cert.SubjectKeyId), first.Subject)
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,122 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func findingKinds(findings []Finding) map[FindingKind]int {
+	ret := map[FindingKind]int{}
+	for _, f := range findings {
+		ret[f.Kind]++
+	}
+	return ret
+}
+
+func TestAuditCleanChain(t *testing.T) {
+	c := newTestChain(t)
+	pems, err := ParsePEMs(c.pem(t, c.leaf, c.intermediate, c.root, c.leafKey))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if findings := Audit(pems, AuditOptions{}); len(findings) != 0 {
+		t.Errorf("unexpected findings %v", findings)
+	}
+	if pems.Length() != 4 {
+		t.Errorf("audit consumed the parsed PEMs")
+	}
+}
+
+func TestAuditFindings(t *testing.T) {
+	c := newTestChain(t)
+	expired, _ := issueTestCert(t, &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "expired"},
+		SerialNumber: big.NewInt(42),
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     time.Now().Add(-24 * time.Hour),
+	}, c.intermediate, c.intermediateKey)
+	early, _ := issueTestCert(t, &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "early"},
+		SerialNumber: big.NewInt(42),
+		NotBefore:    time.Now().Add(24 * time.Hour),
+		NotAfter:     time.Now().Add(48 * time.Hour),
+	}, c.intermediate, c.intermediateKey)
+	rsaKeys, err := ParsePEMs(test_rsakey)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	rsaKey := rsaKeys.MustRSAPrivateKey()
+	sha1 := issueTestCertForKey(t, &x509.Certificate{
+		Subject:            pkix.Name{CommonName: "sha1"},
+		SignatureAlgorithm: x509.SHA1WithRSA,
+	}, &x509.Certificate{Subject: pkix.Name{CommonName: "RSA Root"}}, rsaKey, rsaKey)
+	dh, err := asn1.Marshal(struct{ P, G *big.Int }{new(big.Int).Lsh(big.NewInt(1), 1023), big.NewInt(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := c.pem(t, expired, early, sha1, rsaKey)
+	bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: dh})...)
+	pems, err := ParsePEMs(bundle)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	kinds := findingKinds(Audit(pems, AuditOptions{}))
+	want := map[FindingKind]int{
+		FindingExpired:       1,
+		FindingNotYetValid:   1,
+		FindingSerialReuse:   1,
+		FindingWeakSignature: 1,
+		// the sha1 certificate and the key itself
+		FindingWeakRSAKey:   2,
+		FindingWeakDHParams: 1,
+	}
+	for kind, n := range want {
+		if kinds[kind] != n {
+			t.Errorf("expected %d %s findings, got %d", n, kind, kinds[kind])
+		}
+	}
+
+	kinds = findingKinds(Audit(pems, AuditOptions{MinRSABits: rsaKey.N.BitLen(), MinDHBits: 1024, Now: time.Now().Add(36 * time.Hour)}))
+	if kinds[FindingWeakRSAKey] != 0 || kinds[FindingWeakDHParams] != 0 {
+		t.Errorf("thresholds were not honoured: %v", kinds)
+	}
+	// by then the sha1 certificate has expired too
+	if kinds[FindingExpired] != 2 || kinds[FindingNotYetValid] != 0 {
+		t.Errorf("audit time was not honoured: %v", kinds)
+	}
+}
+
+func TestParseDHParameters(t *testing.T) {
+	der, err := asn1.Marshal(struct{ P, G *big.Int }{big.NewInt(23), big.NewInt(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pems, err := ParsePEMs(pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: der}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	dh, ok := pems.Interface().(*DHParameters)
+	if !ok || dh.Bits() != 5 || dh.G.Int64() != 5 {
+		t.Errorf("unexpected DH parameters %#v", dh)
+	}
+}
+
+func TestAuditDHParametersOnly(t *testing.T) {
+	der, err := asn1.Marshal(struct{ P, G *big.Int }{new(big.Int).Lsh(big.NewInt(1), 1023), big.NewInt(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pems, err := ParsePEMs(pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: der}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if kinds := findingKinds(Audit(pems, AuditOptions{})); kinds[FindingWeakDHParams] != 1 {
+		t.Errorf("expected a weak DH parameters finding, got %v", kinds)
+	}
+}
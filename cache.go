@@ -0,0 +1,76 @@
+package betterpem
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/pem"
+	"sync"
+)
+
+// Create a Parser which remembers what it parsed, for when the same
+// bundles are loaded on every request or every pass of a reconcile loop.
+//
+// Results are looked up by the SHA-256 hash of the input, and each call
+// gets its own copy of the ParsedPEMs to consume, though the objects in
+// it are shared between calls and must not be modified.  Errors are
+// remembered too.  Once maxEntries results are cached, the least
+// recently used is forgotten; maxEntries of zero or less caches without
+// limit.  Warnings are as of when the input was first parsed.
+//
+// WithInPlaceDecoding is ignored, since the results kept would share
+// memory with an input the caller may reuse.
+func NewCachingParser(maxEntries int, opts ...Option) *Parser {
+	p := NewParser(opts...)
+	p.cache = &parseCache{max: maxEntries, entries: map[[sha256.Size]byte]*list.Element{}, order: list.New()}
+	return p
+}
+
+type cachedParse struct {
+	key  [sha256.Size]byte
+	pems ParsedPEMs
+	err  error
+}
+
+// A least recently used cache of parse results
+type parseCache struct {
+	sync.Mutex
+	max     int
+	entries map[[sha256.Size]byte]*list.Element
+	// most recently used first
+	order *list.List
+}
+
+func (c *parseCache) parse(pemBytes []byte, o *parseOptions) (ParsedPEMs, error) {
+	key := sha256.Sum256(pemBytes)
+	c.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		r := e.Value.(*cachedParse)
+		c.Unlock()
+		return r.pems.clone(), r.err
+	}
+	c.Unlock()
+
+	pems, err := o.parse(pemBytes, false)
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.order.PushFront(&cachedParse{key: key, pems: pems, err: err})
+		if c.max > 0 && c.order.Len() > c.max {
+			oldest := c.order.Remove(c.order.Back()).(*cachedParse)
+			delete(c.entries, oldest.key)
+		}
+	}
+	return pems.clone(), err
+}
+
+// A copy of the parsed PEMs which can be consumed without affecting p
+func (p *ParsedPEMs) clone() ParsedPEMs {
+	c := *p
+	c.objs = append([]interface{}(nil), p.objs...)
+	c.blocks = append([]*pem.Block(nil), p.blocks...)
+	c.invalid = append([]*BlockError(nil), p.invalid...)
+	c.skipped = append([]SkippedBlock(nil), p.skipped...)
+	c.warnings = append([]Finding(nil), p.warnings...)
+	return c
+}
@@ -0,0 +1,53 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/pem"
+	"testing"
+)
+
+func TestCachingParser(t *testing.T) {
+	var calls int
+	p := NewCachingParser(2, WithBlockType("COUNTED", func(der *pem.Block) (interface{}, error) {
+		calls++
+		return der.Bytes, nil
+	}))
+	counted := func(b byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: "COUNTED", Bytes: []byte{b}})
+	}
+
+	for i := 0; i < 3; i++ {
+		pems, err := p.Parse(bytes.NewReader(counted(1)))
+		if err != nil {
+			t.Fatalf("unexpected error parsing pem %#v", err)
+		}
+		// consuming one copy leaves the cached result alone
+		if obj, err := pems.Next(); err != nil || !bytes.Equal(obj.([]byte), []byte{1}) {
+			t.Fatalf("unexpected result from the cache")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the input to be parsed once, parsed %d times", calls)
+	}
+
+	// filling the cache forgets the least recently used result
+	p.Parse(counted(2))
+	p.Parse(counted(1))
+	p.Parse(counted(3))
+	p.Parse(counted(1))
+	if calls != 3 {
+		t.Errorf("expected 3 parses, got %d", calls)
+	}
+	p.Parse(counted(2))
+	if calls != 4 {
+		t.Errorf("expected the evicted input to be parsed again, got %d parses", calls)
+	}
+
+	// errors are cached too
+	if _, err := p.Parse("nothing here"); err != ErrNoPEMFound {
+		t.Errorf("expected ErrNoPEMFound, got %#v", err)
+	}
+	if _, err := p.Parse("nothing here"); err != ErrNoPEMFound {
+		t.Errorf("expected ErrNoPEMFound from the cache, got %#v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package betterpem
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCertdata = errors.New("invalid certdata.txt")
+
+// NSS trust values for a purpose
+const (
+	// Trusted to issue certificates for the purpose
+	CertdataTrustedDelegator = "CKT_NSS_TRUSTED_DELEGATOR"
+	// Not trusted itself, but may chain to a trusted certificate
+	CertdataMustVerifyTrust = "CKT_NSS_MUST_VERIFY_TRUST"
+	// Explicitly distrusted
+	CertdataNotTrusted = "CKT_NSS_NOT_TRUSTED"
+)
+
+// A certificate from an NSS certdata.txt with the trust NSS gives it
+type CertdataEntry struct {
+	Label       string
+	Certificate *x509.Certificate
+	// The trust for each purpose, such as CertdataTrustedDelegator, or
+	// empty if certdata.txt gives no trust object for the certificate
+	ServerAuth, EmailProtection, CodeSigning string
+	// Certificates issued by this one after these times are not
+	// trusted for the purpose.  Zero when there is no such limit.
+	ServerDistrustAfter, EmailDistrustAfter time.Time
+}
+
+// An attribute of a certdata.txt object
+type certdataAttr struct {
+	typ   string
+	value []byte
+}
+
+// Decode the octal escapes of a MULTILINE_OCTAL value's lines
+func decodeCertdataOctal(line string, value []byte) ([]byte, error) {
+	for _, part := range strings.Split(line, `\`)[1:] {
+		b, err := strconv.ParseUint(part, 8, 8)
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, byte(b))
+	}
+	return value, nil
+}
+
+// Split certdata.txt into its objects' attributes
+func certdataObjects(data []byte) ([]map[string]certdataAttr, error) {
+	var objects []map[string]certdataAttr
+	var current map[string]certdataAttr
+	scan := bufio.NewScanner(bytes.NewReader(data))
+	scan.Buffer(nil, 1<<20)
+	lineNo := 0
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: line %d: %s", ErrInvalidCertdata, lineNo, fmt.Sprintf(format, args...))
+	}
+	for scan.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || line[0] == '#' || line == "BEGINDATA" || strings.HasPrefix(line, "CVS_ID") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) < 2 {
+			return nil, fail("expected an attribute, got %q", line)
+		}
+		name, typ := fields[0], fields[1]
+		attr := certdataAttr{typ: typ}
+		switch typ {
+		case "MULTILINE_OCTAL":
+			for {
+				if !scan.Scan() {
+					return nil, fail("%s has no END", name)
+				}
+				lineNo++
+				octal := strings.TrimSpace(scan.Text())
+				if octal == "END" {
+					break
+				}
+				var err error
+				if attr.value, err = decodeCertdataOctal(octal, attr.value); err != nil {
+					return nil, fail("bad octal in %s", name)
+				}
+			}
+		case "UTF8":
+			if len(fields) < 3 {
+				return nil, fail("%s has no value", name)
+			}
+			s, err := strconv.Unquote(fields[2])
+			if err != nil {
+				return nil, fail("bad string in %s", name)
+			}
+			attr.value = []byte(s)
+		default:
+			if len(fields) == 3 {
+				attr.value = []byte(fields[2])
+			}
+		}
+		if name == "CKA_CLASS" {
+			current = map[string]certdataAttr{}
+			objects = append(objects, current)
+		}
+		if current == nil {
+			return nil, fail("%s comes before any CKA_CLASS", name)
+		}
+		current[name] = attr
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Decode a distrust after attribute: CK_FALSE, or a UTCTime
+func certdataDistrustAfter(attr certdataAttr) (time.Time, error) {
+	if attr.typ != "MULTILINE_OCTAL" {
+		return time.Time{}, nil
+	}
+	return time.Parse("060102150405Z0700", string(attr.value))
+}
+
+// Read every certificate in an NSS certdata.txt, as found in NSS's
+// lib/ckfw/builtins and the sources of ca-certificates packages, with the
+// trust NSS gives it.
+//
+// Certificates are in the order they appear.  Trust objects are matched
+// to certificates by issuer and serial number.
+func ParseCertdataEntries(data []byte) ([]CertdataEntry, error) {
+	objects, err := certdataObjects(data)
+	if err != nil {
+		return nil, err
+	}
+	type issuerSerial struct{ issuer, serial string }
+	ret := []CertdataEntry{}
+	index := map[issuerSerial]int{}
+	for _, obj := range objects {
+		if string(obj["CKA_CLASS"].value) != "CKO_CERTIFICATE" {
+			continue
+		}
+		label := string(obj["CKA_LABEL"].value)
+		cert, err := x509.ParseCertificate(obj["CKA_VALUE"].value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: certificate %q: %v", ErrInvalidCertdata, label, err)
+		}
+		entry := CertdataEntry{Label: label, Certificate: cert}
+		if attr, ok := obj["CKA_NSS_SERVER_DISTRUST_AFTER"]; ok {
+			if entry.ServerDistrustAfter, err = certdataDistrustAfter(attr); err != nil {
+				return nil, fmt.Errorf("%w: certificate %q: bad server distrust after", ErrInvalidCertdata, label)
+			}
+		}
+		if attr, ok := obj["CKA_NSS_EMAIL_DISTRUST_AFTER"]; ok {
+			if entry.EmailDistrustAfter, err = certdataDistrustAfter(attr); err != nil {
+				return nil, fmt.Errorf("%w: certificate %q: bad email distrust after", ErrInvalidCertdata, label)
+			}
+		}
+		index[issuerSerial{string(obj["CKA_ISSUER"].value), string(obj["CKA_SERIAL_NUMBER"].value)}] = len(ret)
+		ret = append(ret, entry)
+	}
+	for _, obj := range objects {
+		if string(obj["CKA_CLASS"].value) != "CKO_NSS_TRUST" {
+			continue
+		}
+		i, ok := index[issuerSerial{string(obj["CKA_ISSUER"].value), string(obj["CKA_SERIAL_NUMBER"].value)}]
+		if !ok {
+			// trust for a certificate not in the file, such as a
+			// distrusted intermediate
+			continue
+		}
+		ret[i].ServerAuth = string(obj["CKA_TRUST_SERVER_AUTH"].value)
+		ret[i].EmailProtection = string(obj["CKA_TRUST_EMAIL_PROTECTION"].value)
+		ret[i].CodeSigning = string(obj["CKA_TRUST_CODE_SIGNING"].value)
+	}
+	return ret, nil
+}
+
+// Read the CAs which an NSS certdata.txt trusts to issue TLS server
+// certificates, as a trust store for CertPool or Encode.
+//
+// Only certificates trusted as delegators for server authentication are
+// included, and those whose server distrust after date has passed, as of
+// the clock from WithClock, are left out, as ca-certificates packages
+// build their bundles.  Use ParseCertdataEntries for other purposes.
+// Options besides WithClock are ignored.
+func ParseCertdata(data []byte, opts ...Option) (ParsedPEMs, error) {
+	entries, err := ParseCertdataEntries(data)
+	if err != nil {
+		return ParsedPEMs{}, err
+	}
+	ret := ParsedPEMs{clock: newParseOptions(opts).clock}
+	now := ret.now()
+	for _, e := range entries {
+		if e.ServerAuth != CertdataTrustedDelegator {
+			continue
+		}
+		if !e.ServerDistrustAfter.IsZero() && now.After(e.ServerDistrustAfter) {
+			continue
+		}
+		ret.add(e.Certificate, nil)
+	}
+	return ret, nil
+}
@@ -0,0 +1,108 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Write a value as certdata.txt writes MULTILINE_OCTAL, 16 bytes a line
+func certdataOctal(b []byte) string {
+	var sb strings.Builder
+	sb.WriteString("MULTILINE_OCTAL\n")
+	for i, c := range b {
+		fmt.Fprintf(&sb, `\%03o`, c)
+		if i%16 == 15 || i == len(b)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString("END\n")
+	return sb.String()
+}
+
+// A certificate and its trust object as certdata.txt has them
+func certdataCert(cert *x509.Certificate, label, serverTrust, distrustAfter string) string {
+	// the serial number is a DER INTEGER, tag and all
+	serial, _ := asn1.Marshal(cert.SerialNumber)
+	obj := fmt.Sprintf("#\n# Certificate %q\n#\nCKA_CLASS CK_OBJECT_CLASS CKO_CERTIFICATE\nCKA_TOKEN CK_BBOOL CK_TRUE\nCKA_LABEL UTF8 %q\nCKA_CERTIFICATE_TYPE CK_CERTIFICATE_TYPE CKC_X_509\n", label, label) +
+		"CKA_SUBJECT " + certdataOctal(cert.RawSubject) +
+		"CKA_ID UTF8 \"0\"\n" +
+		"CKA_ISSUER " + certdataOctal(cert.RawIssuer) +
+		"CKA_SERIAL_NUMBER " + certdataOctal(serial) +
+		"CKA_VALUE " + certdataOctal(cert.Raw) +
+		"CKA_NSS_MOZILLA_CA_POLICY CK_BBOOL CK_TRUE\n"
+	if distrustAfter == "" {
+		obj += "CKA_NSS_SERVER_DISTRUST_AFTER CK_BBOOL CK_FALSE\n"
+	} else {
+		obj += "CKA_NSS_SERVER_DISTRUST_AFTER " + certdataOctal([]byte(distrustAfter))
+	}
+	obj += "CKA_NSS_EMAIL_DISTRUST_AFTER CK_BBOOL CK_FALSE\n\n"
+	obj += fmt.Sprintf("# Trust for %q\n#\nCKA_CLASS CK_OBJECT_CLASS CKO_NSS_TRUST\nCKA_TOKEN CK_BBOOL CK_TRUE\nCKA_LABEL UTF8 %q\n", label, label) +
+		"CKA_ISSUER " + certdataOctal(cert.RawIssuer) +
+		"CKA_SERIAL_NUMBER " + certdataOctal(serial) +
+		"CKA_TRUST_SERVER_AUTH CK_TRUST " + serverTrust + "\n" +
+		"CKA_TRUST_EMAIL_PROTECTION CK_TRUST CKT_NSS_TRUSTED_DELEGATOR\n" +
+		"CKA_TRUST_CODE_SIGNING CK_TRUST CKT_NSS_MUST_VERIFY_TRUST\n" +
+		"CKA_TRUST_STEP_UP_APPROVED CK_BBOOL CK_FALSE\n\n"
+	return obj
+}
+
+func TestParseCertdata(t *testing.T) {
+	c := newTestChain(t)
+	newRoot := func(name string) *x509.Certificate {
+		cert, _ := issueTestCert(t, &x509.Certificate{
+			Subject:               pkix.Name{CommonName: name},
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+		}, nil, nil)
+		return cert
+	}
+	distrusted, email, retired := newRoot("Distrusted Root"), newRoot("Email Root"), newRoot("Retired Root")
+	data := "CVS_ID \"@(#) $RCSfile: certdata.txt $\"\n\nBEGINDATA\n" +
+		"CKA_CLASS CK_OBJECT_CLASS CKO_NSS_BUILTIN_ROOT_LIST\nCKA_TOKEN CK_BBOOL CK_TRUE\nCKA_LABEL UTF8 \"Mozilla Builtin Roots\"\n\n" +
+		certdataCert(c.root, "Test Root", CertdataTrustedDelegator, "") +
+		certdataCert(distrusted, "Distrusted Root", CertdataNotTrusted, "") +
+		certdataCert(email, "Email Root", CertdataMustVerifyTrust, "") +
+		certdataCert(retired, "Retired Root", CertdataTrustedDelegator, "200101000000Z")
+
+	entries, err := ParseCertdataEntries([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error parsing certdata %#v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 certificates, got %d", len(entries))
+	}
+	if entries[0].Label != "Test Root" || !entries[0].Certificate.Equal(c.root) || entries[0].ServerAuth != CertdataTrustedDelegator {
+		t.Errorf("unexpected first entry %#v", entries[0])
+	}
+	if entries[2].EmailProtection != CertdataTrustedDelegator || entries[2].CodeSigning != CertdataMustVerifyTrust {
+		t.Errorf("unexpected trust for the email root %#v", entries[2])
+	}
+	if want := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC); !entries[3].ServerDistrustAfter.Equal(want) || !entries[0].ServerDistrustAfter.IsZero() {
+		t.Errorf("unexpected distrust after %v", entries[3].ServerDistrustAfter)
+	}
+
+	trusted, err := ParseCertdata([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error parsing certdata %#v", err)
+	}
+	if trusted.Length() != 1 || !trusted.MustCertificate().Equal(c.root) {
+		t.Errorf("expected only the trusted root, got %d certificates", trusted.Length())
+	}
+	before, err := ParseCertdata([]byte(data), WithClock(func() time.Time { return time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC) }))
+	if err != nil {
+		t.Fatalf("unexpected error parsing certdata %#v", err)
+	}
+	if before.Length() != 2 {
+		t.Errorf("expected the retired root before its distrust date, got %d certificates", before.Length())
+	}
+
+	if _, err := ParseCertdata([]byte("CKA_CLASS CK_OBJECT_CLASS CKO_CERTIFICATE\nCKA_VALUE MULTILINE_OCTAL\n\\060\n")); !errors.Is(err, ErrInvalidCertdata) {
+		t.Errorf("expected ErrInvalidCertdata, got %#v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+)
+
+var ErrNoLeaf = errors.New("no leaf certificate was found")
+var ErrMultipleLeaves = errors.New("more than one certificate could be the leaf")
+var ErrChainGap = errors.New("certificates do not form a single chain")
+
+// Drop certificates which are byte for byte the same as an earlier one
+func uniqueCertificates(certs []*x509.Certificate) []*x509.Certificate {
+	seen := map[string]bool{}
+	ret := []*x509.Certificate{}
+	for _, c := range certs {
+		if !seen[string(c.Raw)] {
+			seen[string(c.Raw)] = true
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Certificates which didn't issue any of the others
+func leavesOf(certs []*x509.Certificate) []*x509.Certificate {
+	ret := []*x509.Certificate{}
+	for _, c := range certs {
+		issuer := false
+		for _, other := range certs {
+			if other != c && issuedBy(other, c) {
+				issuer = true
+				break
+			}
+		}
+		if !issuer {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Find the one certificate in a pile which could be the leaf
+func soleLeaf(certs []*x509.Certificate) (*x509.Certificate, error) {
+	leaves := leavesOf(uniqueCertificates(certs))
+	if len(leaves) > 1 {
+		// a root which issued nothing here is a sign of a missing
+		// intermediate rather than a second leaf
+		nonroots := []*x509.Certificate{}
+		for _, c := range leaves {
+			if !isSelfSigned(c) {
+				nonroots = append(nonroots, c)
+			}
+		}
+		if len(nonroots) == 1 {
+			leaves = nonroots
+		}
+	}
+	if len(leaves) == 0 {
+		return nil, ErrNoLeaf
+	}
+	if len(leaves) > 1 {
+		return nil, ErrMultipleLeaves
+	}
+	return leaves[0], nil
+}
+
+// Find the end-entity certificate in a pile of certificates.
+//
+// The leaf is the certificate which isn't a CA and didn't issue any of
+// the others.  If key is not nil, the leaf must also be for that key,
+// which may be a private or public key.  Duplicates are ignored.
+//
+// ErrNoLeaf is returned if no certificate qualifies and ErrMultipleLeaves
+// if more than one does.
+func FindLeaf(certs []*x509.Certificate, key crypto.PublicKey) (*x509.Certificate, error) {
+	var pub crypto.PublicKey
+	if key != nil {
+		var err error
+		pub, err = publicKeyOf(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	found := []*x509.Certificate{}
+	for _, c := range leavesOf(uniqueCertificates(certs)) {
+		if c.IsCA {
+			continue
+		}
+		if pub != nil && !publicKeysEqual(pub, c.PublicKey) {
+			continue
+		}
+		found = append(found, c)
+	}
+	if len(found) == 0 {
+		return nil, ErrNoLeaf
+	}
+	if len(found) > 1 {
+		return nil, ErrMultipleLeaves
+	}
+	return found[0], nil
+}
+
+// Put certificates in order from the leaf to the root.
+//
+// The leaf is the one certificate which didn't issue any of the others.
+// Each following certificate is the issuer of the one before it, matched
+// by subject, key identifiers, and signature.  The root is included if it
+// was given, and duplicates are dropped.
+//
+// ErrMultipleLeaves is returned if more than one certificate could be the
+// leaf, and ErrChainGap if some certificates aren't part of the leaf's
+// chain, such as when an intermediate is missing.
+func OrderChain(certs []*x509.Certificate) ([]*x509.Certificate, error) {
+	certs = uniqueCertificates(certs)
+	leaf, err := soleLeaf(certs)
+	if err != nil {
+		return nil, err
+	}
+	chain := []*x509.Certificate{leaf}
+	used := map[*x509.Certificate]bool{leaf: true}
+	for cur := leaf; !isSelfSigned(cur); {
+		var next *x509.Certificate
+		for _, c := range certs {
+			if !used[c] && issuedBy(cur, c) {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		used[next] = true
+		chain = append(chain, next)
+		cur = next
+	}
+	if len(chain) != len(certs) {
+		return nil, ErrChainGap
+	}
+	return chain, nil
+}
+
+// Sort the certificates remaining to be consumed into leaves,
+// intermediates, and roots.
+//
+// Certificates which aren't CAs and didn't issue any of the others are
+// leaves, even if they are self-signed.  Self-signed CAs are roots.
+// Everything else, including certificates without basic constraints
+// which issued others here, is an intermediate.  Duplicates are dropped
+// and order is otherwise kept.  The parsed PEMs are not consumed.
+func (p *ParsedPEMs) Partition() (leaves, intermediates, roots []*x509.Certificate) {
+	certs := uniqueCertificates(p.certificates())
+	notIssuers := map[*x509.Certificate]bool{}
+	for _, c := range leavesOf(certs) {
+		notIssuers[c] = true
+	}
+	for _, c := range certs {
+		switch {
+		case !c.IsCA && notIssuers[c]:
+			leaves = append(leaves, c)
+		case isSelfSigned(c):
+			roots = append(roots, c)
+		default:
+			intermediates = append(intermediates, c)
+		}
+	}
+	return leaves, intermediates, roots
+}
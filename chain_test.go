@@ -0,0 +1,80 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestOrderChain(t *testing.T) {
+	c := newTestChain(t)
+	ordered, err := OrderChain([]*x509.Certificate{c.root, c.leaf, c.intermediate, c.leaf})
+	if err != nil {
+		t.Fatalf("unexpected error ordering %#v", err)
+	}
+	if len(ordered) != 3 || ordered[0] != c.leaf || ordered[1] != c.intermediate || ordered[2] != c.root {
+		t.Errorf("unexpected order %v", ordered)
+	}
+
+	ordered, err = OrderChain([]*x509.Certificate{c.intermediate, c.leaf})
+	if err != nil || len(ordered) != 2 || ordered[0] != c.leaf {
+		t.Errorf("unexpected result without a root: %v %v", ordered, err)
+	}
+
+	if _, err := OrderChain([]*x509.Certificate{c.leaf, c.root}); err != ErrChainGap {
+		t.Errorf("expected ErrChainGap for a missing intermediate, got %#v", err)
+	}
+
+	other := newTestChain(t)
+	if _, err := OrderChain([]*x509.Certificate{c.leaf, c.intermediate, other.leaf}); err != ErrMultipleLeaves {
+		t.Errorf("expected ErrMultipleLeaves, got %#v", err)
+	}
+	if _, err := OrderChain(nil); err != ErrNoLeaf {
+		t.Errorf("expected ErrNoLeaf, got %#v", err)
+	}
+}
+
+func TestFindLeaf(t *testing.T) {
+	c := newTestChain(t)
+	other := newTestChain(t)
+	certs := []*x509.Certificate{c.root, other.leaf, c.intermediate, c.leaf}
+
+	if _, err := FindLeaf(certs, nil); err != ErrMultipleLeaves {
+		t.Errorf("expected ErrMultipleLeaves, got %#v", err)
+	}
+	leaf, err := FindLeaf(certs, c.leafKey)
+	if err != nil || leaf != c.leaf {
+		t.Errorf("unexpected leaf for key: %v %v", leaf, err)
+	}
+	leaf, err = FindLeaf(certs, other.leafKey.Public())
+	if err != nil || leaf != other.leaf {
+		t.Errorf("unexpected leaf for public key: %v %v", leaf, err)
+	}
+	if _, err := FindLeaf([]*x509.Certificate{c.root, c.intermediate}, nil); err != ErrNoLeaf {
+		t.Errorf("CAs should never be leaves, got %#v", err)
+	}
+	if _, err := FindLeaf(certs, c.rootKey); err != ErrNoLeaf {
+		t.Errorf("expected ErrNoLeaf for a key with no leaf, got %#v", err)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	c := newTestChain(t)
+	dev, err := SelfSigned(SelfSignedOptions{CommonName: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := ParsePEMs(c.pem(t, c.leaf, c.root, dev.Certificate, c.intermediate, c.leafKey, c.root))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	leaves, intermediates, roots := objs.Partition()
+	if len(leaves) != 2 || !leaves[0].Equal(c.leaf) || !leaves[1].Equal(dev.Certificate) {
+		t.Errorf("unexpected leaves %v", leaves)
+	}
+	if len(intermediates) != 1 || !intermediates[0].Equal(c.intermediate) {
+		t.Errorf("unexpected intermediates %v", intermediates)
+	}
+	if len(roots) != 1 || !roots[0].Equal(c.root) {
+		t.Errorf("unexpected roots %v", roots)
+	}
+}
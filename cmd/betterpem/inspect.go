@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jamesandariese/betterpem"
+)
+
+var errNoPEM = errors.New("no PEM blocks found")
+
+// What inspect found in one PEM block.  This is the schema of --json.
+type blockReport struct {
+	File string `json:"file"`
+	// From 1, in the order of the file
+	Index int `json:"index"`
+	// The PEM block type
+	Type    string `json:"type"`
+	Subject string `json:"subject,omitempty"`
+	Issuer  string `json:"issuer,omitempty"`
+	// The validity of a certificate
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+	// The validity of a CRL
+	ThisUpdate *time.Time         `json:"this_update,omitempty"`
+	NextUpdate *time.Time         `json:"next_update,omitempty"`
+	Key        *betterpem.KeyInfo `json:"key,omitempty"`
+	// Fingerprints are lowercase hex; those of the DER are only given
+	// for certificates
+	SHA256Fingerprint string `json:"sha256_fingerprint,omitempty"`
+	SHA1Fingerprint   string `json:"sha1_fingerprint,omitempty"`
+	SPKISHA256        string `json:"spki_sha256,omitempty"`
+	// Set for blocks of types betterpem doesn't parse
+	Unsupported bool `json:"unsupported,omitempty"`
+	// Why the block couldn't be parsed
+	Error string `json:"error,omitempty"`
+
+	sha256, sha1, spki *betterpem.Digest
+}
+
+func timePtr(t time.Time) *time.Time {
+	t = t.UTC()
+	return &t
+}
+
+// Fingerprint obj with hash, or nil if it can't be
+func digestOf(obj interface{}, hash crypto.Hash) *betterpem.Digest {
+	d, err := betterpem.Fingerprint(obj, hash)
+	if err != nil {
+		return nil
+	}
+	return &d
+}
+
+// Describe the object parsed from a block
+func (r *blockReport) describe(obj interface{}) {
+	switch v := obj.(type) {
+	case *x509.Certificate:
+		r.Subject, r.Issuer = v.Subject.String(), v.Issuer.String()
+		r.NotBefore, r.NotAfter = timePtr(v.NotBefore), timePtr(v.NotAfter)
+		r.sha256, r.sha1 = digestOf(v, crypto.SHA256), digestOf(v, crypto.SHA1)
+	case *x509.CertificateRequest:
+		r.Subject = v.Subject.String()
+	case *x509.RevocationList:
+		r.Issuer = v.Issuer.String()
+		r.ThisUpdate = timePtr(v.ThisUpdate)
+		if !v.NextUpdate.IsZero() {
+			r.NextUpdate = timePtr(v.NextUpdate)
+		}
+	}
+	if info, ok := betterpem.KeyInfoOf(obj); ok {
+		r.Key = &info
+		if pin, err := betterpem.SPKIPin(obj); err == nil {
+			sum, _ := base64.StdEncoding.DecodeString(pin)
+			r.spki = &betterpem.Digest{Hash: crypto.SHA256, Sum: sum}
+		}
+	}
+	for _, d := range []struct {
+		digest *betterpem.Digest
+		field  *string
+	}{{r.sha256, &r.SHA256Fingerprint}, {r.sha1, &r.SHA1Fingerprint}, {r.spki, &r.SPKISHA256}} {
+		if d.digest != nil {
+			*d.field = d.digest.Hex()
+		}
+	}
+}
+
+// Inspect every block of an input, in order.  The blocks found are
+// returned even if a truncated block is also reported.
+func inspectInput(in input) ([]blockReport, error) {
+	blocks, err := pemBlocks(in.data)
+	var ret []blockReport
+	for _, block := range blocks {
+		r := blockReport{File: in.name, Index: len(ret) + 1, Type: block.Type}
+		pems, err := betterpem.ParsePEMs(pem.EncodeToMemory(block))
+		var obj interface{}
+		if err == nil {
+			obj, err = pems.Next()
+		}
+		var unsupported *betterpem.ErrOnlyUnsupportedBlocks
+		if errors.As(err, &unsupported) {
+			r.Unsupported = true
+		} else if err != nil {
+			r.Error = err.Error()
+		} else {
+			r.describe(obj)
+		}
+		ret = append(ret, r)
+	}
+	return ret, err
+}
+
+func (r *blockReport) writeText(w io.Writer) {
+	fmt.Fprintf(w, "%s #%d: %s\n", r.File, r.Index, r.Type)
+	line := func(name, value string) {
+		fmt.Fprintf(w, "    %s: %s\n", name, value)
+	}
+	if r.Error != "" {
+		line("Error", r.Error)
+		return
+	}
+	if r.Unsupported {
+		fmt.Fprintln(w, "    Unsupported block type")
+		return
+	}
+	if r.Subject != "" {
+		line("Subject", r.Subject)
+	}
+	if r.Issuer != "" {
+		line("Issuer", r.Issuer)
+	}
+	for _, t := range []struct {
+		name  string
+		value *time.Time
+	}{{"Not Before", r.NotBefore}, {"Not After", r.NotAfter}, {"This Update", r.ThisUpdate}, {"Next Update", r.NextUpdate}} {
+		if t.value != nil {
+			line(t.name, t.value.Format(time.RFC3339))
+		}
+	}
+	if r.Key != nil {
+		line("Key", r.Key.String())
+	}
+	if r.sha256 != nil {
+		line("SHA-256 Fingerprint", r.sha256.Colon())
+	}
+	if r.sha1 != nil {
+		line("SHA-1 Fingerprint", r.sha1.Colon())
+	}
+	if r.spki != nil {
+		line("SPKI SHA-256", r.spki.Colon())
+	}
+}
+
+// Print the type, names, validity, key, and fingerprints of every PEM
+// block in the files
+func inspect(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("inspect", stderr)
+	asJSON := flags.Bool("json", false, "print a JSON array of the blocks")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	inputs, err := readInputs(flags.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "betterpem inspect: %v\n", err)
+		return 1
+	}
+	status := 0
+	reports := []blockReport{}
+	for _, in := range inputs {
+		found, err := inspectInput(in)
+		if err != nil {
+			fmt.Fprintf(stderr, "betterpem inspect: %s: %v\n", in.name, err)
+			status = 1
+		}
+		for _, r := range found {
+			if r.Error != "" {
+				status = 1
+			}
+		}
+		reports = append(reports, found...)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			fmt.Fprintf(stderr, "betterpem inspect: %v\n", err)
+			return 1
+		}
+		return status
+	}
+	for i := range reports {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		reports[i].writeText(stdout)
+	}
+	return status
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	cert, data := testCertPEM(t, "example.com")
+	data = append(data, "-----BEGIN WIDGET-----\nMAA=\n-----END WIDGET-----\n"...)
+	sum := sha256.Sum256(cert.Raw)
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"inspect"}, bytes.NewReader(data), &stdout, &stderr); status != 0 {
+		t.Fatalf("unexpected status %d: %s", status, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"<stdin> #1: CERTIFICATE\n",
+		"    Subject: CN=example.com\n",
+		"    Not After: " + cert.NotAfter.UTC().Format("2006-01-02T15:04:05Z"),
+		"    Key: ECDSA P-256 (256 bits)\n",
+		"    SHA-256 Fingerprint: " + strings.ToUpper(hex.EncodeToString(sum[:1])) + ":",
+		"<stdin> #2: PRIVATE KEY\n",
+		"<stdin> #3: WIDGET\n    Unsupported block type\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	stdout.Reset()
+	if status := run([]string{"inspect", "--json", "-"}, bytes.NewReader(data), &stdout, &stderr); status != 0 {
+		t.Fatalf("unexpected status %d: %s", status, stderr.String())
+	}
+	var reports []blockReport
+	if err := json.Unmarshal(stdout.Bytes(), &reports); err != nil {
+		t.Fatalf("unexpected error decoding json %#v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(reports))
+	}
+	if reports[0].SHA256Fingerprint != hex.EncodeToString(sum[:]) || reports[0].Key.Curve != "P-256" {
+		t.Errorf("unexpected certificate %+v", reports[0])
+	}
+	if reports[1].SPKISHA256 != reports[0].SPKISHA256 || reports[1].SHA256Fingerprint != "" {
+		t.Errorf("expected the key to have the certificate's SPKI and no fingerprint, got %+v", reports[1])
+	}
+
+	stderr.Reset()
+	if status := run([]string{"inspect"}, strings.NewReader("not pem"), &stdout, &stderr); status != 1 || !strings.Contains(stderr.String(), "no PEM blocks found") {
+		t.Errorf("expected no PEM blocks, got %d %q", status, stderr.String())
+	}
+
+	// a truncated block is reported, and so is the block ParsePEMs would
+	// find after the last of several BEGIN lines
+	stdout.Reset()
+	stderr.Reset()
+	odd := "-----BEGIN WIDGET-----\nMAA=\n-----BEGIN WIDGET-----\nMAA=\n-----END WIDGET-----\n-----BEGIN CERTIFICATE-----\nMIIB\n"
+	if status := run([]string{"inspect"}, strings.NewReader(odd), &stdout, &stderr); status != 1 || !strings.Contains(stderr.String(), "CERTIFICATE block starting at line 6") {
+		t.Errorf("expected the truncated block to be reported, got %d %q", status, stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, "<stdin> #1: WIDGET\n") || strings.Contains(out, "#2") {
+		t.Errorf("expected just the WIDGET block, got:\n%s", out)
+	}
+}
@@ -0,0 +1,133 @@
+// Command betterpem inspects and splits up PEM files.
+//
+// Usage:
+//
+//	betterpem inspect [--json] [file ...]
+//	betterpem split [--out dir] [--name template] [--force] [file ...]
+//
+// Files are read from standard input when none are given, or when one is
+// named "-".
+package main
+
+import (
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/jamesandariese/betterpem"
+)
+
+// A subcommand, given its arguments after its name
+type command func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+var commands = map[string]command{
+	"inspect": inspect,
+	"split":   split,
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "usage: betterpem <command> [flags] [file ...]")
+	fmt.Fprintln(w, "commands:")
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+name)
+	}
+}
+
+// Run the command line, returning the exit status: 0 on success, 1 when
+// something couldn't be read or parsed, and 2 for bad usage
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(stdout)
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "betterpem: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+	return cmd(args[1:], stdin, stdout, stderr)
+}
+
+// Make the flag set for a subcommand, which reports errors to stderr
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("betterpem "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	return flags
+}
+
+// A file given on the command line
+type input struct {
+	name string
+	data []byte
+}
+
+// Read the files named on the command line, or standard input if there
+// are none
+func readInputs(paths []string, stdin io.Reader) ([]input, error) {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	ret := make([]input, 0, len(paths))
+	for _, path := range paths {
+		var data []byte
+		var err error
+		if path == "-" {
+			data, err = io.ReadAll(stdin)
+			path = "<stdin>"
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, input{name: path, data: data})
+	}
+	return ret, nil
+}
+
+// The PEM blocks in data, in order, found the way betterpem finds them.
+// A BEGIN line with no END line gives a *betterpem.TruncatedBlockError
+// along with the blocks which were found, and no blocks at all gives
+// errNoPEM.
+func pemBlocks(data []byte) ([]*pem.Block, error) {
+	report, err := betterpem.ValidatePEM(data)
+	var truncated *betterpem.TruncatedBlockError
+	errors.As(err, &truncated)
+	// ScanPEM also lists blocks which don't decode, so match them up by
+	// where they start
+	index, _ := betterpem.ScanPEM(data)
+	text := map[int][]byte{}
+	for _, b := range index {
+		text[b.Offset] = data[b.Offset : b.Offset+b.Length]
+	}
+	ret := make([]*pem.Block, 0, len(report.Blocks))
+	for _, info := range report.Blocks {
+		block, _ := pem.Decode(text[info.Offset])
+		ret = append(ret, block)
+	}
+	if truncated != nil {
+		return ret, truncated
+	}
+	if len(ret) == 0 {
+		return nil, errNoPEM
+	}
+	return ret, nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A self-signed certificate for name and its key, in PEM
+func testCertPEM(t *testing.T, name string) (*x509.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})...)
+	return cert, data
+}
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if status := run(nil, nil, &stdout, &stderr); status != 2 || !strings.Contains(stderr.String(), "inspect") {
+		t.Errorf("expected usage listing the commands, got %d %q", status, stderr.String())
+	}
+	stderr.Reset()
+	if status := run([]string{"frobnicate"}, nil, &stdout, &stderr); status != 2 || !strings.Contains(stderr.String(), `unknown command "frobnicate"`) {
+		t.Errorf("expected an unknown command, got %d %q", status, stderr.String())
+	}
+}
+
+func TestReadInputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.pem")
+	if err := os.WriteFile(path, []byte("file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := readInputs([]string{path, "-"}, strings.NewReader("stdin"))
+	if err != nil {
+		t.Fatalf("unexpected error reading inputs %#v", err)
+	}
+	if len(inputs) != 2 || string(inputs[0].data) != "file" || inputs[1].name != "<stdin>" || string(inputs[1].data) != "stdin" {
+		t.Errorf("unexpected inputs %q", inputs)
+	}
+	if _, err := readInputs([]string{path + ".missing"}, nil); !os.IsNotExist(err) {
+		t.Errorf("expected a missing file, got %#v", err)
+	}
+}
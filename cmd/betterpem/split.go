@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/jamesandariese/betterpem"
+)
+
+const defaultSplitName = `{{.Kind}}-{{printf "%02d" .Index}}{{if .Name}}-{{.Name}}{{end}}.pem`
+
+// What a split file's naming template is given
+type splitFile struct {
+	// A short name for the kind of block: cert, key, csr, crl, pub, or
+	// the block type in lowercase with dashes for anything else
+	Kind string
+	// From 1, counting blocks of the same kind
+	Index int
+	// The PEM block type
+	Type string
+	// The subject's common name, or else its first DNS name, of a
+	// certificate or request, made safe for a file name.  Empty for
+	// other blocks.
+	Name string
+}
+
+func splitKind(blockType string) string {
+	switch {
+	// before the others, so "PGP PRIVATE KEY BLOCK" is a key
+	case strings.Contains(blockType, "PRIVATE KEY"):
+		return "key"
+	case blockType == "CERTIFICATE" || blockType == "TRUSTED CERTIFICATE":
+		return "cert"
+	case strings.HasSuffix(blockType, "CERTIFICATE REQUEST"):
+		return "csr"
+	case blockType == "X509 CRL":
+		return "crl"
+	case strings.HasSuffix(blockType, "PUBLIC KEY"):
+		return "pub"
+	}
+	return strings.ReplaceAll(strings.ToLower(blockType), " ", "-")
+}
+
+// Make a name safe to use in a file name
+func sanitizeName(name string) string {
+	name = strings.ReplaceAll(name, "*", "wildcard")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// The name of the certificate or request in a block, if it has one
+func splitName(block *pem.Block) string {
+	pems, err := betterpem.ParsePEMs(pem.EncodeToMemory(block))
+	if err != nil {
+		return ""
+	}
+	obj, err := pems.Next()
+	if err != nil {
+		return ""
+	}
+	var cn string
+	var dns []string
+	switch v := obj.(type) {
+	case *x509.Certificate:
+		cn, dns = v.Subject.CommonName, v.DNSNames
+	case *x509.CertificateRequest:
+		cn, dns = v.Subject.CommonName, v.DNSNames
+	}
+	if cn == "" && len(dns) > 0 {
+		cn = dns[0]
+	}
+	return sanitizeName(cn)
+}
+
+// Write every PEM block of the files to its own file, named by its type
+// and subject
+func split(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("split", stderr)
+	out := flags.String("out", ".", "the directory to write the files to")
+	name := flags.String("name", defaultSplitName, "the text/template for each file's name, given Kind, Index, Type, and Name")
+	force := flags.Bool("force", false, "overwrite files which already exist")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(*name)
+	if err != nil {
+		fmt.Fprintf(stderr, "betterpem split: bad --name: %v\n", err)
+		return 2
+	}
+	inputs, err := readInputs(flags.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "betterpem split: %v\n", err)
+		return 1
+	}
+
+	// name every file before writing any, so a bad template or input
+	// doesn't leave half a split behind
+	type outFile struct {
+		path  string
+		block *pem.Block
+	}
+	var files []outFile
+	counts := map[string]int{}
+	used := map[string]bool{}
+	for _, in := range inputs {
+		blocks, err := pemBlocks(in.data)
+		if err != nil {
+			fmt.Fprintf(stderr, "betterpem split: %s: %v\n", in.name, err)
+			return 1
+		}
+		for _, block := range blocks {
+			f := splitFile{Kind: splitKind(block.Type), Type: block.Type, Name: splitName(block)}
+			counts[f.Kind]++
+			f.Index = counts[f.Kind]
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, f); err != nil {
+				fmt.Fprintf(stderr, "betterpem split: bad --name: %v\n", err)
+				return 2
+			}
+			base := buf.String()
+			if base == "" || base == "." || base == ".." || strings.ContainsAny(base, `/\`) {
+				fmt.Fprintf(stderr, "betterpem split: --name gave %q, which is not a file name\n", base)
+				return 2
+			}
+			if used[base] {
+				fmt.Fprintf(stderr, "betterpem split: --name gave %q for more than one block\n", base)
+				return 2
+			}
+			used[base] = true
+			files = append(files, outFile{filepath.Join(*out, base), block})
+		}
+	}
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fmt.Fprintf(stderr, "betterpem split: %v\n", err)
+		return 1
+	}
+	for _, f := range files {
+		private := splitKind(f.block.Type) == "key"
+		mode := os.FileMode(0644)
+		if private {
+			mode = 0600
+		}
+		flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		if *force {
+			flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+		err := writeFile(f.path, flag, mode, private, pem.EncodeToMemory(f.block))
+		if errors.Is(err, os.ErrExist) {
+			fmt.Fprintf(stderr, "betterpem split: %s already exists; use --force to overwrite it\n", f.path)
+			return 1
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "betterpem split: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, f.path)
+	}
+	return 0
+}
+
+// Write a file, setting its mode even if it already existed when private
+// is true, so a key never lands in a file others can read
+func writeFile(path string, flag int, mode os.FileMode, private bool, data []byte) error {
+	f, err := os.OpenFile(path, flag, mode)
+	if err != nil {
+		return err
+	}
+	if private {
+		if err := f.Chmod(mode); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
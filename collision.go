@@ -0,0 +1,64 @@
+package betterpem
+
+import (
+	"crypto/x509"
+)
+
+// Certificates in one bundle which should be distinct but aren't
+type Collision struct {
+	// FindingSerialReuse or FindingSubjectKeyIdReuse
+	Kind FindingKind
+	// The colliding certificates, in order, without exact duplicates
+	Certificates []*x509.Certificate
+}
+
+// Return a collision for each group of certificates of more than one
+// where the keys match, in the order the groups were first seen
+func collisionsOf(kind FindingKind, certs []*x509.Certificate, key func(*x509.Certificate) (string, bool), distinct func(a, b *x509.Certificate) bool) []Collision {
+	order := []string{}
+	groups := map[string][]*x509.Certificate{}
+	for _, c := range certs {
+		k, ok := key(c)
+		if !ok {
+			continue
+		}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], c)
+	}
+	ret := []Collision{}
+	for _, k := range order {
+		group := groups[k]
+		for _, c := range group[1:] {
+			if distinct(group[0], c) {
+				ret = append(ret, Collision{Kind: kind, Certificates: group})
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// Find certificates remaining to be consumed which collide: certificates
+// from the same issuer with the same serial number, and certificates
+// with the same subject key identifier but different keys.  Both are
+// usually signs of misissued or stale certificates.
+//
+// Serial number collisions come before subject key identifier ones.
+// Certificates which appear more than once are not collisions.  The
+// parsed PEMs are not consumed.
+func (p *ParsedPEMs) Collisions() []Collision {
+	certs := uniqueCertificates(p.certificates())
+	serials := collisionsOf(FindingSerialReuse, certs, func(c *x509.Certificate) (string, bool) {
+		return string(c.RawIssuer) + "\x00" + string(c.SerialNumber.Bytes()), true
+	}, func(a, b *x509.Certificate) bool {
+		return true
+	})
+	skis := collisionsOf(FindingSubjectKeyIdReuse, certs, func(c *x509.Certificate) (string, bool) {
+		return string(c.SubjectKeyId), len(c.SubjectKeyId) > 0
+	}, func(a, b *x509.Certificate) bool {
+		return string(a.RawSubjectPublicKeyInfo) != string(b.RawSubjectPublicKeyInfo)
+	})
+	return append(serials, skis...)
+}
@@ -0,0 +1,53 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+)
+
+func TestCollisions(t *testing.T) {
+	c := newTestChain(t)
+	first, _ := issueTestCert(t, &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "first"},
+		SerialNumber: big.NewInt(7),
+		SubjectKeyId: []byte{1, 2, 3, 4},
+	}, c.intermediate, c.intermediateKey)
+	second, _ := issueTestCert(t, &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "second"},
+		SerialNumber: big.NewInt(7),
+	}, c.intermediate, c.intermediateKey)
+	third, _ := issueTestCert(t, &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "third"},
+		SubjectKeyId: []byte{1, 2, 3, 4},
+	}, c.intermediate, c.intermediateKey)
+
+	clean, err := ParsePEMs(c.pem(t, c.leaf, c.intermediate, c.root, c.leaf))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if collisions := clean.Collisions(); len(collisions) != 0 {
+		t.Errorf("unexpected collisions %v", collisions)
+	}
+
+	objs, err := ParsePEMs(c.pem(t, first, second, third, c.intermediate))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	collisions := objs.Collisions()
+	if len(collisions) != 2 {
+		t.Fatalf("expected 2 collisions, got %v", collisions)
+	}
+	if collisions[0].Kind != FindingSerialReuse || len(collisions[0].Certificates) != 2 || !collisions[0].Certificates[1].Equal(second) {
+		t.Errorf("unexpected serial collision %v", collisions[0])
+	}
+	if collisions[1].Kind != FindingSubjectKeyIdReuse || len(collisions[1].Certificates) != 2 || !collisions[1].Certificates[1].Equal(third) {
+		t.Errorf("unexpected subject key identifier collision %v", collisions[1])
+	}
+
+	kinds := findingKinds(Audit(objs, AuditOptions{}))
+	if kinds[FindingSerialReuse] != 1 || kinds[FindingSubjectKeyIdReuse] != 1 {
+		t.Errorf("audit did not report the collisions: %v", kinds)
+	}
+}
@@ -0,0 +1,323 @@
+package betterpem
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrInvalidCOSEKey = errors.New("invalid COSE key")
+var ErrUnsupportedCOSEKey = errors.New("COSE key is of an unsupported key type or curve")
+
+// COSE_Key labels and values from RFC 9052 and RFC 9053
+const (
+	coseKty = 1
+	coseAlg = 3
+	coseCrv = -1
+	coseX   = -2
+	coseY   = -3
+	coseD   = -4
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+
+	coseCrvP256    = 1
+	coseCrvP384    = 2
+	coseCrvP521    = 3
+	coseCrvEd25519 = 6
+
+	coseAlgES256 = -7
+	coseAlgES384 = -35
+	coseAlgES512 = -36
+	coseAlgEdDSA = -8
+)
+
+var coseCurves = map[int64]elliptic.Curve{
+	coseCrvP256: elliptic.P256(),
+	coseCrvP384: elliptic.P384(),
+	coseCrvP521: elliptic.P521(),
+}
+
+// A CBOR head: the major type and argument of an item
+func cborHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+}
+
+func cborInt(n int64) []byte {
+	if n < 0 {
+		return cborHead(1, uint64(-1-n))
+	}
+	return cborHead(0, uint64(n))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, uint64(len(b))), b...)
+}
+
+// Reads CBOR items, remembering whether anything was malformed
+type cborReader struct {
+	b   []byte
+	bad bool
+}
+
+func (r *cborReader) head() (major byte, arg uint64) {
+	if len(r.b) == 0 {
+		r.bad = true
+		return 0, 0
+	}
+	major, info := r.b[0]>>5, r.b[0]&31
+	r.b = r.b[1:]
+	if info < 24 {
+		return major, uint64(info)
+	}
+	if info > 27 {
+		// indefinite lengths aren't allowed in COSE keys
+		r.bad = true
+		return 0, 0
+	}
+	n := 1 << (info - 24)
+	if len(r.b) < n {
+		r.bad = true
+		return 0, 0
+	}
+	for _, c := range r.b[:n] {
+		arg = arg<<8 | uint64(c)
+	}
+	r.b = r.b[n:]
+	return major, arg
+}
+
+// Read one item: an integer, a byte or text string, a simple value, or a
+// structure, which is skipped
+func (r *cborReader) item(depth int) interface{} {
+	major, arg := r.head()
+	if r.bad || depth > 16 {
+		r.bad = true
+		return nil
+	}
+	switch major {
+	case 0:
+		if arg > 1<<63-1 {
+			r.bad = true
+		}
+		return int64(arg)
+	case 1:
+		if arg > 1<<63-1 {
+			r.bad = true
+		}
+		return -1 - int64(arg)
+	case 2, 3:
+		if arg > uint64(len(r.b)) {
+			r.bad = true
+			return nil
+		}
+		s := r.b[:arg]
+		r.b = r.b[arg:]
+		if major == 3 {
+			return string(s)
+		}
+		return append([]byte{}, s...)
+	case 4, 5:
+		// every item is at least a byte
+		if arg > uint64(len(r.b)) {
+			r.bad = true
+			return nil
+		}
+		if major == 5 {
+			arg *= 2
+		}
+		for i := uint64(0); i < arg; i++ {
+			r.item(depth + 1)
+		}
+		return nil
+	case 6:
+		return r.item(depth + 1)
+	}
+	switch arg {
+	case 20:
+		return false
+	case 21:
+		return true
+	}
+	return nil
+}
+
+// The alg, crv, x, and y parameters of an EC2 key
+func coseEC2(pub *ecdsa.PublicKey) (alg, crv int64, x, y []byte, err error) {
+	switch pub.Curve {
+	case elliptic.P256():
+		alg, crv = coseAlgES256, coseCrvP256
+	case elliptic.P384():
+		alg, crv = coseAlgES384, coseCrvP384
+	case elliptic.P521():
+		alg, crv = coseAlgES512, coseCrvP521
+	default:
+		return 0, 0, nil, nil, ErrCannotEncode
+	}
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	return alg, crv, pub.X.FillBytes(make([]byte, size)), pub.Y.FillBytes(make([]byte, size)), nil
+}
+
+// Encode an EC or Ed25519 key as an RFC 9052 COSE_Key, as WebAuthn and
+// other CBOR based protocols exchange keys.
+//
+// The key may be an *ecdsa.PublicKey or *ecdsa.PrivateKey on P-256,
+// P-384, or P-521, or an Ed25519 public or private key.  Private keys
+// include their private part.  The alg parameter is set to the usual
+// algorithm for the curve: ES256, ES384, ES512, or EdDSA.
+func ToCOSEKey(key interface{}) ([]byte, error) {
+	var kty, alg, crv int64
+	var x, y, d []byte
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		var err error
+		if alg, crv, x, y, err = coseEC2(&k.PublicKey); err != nil {
+			return nil, err
+		}
+		kty, d = coseKtyEC2, k.D.FillBytes(make([]byte, len(x)))
+	case *ecdsa.PublicKey:
+		var err error
+		if alg, crv, x, y, err = coseEC2(k); err != nil {
+			return nil, err
+		}
+		kty = coseKtyEC2
+	case ed25519.PrivateKey:
+		kty, alg, crv = coseKtyOKP, coseAlgEdDSA, coseCrvEd25519
+		x, d = k.Public().(ed25519.PublicKey), k.Seed()
+	case ed25519.PublicKey:
+		kty, alg, crv = coseKtyOKP, coseAlgEdDSA, coseCrvEd25519
+		x = k
+	default:
+		return nil, ErrCannotEncode
+	}
+
+	// in the deterministic order of RFC 8949: 1, 3, -1, -2, -3, -4
+	pairs := [][]byte{cborInt(coseKty), cborInt(kty), cborInt(coseAlg), cborInt(alg), cborInt(coseCrv), cborInt(crv), cborInt(coseX), cborBytes(x)}
+	if y != nil {
+		pairs = append(pairs, cborInt(coseY), cborBytes(y))
+	}
+	if d != nil {
+		pairs = append(pairs, cborInt(coseD), cborBytes(d))
+	}
+	ret := cborHead(5, uint64(len(pairs)/2))
+	for _, p := range pairs {
+		ret = append(ret, p...)
+	}
+	return ret, nil
+}
+
+// Decode an RFC 9052 COSE_Key, such as a WebAuthn credential public key,
+// into the key ParsePEMs would give for it in PEM: an *ecdsa.PublicKey or
+// *ecdsa.PrivateKey for EC2 keys on P-256, P-384, and P-521, or an
+// ed25519.PublicKey or ed25519.PrivateKey for OKP Ed25519 keys.
+//
+// Other key types and curves give an error matching
+// ErrUnsupportedCOSEKey, and malformed keys one matching
+// ErrInvalidCOSEKey.  Parameters besides the key itself, as kid and alg,
+// are ignored.
+func FromCOSEKey(data []byte) (interface{}, error) {
+	r := &cborReader{b: data}
+	major, n := r.head()
+	if r.bad || major != 5 || n > uint64(len(data)) {
+		return nil, fmt.Errorf("%w: not a CBOR map", ErrInvalidCOSEKey)
+	}
+	params := map[int64]interface{}{}
+	for i := uint64(0); i < n; i++ {
+		label := r.item(0)
+		value := r.item(0)
+		if label, ok := label.(int64); ok {
+			params[label] = value
+		}
+	}
+	if r.bad || len(r.b) > 0 {
+		return nil, fmt.Errorf("%w: malformed CBOR", ErrInvalidCOSEKey)
+	}
+	bstr := func(label int64, name string) ([]byte, error) {
+		b, ok := params[label].([]byte)
+		if !ok {
+			return nil, fmt.Errorf("%w: bad %s parameter", ErrInvalidCOSEKey, name)
+		}
+		return b, nil
+	}
+	kty, _ := params[coseKty].(int64)
+	crv, _ := params[coseCrv].(int64)
+	switch kty {
+	case coseKtyEC2:
+		curve, ok := coseCurves[crv]
+		if !ok {
+			return nil, fmt.Errorf("%w: EC2 curve %d", ErrUnsupportedCOSEKey, crv)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		x, err := bstr(coseX, "x")
+		if err != nil {
+			return nil, err
+		}
+		// a boolean y is point compression, which nothing seems to use
+		y, err := bstr(coseY, "y")
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("%w: coordinates are the wrong size", ErrInvalidCOSEKey)
+		}
+		point := append(append([]byte{4}, x...), y...)
+		ecdhCurve := jwkECDHCurves[curve.Params().Name]
+		if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("%w: point is not on the curve", ErrInvalidCOSEKey)
+		}
+		pub := ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if _, ok := params[coseD]; !ok {
+			return &pub, nil
+		}
+		d, err := bstr(coseD, "d")
+		if err != nil {
+			return nil, err
+		}
+		priv, err := ecdhCurve.NewPrivateKey(d)
+		if err != nil || string(priv.PublicKey().Bytes()) != string(point) {
+			return nil, fmt.Errorf("%w: private key does not match its public key", ErrInvalidCOSEKey)
+		}
+		return &ecdsa.PrivateKey{PublicKey: pub, D: new(big.Int).SetBytes(d)}, nil
+	case coseKtyOKP:
+		if crv != coseCrvEd25519 {
+			return nil, fmt.Errorf("%w: OKP curve %d", ErrUnsupportedCOSEKey, crv)
+		}
+		x, err := bstr(coseX, "x")
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: bad x parameter", ErrInvalidCOSEKey)
+		}
+		if _, ok := params[coseD]; !ok {
+			return ed25519.PublicKey(x), nil
+		}
+		d, err := bstr(coseD, "d")
+		if err != nil {
+			return nil, err
+		}
+		if len(d) != ed25519.SeedSize {
+			return nil, fmt.Errorf("%w: bad d parameter", ErrInvalidCOSEKey)
+		}
+		key := ed25519.NewKeyFromSeed(d)
+		if !key.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+			return nil, fmt.Errorf("%w: private key does not match its public key", ErrInvalidCOSEKey)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: key type %d", ErrUnsupportedCOSEKey, kty)
+}
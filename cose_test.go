@@ -0,0 +1,109 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestCOSEKeyEC2(t *testing.T) {
+	c := newTestChain(t)
+	key := c.leafKey.(*ecdsa.PrivateKey)
+	encoded, err := ToCOSEKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("unexpected error encoding key %#v", err)
+	}
+	// the prefix every WebAuthn ES256 credential public key has
+	if !bytes.HasPrefix(encoded, []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20}) || len(encoded) != 77 {
+		t.Errorf("unexpected encoding %x", encoded)
+	}
+	pub, err := FromCOSEKey(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error decoding key %#v", err)
+	}
+	if !key.PublicKey.Equal(pub) {
+		t.Error("expected the public key back")
+	}
+
+	encoded, err = ToCOSEKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error encoding key %#v", err)
+	}
+	priv, err := FromCOSEKey(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error decoding key %#v", err)
+	}
+	if !key.Equal(priv) {
+		t.Error("expected the private key back")
+	}
+
+	p384, _, err := GenerateEC(elliptic.P384())
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err = ToCOSEKey(p384)
+	if err != nil {
+		t.Fatalf("unexpected error encoding key %#v", err)
+	}
+	if decoded, err := FromCOSEKey(encoded); err != nil || !p384.Equal(decoded) {
+		t.Errorf("expected the P-384 key back, got %#v", err)
+	}
+}
+
+func TestCOSEKeyEd25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := ToCOSEKey(pub)
+	if err != nil {
+		t.Fatalf("unexpected error encoding key %#v", err)
+	}
+	if !bytes.Equal(encoded[:9], []byte{0xa4, 0x01, 0x01, 0x03, 0x27, 0x20, 0x06, 0x21, 0x58}) {
+		t.Errorf("unexpected encoding %x", encoded)
+	}
+	if decoded, err := FromCOSEKey(encoded); err != nil || !pub.Equal(decoded) {
+		t.Errorf("expected the public key back, got %#v", err)
+	}
+	encoded, err = ToCOSEKey(priv)
+	if err != nil {
+		t.Fatalf("unexpected error encoding key %#v", err)
+	}
+	if decoded, err := FromCOSEKey(encoded); err != nil || !priv.Equal(decoded) {
+		t.Errorf("expected the private key back, got %#v", err)
+	}
+
+	// parameters in any order, with a kid and a text label
+	kid := []byte{0xa5, 0x20, 0x06, 0x02, 0x43, 'k', 'i', 'd', 0x63, 'f', 'o', 'o', 0x80, 0x21, 0x58, 0x20}
+	kid = append(kid, pub...)
+	kid = append(kid, 0x01, 0x01)
+	if decoded, err := FromCOSEKey(kid); err != nil || !pub.Equal(decoded) {
+		t.Errorf("expected the public key from the reordered map, got %#v", err)
+	}
+}
+
+func TestCOSEKeyErrors(t *testing.T) {
+	// an RSA key, kty 3
+	rsa, _ := hex.DecodeString("a10103")
+	if _, err := FromCOSEKey(rsa); !errors.Is(err, ErrUnsupportedCOSEKey) {
+		t.Errorf("expected ErrUnsupportedCOSEKey, got %#v", err)
+	}
+	for _, bad := range []string{"", "a2", "a201022001", "a4010220012158010022580100"} {
+		data, _ := hex.DecodeString(bad)
+		if _, err := FromCOSEKey(data); !errors.Is(err, ErrInvalidCOSEKey) {
+			t.Errorf("expected ErrInvalidCOSEKey for %s, got %#v", bad, err)
+		}
+	}
+	pems, err := ParsePEMs(test_rsakey)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if _, err := ToCOSEKey(pems.MustRSAPrivateKey()); err != ErrCannotEncode {
+		t.Errorf("expected ErrCannotEncode, got %#v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"time"
+)
+
+var ErrNoCRL = errors.New("no CRL from the certificate's issuer was found")
+
+// Reason codes from RFC 5280 section 5.3.1
+var crlReasons = map[int]string{
+	0:  "unspecified",
+	1:  "keyCompromise",
+	2:  "cACompromise",
+	3:  "affiliationChanged",
+	4:  "superseded",
+	5:  "cessationOfOperation",
+	6:  "certificateHold",
+	8:  "removeFromCRL",
+	9:  "privilegeWithdrawn",
+	10: "aACompromise",
+}
+
+// What a CRL says about a certificate
+type RevocationStatus struct {
+	Revoked bool
+	// When the certificate was revoked, if it was
+	RevokedAt time.Time
+	// The RFC 5280 reason code and its name, if the certificate was
+	// revoked
+	ReasonCode int
+	Reason     string
+	// The CRL which was consulted
+	CRL *x509.RevocationList
+	// Whether the CRL's signature was checked against its issuer.  This
+	// is false when the issuer's certificate wasn't among the parsed PEMs.
+	SignatureVerified bool
+	// Whether the CRL's next update time has passed, meaning a newer
+	// CRL should exist
+	Stale bool
+}
+
+func (p *ParsedPEMs) revocationLists() []*x509.RevocationList {
+	ret := []*x509.RevocationList{}
+	for _, obj := range p.all() {
+		if crl, ok := obj.(*x509.RevocationList); ok {
+			ret = append(ret, crl)
+		}
+	}
+	return ret
+}
+
+func crlCovers(crl *x509.RevocationList, cert *x509.Certificate) bool {
+	if !bytes.Equal(crl.RawIssuer, cert.RawIssuer) {
+		return false
+	}
+	return len(crl.AuthorityKeyId) == 0 || len(cert.AuthorityKeyId) == 0 || bytes.Equal(crl.AuthorityKeyId, cert.AuthorityKeyId)
+}
+
+// Check a CRL's signature against each of the candidate issuers,
+// succeeding if any of them signed it
+func crlSignedBy(crl *x509.RevocationList, issuers []*x509.Certificate) error {
+	var errs []error
+	for _, issuer := range issuers {
+		err := crl.CheckSignatureFrom(issuer)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Check whether a certificate has been revoked, according to the CRLs
+// remaining to be consumed in crls.
+//
+// If crls also holds certificates which issued cert, only CRLs whose
+// signature checks against one of them are considered, and the newest
+// of those is consulted.  An error is returned if none of them verify.
+// Without the issuer's certificate, the newest CRL from the issuer's
+// name is consulted unverified.  ErrNoCRL is returned if there's no
+// CRL from the issuer.  The parsed PEMs are not consumed.
+func CheckRevoked(cert *x509.Certificate, crls ParsedPEMs) (RevocationStatus, error) {
+	issuers := crls.IssuerOf(cert)
+	var crl *x509.RevocationList
+	var sigErr error
+	for _, c := range crls.revocationLists() {
+		if !crlCovers(c, cert) {
+			continue
+		}
+		if len(issuers) > 0 {
+			if err := crlSignedBy(c, issuers); err != nil {
+				sigErr = err
+				continue
+			}
+		}
+		if crl == nil || c.ThisUpdate.After(crl.ThisUpdate) {
+			crl = c
+		}
+	}
+	if crl == nil {
+		if sigErr != nil {
+			return RevocationStatus{}, sigErr
+		}
+		return RevocationStatus{}, ErrNoCRL
+	}
+	status := RevocationStatus{
+		CRL:               crl,
+		SignatureVerified: len(issuers) > 0,
+		Stale:             !crl.NextUpdate.IsZero() && crls.now().After(crl.NextUpdate),
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			status.Revoked = true
+			status.RevokedAt = entry.RevocationTime
+			status.ReasonCode = entry.ReasonCode
+			status.Reason = crlReasons[entry.ReasonCode]
+			break
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,139 @@
+package betterpem
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckRevoked(t *testing.T) {
+	c := newTestChain(t)
+	revokedAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: c.leaf.SerialNumber, RevocationTime: revokedAt, ReasonCode: 1},
+		},
+	}, c.intermediate, c.intermediateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crls, err := ParsePEMs(c.pem(t, crl, c.intermediate))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	status, err := CheckRevoked(c.leaf, crls)
+	if err != nil {
+		t.Fatalf("unexpected error checking revocation %#v", err)
+	}
+	if !status.Revoked || status.Reason != "keyCompromise" || !status.RevokedAt.Equal(revokedAt) || !status.SignatureVerified || status.Stale {
+		t.Errorf("unexpected status %#v", status)
+	}
+
+	sibling, _ := issueTestCert(t, &x509.Certificate{}, c.intermediate, c.intermediateKey)
+	if status, err := CheckRevoked(sibling, crls); err != nil || status.Revoked {
+		t.Errorf("expected an unrevoked certificate, got %#v %v", status, err)
+	}
+	later, err := ParsePEMs(c.pem(t, crl, c.intermediate), WithClock(func() time.Time { return time.Now().Add(2 * time.Hour) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := CheckRevoked(sibling, later); !status.Stale {
+		t.Error("expected the CRL to be stale")
+	}
+
+	if _, err := CheckRevoked(c.intermediate, crls); err != ErrNoCRL {
+		t.Errorf("expected ErrNoCRL, got %#v", err)
+	}
+
+	// a CRL with the intermediate's name, signed by someone else
+	impostor := newTestChain(t)
+	forged, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(2),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, &x509.Certificate{Subject: c.intermediate.Subject, KeyUsage: x509.KeyUsageCRLSign, SubjectKeyId: c.intermediate.SubjectKeyId}, impostor.intermediateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forgedCRL, err := x509.ParseRevocationList(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad, err := ParsePEMs(c.pem(t, crl, forgedCRL, c.intermediate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the newer forged CRL mustn't hide the older genuine one
+	status, err = CheckRevoked(c.leaf, bad)
+	if err != nil {
+		t.Fatalf("unexpected error checking revocation %#v", err)
+	}
+	if status.CRL.Number.Int64() != 1 || !status.Revoked || !status.SignatureVerified {
+		t.Errorf("expected the genuine CRL to be consulted, got %#v", status)
+	}
+	forgedOnly, err := ParsePEMs(c.pem(t, forgedCRL, c.intermediate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckRevoked(c.leaf, forgedOnly); err == nil {
+		t.Error("expected a signature error for a forged CRL")
+	}
+	unverified, err := ParsePEMs(c.pem(t, crl, forgedCRL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := CheckRevoked(c.leaf, unverified); err != nil || status.CRL.Number.Int64() != 2 || status.SignatureVerified {
+		t.Errorf("expected the newest CRL, unverified, got %#v %v", status, err)
+	}
+
+	// a cross-signed copy of the intermediate which may not sign CRLs,
+	// ahead of the one which may
+	cross := issueTestCertForKey(t, &x509.Certificate{
+		Subject:               c.intermediate.Subject,
+		SubjectKeyId:          c.intermediate.SubjectKeyId,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, c.root, c.rootKey, c.intermediateKey)
+	crossed, err := ParsePEMs(c.pem(t, crl, cross, c.intermediate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := CheckRevoked(c.leaf, crossed); err != nil || !status.Revoked || !status.SignatureVerified {
+		t.Errorf("expected a verified revocation, got %#v %v", status, err)
+	}
+}
+
+func TestParseCRL(t *testing.T) {
+	c := newTestChain(t)
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(7),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, c.root, c.rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := ParsePEMs(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if !strings.Contains(Describe(objs.objs[0]), "CRL Number: 7") {
+		t.Errorf("unexpected description %s", Describe(objs.objs[0]))
+	}
+	if crl := objs.MustRevocationList(); crl.Number.Int64() != 7 {
+		t.Errorf("unexpected crl %#v", crl)
+	}
+}
@@ -0,0 +1,79 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+)
+
+// What a CA operator reviews before signing a certificate signing request
+type CSRSummary struct {
+	Subject            string     `json:"subject"`
+	SANs               SANDetails `json:"sans"`
+	Key                KeyInfo    `json:"key"`
+	SignatureAlgorithm string     `json:"signature_algorithm"`
+	// Whether the request is signed by the key it contains, proving the
+	// requester holds the private key
+	SignatureValid bool `json:"signature_valid"`
+	// Why the signature is not valid, if it isn't
+	SignatureError error `json:"-"`
+}
+
+// Summarize a certificate signing request.
+func CSRInfo(csr *x509.CertificateRequest) CSRSummary {
+	err := csr.CheckSignature()
+	return CSRSummary{
+		Subject:            csr.Subject.String(),
+		SANs:               newSANDetails(csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, csr.URIs),
+		Key:                keyInfoOf(csr.PublicKey),
+		SignatureAlgorithm: csr.SignatureAlgorithm.String(),
+		SignatureValid:     err == nil,
+		SignatureError:     err,
+	}
+}
+
+// What to ask for in a certificate signing request made by CreateCSR
+type CSRRequest struct {
+	CommonName         string
+	Organization       []string
+	OrganizationalUnit []string
+	Country            []string
+	// DNS names, IP addresses, email addresses, and URIs, which are told
+	// apart by their form
+	SANs []string
+}
+
+// Make a certificate signing request for a key, returning it parsed and
+// as a CERTIFICATE REQUEST block.
+func CreateCSR(key crypto.Signer, req CSRRequest) (*x509.CertificateRequest, []byte, error) {
+	dns, ips, emails, uris, err := splitSANs(req.SANs)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:         req.CommonName,
+			Organization:       req.Organization,
+			OrganizationalUnit: req.OrganizationalUnit,
+			Country:            req.Country,
+		},
+		DNSNames:       dns,
+		IPAddresses:    ips,
+		EmailAddresses: emails,
+		URIs:           uris,
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	pemBytes, err := EncodePEM(csr, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return csr, pemBytes, nil
+}
@@ -0,0 +1,59 @@
+package betterpem
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCSRInfo(t *testing.T) {
+	objs, err := ParsePEMs(test_rsareq)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	csr := objs.MustCertificateRequest()
+	info := CSRInfo(csr)
+	if !info.SignatureValid {
+		t.Errorf("unexpected invalid signature: %v", info.SignatureError)
+	}
+	if info.Subject != csr.Subject.String() || info.Key.Algorithm != "RSA" || info.Key.Bits != 512 {
+		t.Errorf("unexpected summary %#v", info)
+	}
+	if len(info.SANs.DNS) != len(csr.DNSNames) {
+		t.Errorf("unexpected SANs %#v", info.SANs)
+	}
+
+	csr.Signature[len(csr.Signature)-1] ^= 0xff
+	if info := CSRInfo(csr); info.SignatureValid || info.SignatureError == nil {
+		t.Error("expected a tampered signature to be invalid")
+	}
+}
+
+func TestCreateCSR(t *testing.T) {
+	key, _, err := GenerateEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, csrPEM, err := CreateCSR(key, CSRRequest{
+		CommonName:   "api.example.com",
+		Organization: []string{"Example"},
+		SANs:         []string{"api.example.com", "192.0.2.1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating csr %#v", err)
+	}
+	objs, err := ParsePEMs(csrPEM)
+	if err != nil {
+		t.Fatalf("unexpected error parsing our own csr %#v", err)
+	}
+	parsed := objs.MustCertificateRequest()
+	info := CSRInfo(parsed)
+	if !info.SignatureValid || info.Key.Algorithm != "Ed25519" {
+		t.Errorf("unexpected summary %#v", info)
+	}
+	if parsed.Subject.CommonName != "api.example.com" || len(parsed.DNSNames) != 1 || len(parsed.IPAddresses) != 1 {
+		t.Errorf("unexpected request %#v", parsed)
+	}
+	if !bytes.Equal(parsed.Raw, csr.Raw) {
+		t.Error("parsed request and pem differ")
+	}
+}
@@ -0,0 +1,82 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// A string which is the same for two objects exactly when they encode to
+// the same bytes
+func identityOf(obj interface{}, block *pem.Block) string {
+	if block == nil {
+		var err error
+		block, err = blockFor(obj)
+		if err != nil {
+			// can't be compared, so it can't be a duplicate
+			return fmt.Sprintf("%p", obj)
+		}
+	}
+	return block.Type + "\x00" + string(block.Bytes)
+}
+
+// A string which is the same for two objects of the same kind with the
+// same public key: certificates, certificate requests, private keys, or
+// public keys
+func spkiIdentityOf(obj interface{}) (string, bool) {
+	var kind string
+	switch obj.(type) {
+	case *x509.Certificate:
+		kind = "certificate"
+	case *x509.CertificateRequest:
+		kind = "certificate request"
+	default:
+		kind = "public key"
+		if isPrivateKey(obj) {
+			kind = "private key"
+		}
+	}
+	pub, err := publicKeyOf(obj)
+	if err != nil {
+		return "", false
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", false
+	}
+	return kind + "\x00" + string(der), true
+}
+
+// Return a copy of the parsed PEMs remaining to be consumed without
+// duplicates, keeping the first of each in its original position.
+//
+// Objects are duplicates when they encode to the same bytes.  If bySPKI
+// is true, certificates are also duplicates of earlier certificates with
+// the same public key, such as a renewal of one, and private keys of
+// earlier private keys with the same public key, such as the same key in
+// another encoding.  Certificate requests and public keys likewise
+// duplicate earlier ones of their own kind.  Objects of different kinds,
+// such as a key and the request made from it, are never duplicates.  The
+// parsed PEMs are not consumed.
+func (p *ParsedPEMs) Dedupe(bySPKI bool) ParsedPEMs {
+	ret := ParsedPEMs{clock: p.clock}
+	seen := map[string]bool{}
+	v := p.parsed()
+	for i, obj := range v.objs {
+		ids := []string{identityOf(obj, v.blocks[i])}
+		if bySPKI {
+			if id, ok := spkiIdentityOf(obj); ok {
+				ids = append(ids, id)
+			}
+		}
+		dup := false
+		for _, id := range ids {
+			dup = dup || seen[id]
+			seen[id] = true
+		}
+		if !dup {
+			ret.add(obj, v.blocks[i])
+		}
+	}
+	return ret
+}
@@ -0,0 +1,61 @@
+package betterpem
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	c := newTestChain(t)
+	// a renewal, with the same key
+	renewed := issueTestCertForKey(t, &x509.Certificate{Subject: c.leaf.Subject}, c.intermediate, c.intermediateKey, c.leafKey)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(c.leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := append(c.pem(t, c.leaf, c.intermediate, c.leaf, c.leafKey, renewed, c.intermediate), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})...)
+	objs, err := ParsePEMs(input)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+
+	exact := objs.Dedupe(false)
+	if exact.Length() != 5 {
+		t.Errorf("expected 5 objects after removing exact duplicates, got %d", exact.Length())
+	}
+	spki := objs.Dedupe(true)
+	if spki.Length() != 3 {
+		t.Fatalf("expected 3 objects after removing SPKI duplicates, got %d", spki.Length())
+	}
+	if !spki.MustCertificate().Equal(c.leaf) || !spki.MustCertificate().Equal(c.intermediate) {
+		t.Error("the first of each certificate should be kept")
+	}
+	spki.MustECPrivateKey()
+	if objs.Length() != 7 {
+		t.Error("Dedupe consumed the parsed PEMs")
+	}
+}
+
+func TestDedupeKindsBySPKI(t *testing.T) {
+	c := newTestChain(t)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: c.leaf.Subject}, c.leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := ParsePEMs(c.pem(t, c.leafKey, csr, c.leaf))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	spki := objs.Dedupe(true)
+	if spki.Length() != 3 {
+		t.Fatalf("expected a key, its request, and its certificate to be kept, got %d objects", spki.Length())
+	}
+	spki.MustECPrivateKey()
+	spki.MustCertificateRequest()
+}
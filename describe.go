@@ -0,0 +1,346 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// A summary of a key's algorithm and strength
+type KeyInfo struct {
+	Algorithm string `json:"algorithm"`
+	Bits      int    `json:"bits"`
+	Curve     string `json:"curve,omitempty"`
+}
+
+func (k KeyInfo) String() string {
+	if k.Curve != "" {
+		return fmt.Sprintf("%s %s (%d bits)", k.Algorithm, k.Curve, k.Bits)
+	}
+	return fmt.Sprintf("%s (%d bits)", k.Algorithm, k.Bits)
+}
+
+func keyInfoOf(pub crypto.PublicKey) KeyInfo {
+	switch v := pub.(type) {
+	case *rsa.PublicKey:
+		return KeyInfo{Algorithm: "RSA", Bits: v.N.BitLen()}
+	case *ecdsa.PublicKey:
+		return KeyInfo{Algorithm: "ECDSA", Bits: v.Curve.Params().BitSize, Curve: v.Curve.Params().Name}
+	case ed25519.PublicKey:
+		return KeyInfo{Algorithm: "Ed25519", Bits: 256}
+	case *ecdh.PublicKey:
+		return KeyInfo{Algorithm: "ECDH", Bits: len(v.Bytes()) * 8, Curve: fmt.Sprint(v.Curve())}
+	}
+	return KeyInfo{Algorithm: fmt.Sprintf("%T", pub)}
+}
+
+// Summarize the key held by (or derived from) an object: a key, a
+// certificate, a certificate request, or an SSH public key.  False is
+// returned for objects without a key, such as CRLs, and keys of unknown
+// types.
+func KeyInfoOf(obj interface{}) (KeyInfo, bool) {
+	pub, err := publicKeyOf(obj)
+	if err != nil {
+		return KeyInfo{}, false
+	}
+	switch pub.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, *ecdh.PublicKey:
+		return keyInfoOf(pub), true
+	}
+	return KeyInfo{}, false
+}
+
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "Digital Signature"},
+	{x509.KeyUsageContentCommitment, "Non Repudiation"},
+	{x509.KeyUsageKeyEncipherment, "Key Encipherment"},
+	{x509.KeyUsageDataEncipherment, "Data Encipherment"},
+	{x509.KeyUsageKeyAgreement, "Key Agreement"},
+	{x509.KeyUsageCertSign, "Certificate Sign"},
+	{x509.KeyUsageCRLSign, "CRL Sign"},
+	{x509.KeyUsageEncipherOnly, "Encipher Only"},
+	{x509.KeyUsageDecipherOnly, "Decipher Only"},
+}
+
+func describeKeyUsage(ku x509.KeyUsage) []string {
+	ret := []string{}
+	for _, n := range keyUsageNames {
+		if ku&n.usage != 0 {
+			ret = append(ret, n.name)
+		}
+	}
+	return ret
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:                            "Any Extended Key Usage",
+	x509.ExtKeyUsageServerAuth:                     "TLS Web Server Authentication",
+	x509.ExtKeyUsageClientAuth:                     "TLS Web Client Authentication",
+	x509.ExtKeyUsageCodeSigning:                    "Code Signing",
+	x509.ExtKeyUsageEmailProtection:                "E-mail Protection",
+	x509.ExtKeyUsageIPSECEndSystem:                 "IPSec End System",
+	x509.ExtKeyUsageIPSECTunnel:                    "IPSec Tunnel",
+	x509.ExtKeyUsageIPSECUser:                      "IPSec User",
+	x509.ExtKeyUsageTimeStamping:                   "Time Stamping",
+	x509.ExtKeyUsageOCSPSigning:                    "OCSP Signing",
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     "Microsoft Server Gated Crypto",
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      "Netscape Server Gated Crypto",
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: "Microsoft Commercial Code Signing",
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     "Microsoft Kernel Code Signing",
+}
+
+func describeExtKeyUsage(cert *x509.Certificate) []string {
+	ret := []string{}
+	for _, eku := range cert.ExtKeyUsage {
+		if n, ok := extKeyUsageNames[eku]; ok {
+			ret = append(ret, n)
+		} else {
+			ret = append(ret, fmt.Sprintf("unknown (%d)", eku))
+		}
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		ret = append(ret, oid.String())
+	}
+	return ret
+}
+
+// Collect every subject alternative name on a certificate in the form
+// openssl prints them, such as "DNS:example.com" or "IP Address:10.0.0.1"
+func describeSANs(cert *x509.Certificate) []string {
+	ret := []string{}
+	for _, n := range cert.DNSNames {
+		ret = append(ret, "DNS:"+n)
+	}
+	for _, n := range cert.IPAddresses {
+		ret = append(ret, "IP Address:"+n.String())
+	}
+	for _, n := range cert.EmailAddresses {
+		ret = append(ret, "email:"+n)
+	}
+	for _, n := range cert.URIs {
+		ret = append(ret, "URI:"+n.String())
+	}
+	return ret
+}
+
+var extensionNames = map[string]string{
+	"2.5.29.14":               "Subject Key Identifier",
+	"2.5.29.15":               "Key Usage",
+	"2.5.29.17":               "Subject Alternative Name",
+	"2.5.29.19":               "Basic Constraints",
+	"2.5.29.30":               "Name Constraints",
+	"2.5.29.31":               "CRL Distribution Points",
+	"2.5.29.32":               "Certificate Policies",
+	"2.5.29.35":               "Authority Key Identifier",
+	"2.5.29.37":               "Extended Key Usage",
+	"1.3.6.1.5.5.7.1.1":       "Authority Information Access",
+	"1.3.6.1.4.1.11129.2.4.2": "CT Precertificate SCTs",
+	"1.3.6.1.4.1.11129.2.4.3": "CT Precertificate Poison",
+}
+
+func extensionName(oid asn1.ObjectIdentifier) string {
+	if n, ok := extensionNames[oid.String()]; ok {
+		return n
+	}
+	return oid.String()
+}
+
+func colonHex(b []byte) string {
+	return Digest{Sum: b}.Colon()
+}
+
+// Format a serial number the way openssl does, with a zero serial as 00
+// and a negative one as its magnitude marked (Negative)
+func serialHex(serial *big.Int) string {
+	switch serial.Sign() {
+	case 0:
+		return "00"
+	case -1:
+		return "(Negative)" + colonHex(new(big.Int).Neg(serial).Bytes())
+	}
+	return colonHex(serial.Bytes())
+}
+
+func opensslTime(t time.Time) string {
+	return t.UTC().Format("Jan _2 15:04:05 2006 GMT")
+}
+
+// Describe a certificate's extensions, one entry per extension with its
+// value lines indented beneath it.
+func describeExtensions(cert *x509.Certificate) []string {
+	ret := []string{}
+	for _, ext := range cert.Extensions {
+		name := extensionName(ext.Id)
+		if ext.Critical {
+			name += ": critical"
+		} else {
+			name += ":"
+		}
+		values := []string{}
+		switch ext.Id.String() {
+		case "2.5.29.14":
+			values = append(values, colonHex(cert.SubjectKeyId))
+		case "2.5.29.35":
+			values = append(values, colonHex(cert.AuthorityKeyId))
+		case "2.5.29.15":
+			values = append(values, strings.Join(describeKeyUsage(cert.KeyUsage), ", "))
+		case "2.5.29.37":
+			values = append(values, strings.Join(describeExtKeyUsage(cert), ", "))
+		case "2.5.29.17":
+			values = append(values, strings.Join(describeSANs(cert), ", "))
+		case "2.5.29.19":
+			if cert.IsCA {
+				bc := "CA:TRUE"
+				if cert.MaxPathLen > 0 || cert.MaxPathLenZero {
+					bc += fmt.Sprintf(", pathlen:%d", cert.MaxPathLen)
+				}
+				values = append(values, bc)
+			} else {
+				values = append(values, "CA:FALSE")
+			}
+		case "2.5.29.31":
+			for _, u := range cert.CRLDistributionPoints {
+				values = append(values, "URI:"+u)
+			}
+		case "1.3.6.1.5.5.7.1.1":
+			for _, u := range cert.OCSPServer {
+				values = append(values, "OCSP - URI:"+u)
+			}
+			for _, u := range cert.IssuingCertificateURL {
+				values = append(values, "CA Issuers - URI:"+u)
+			}
+		case "2.5.29.32":
+			for _, p := range cert.PolicyIdentifiers {
+				values = append(values, "Policy: "+p.String())
+			}
+		default:
+			values = append(values, hex.EncodeToString(ext.Value))
+		}
+		ret = append(ret, name)
+		for _, v := range values {
+			ret = append(ret, "    "+v)
+		}
+	}
+	return ret
+}
+
+func describeCertificate(cert *x509.Certificate) string {
+	lines := []string{
+		"Certificate:",
+		"    Version: " + fmt.Sprint(cert.Version),
+		"    Serial Number: " + serialHex(cert.SerialNumber),
+		"    Signature Algorithm: " + cert.SignatureAlgorithm.String(),
+		"    Issuer: " + cert.Issuer.String(),
+		"    Validity:",
+		"        Not Before: " + opensslTime(cert.NotBefore),
+		"        Not After : " + opensslTime(cert.NotAfter),
+		"    Subject: " + cert.Subject.String(),
+		"    Subject Public Key Info:",
+		"        Public Key: " + keyInfoOf(cert.PublicKey).String(),
+	}
+	if exts := describeExtensions(cert); len(exts) > 0 {
+		lines = append(lines, "    X509v3 extensions:")
+		for _, e := range exts {
+			lines = append(lines, "        "+e)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func describeCertificateRequest(csr *x509.CertificateRequest) string {
+	info := CSRInfo(csr)
+	valid := "ok"
+	if !info.SignatureValid {
+		valid = info.SignatureError.Error()
+	}
+	lines := []string{
+		"Certificate Request:",
+		"    Subject: " + info.Subject,
+		"    Public Key: " + info.Key.String(),
+		"    Signature Algorithm: " + info.SignatureAlgorithm,
+		"    Signature: " + valid,
+	}
+	if sans := describeRequestSANs(csr); len(sans) > 0 {
+		lines = append(lines, "    Requested Subject Alternative Names:", "        "+strings.Join(sans, ", "))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func describeRequestSANs(csr *x509.CertificateRequest) []string {
+	return describeSANs(&x509.Certificate{
+		DNSNames:       csr.DNSNames,
+		IPAddresses:    csr.IPAddresses,
+		EmailAddresses: csr.EmailAddresses,
+		URIs:           csr.URIs,
+	})
+}
+
+func describeRevocationList(crl *x509.RevocationList) string {
+	lines := []string{
+		"Certificate Revocation List:",
+		"    Issuer: " + crl.Issuer.String(),
+		"    This Update: " + opensslTime(crl.ThisUpdate),
+	}
+	if !crl.NextUpdate.IsZero() {
+		lines = append(lines, "    Next Update: "+opensslTime(crl.NextUpdate))
+	}
+	if crl.Number != nil {
+		lines = append(lines, "    CRL Number: "+crl.Number.String())
+	}
+	lines = append(lines, fmt.Sprintf("    Revoked Certificates: %d", len(crl.RevokedCertificateEntries)))
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Describe a parsed PEM object in human readable form.
+//
+// Certificates are described much like openssl x509 -text would, without
+// the hex dumps of keys and signatures.  Keys are described by their type
+// and size.
+func Describe(obj interface{}) string {
+	switch v := obj.(type) {
+	case *x509.Certificate:
+		return describeCertificate(v)
+	case *x509.CertificateRequest:
+		return describeCertificateRequest(v)
+	case *x509.RevocationList:
+		return describeRevocationList(v)
+	case crypto.Signer:
+		return "Private Key:\n    Key: " + keyInfoOf(v.Public()).String() + "\n"
+	case *ecdh.PrivateKey:
+		return "Private Key:\n    Key: " + keyInfoOf(v.PublicKey()).String() + "\n"
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, *ecdh.PublicKey:
+		return "Public Key:\n    Key: " + keyInfoOf(v).String() + "\n"
+	}
+	return fmt.Sprintf("%T\n", obj)
+}
+
+// Write a description of every parsed PEM remaining to be consumed to w,
+// separated by blank lines.
+//
+// The parsed PEMs are not consumed.  See Describe for the format.
+func (p *ParsedPEMs) Dump(w io.Writer) error {
+	for i, obj := range p.all() {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, Describe(obj)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
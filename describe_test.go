@@ -0,0 +1,76 @@
+package betterpem
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	objs, err := ParsePEMs(bytes.Join([][]byte{test_rsacert, test_eckey}, []byte{'\n'}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	var buf bytes.Buffer
+	if err := objs.Dump(&buf); err != nil {
+		t.Fatalf("unexpected error dumping %#v", err)
+	}
+	out := buf.String()
+	t.Log(out)
+	cert := objs.MustCertificate()
+	for _, want := range []string{
+		"Certificate:",
+		"Subject: " + cert.Subject.String(),
+		"Issuer: " + cert.Issuer.String(),
+		"Public Key: RSA (512 bits)",
+		"Private Key:\n    Key: ECDSA P-521 (521 bits)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dump is missing %q", want)
+		}
+	}
+	if objs.Length() != 1 {
+		t.Error("Dump consumed the parsed PEMs")
+	}
+}
+
+func TestDescribeCertificateRequest(t *testing.T) {
+	objs, err := ParsePEMs(test_rsareq)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	out := Describe(objs.MustCertificateRequest())
+	if !strings.HasPrefix(out, "Certificate Request:") || !strings.Contains(out, "Signature: ok") {
+		t.Errorf("unexpected description:\n%s", out)
+	}
+}
+
+func TestSerialHex(t *testing.T) {
+	for serial, want := range map[int64]string{
+		0:       "00",
+		1:       "01",
+		0x1234:  "12:34",
+		-0x1234: "(Negative)12:34",
+	} {
+		if got := serialHex(big.NewInt(serial)); got != want {
+			t.Errorf("serial %d: expected %q, got %q", serial, want, got)
+		}
+	}
+}
+
+func TestKeyInfoOf(t *testing.T) {
+	objs, err := ParsePEMs(bytes.Join([][]byte{test_rsacert, test_eckey}, []byte{'\n'}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if info, ok := KeyInfoOf(objs.MustCertificate()); !ok || info.String() != "RSA (512 bits)" {
+		t.Errorf("unexpected certificate key info %v", info)
+	}
+	if info, ok := KeyInfoOf(objs.MustECPrivateKey()); !ok || info.Curve != "P-521" {
+		t.Errorf("unexpected private key info %v", info)
+	}
+	if _, ok := KeyInfoOf("not a key"); ok {
+		t.Error("expected no key info for a string")
+	}
+}
@@ -0,0 +1,48 @@
+package betterpem
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+)
+
+var ErrInvalidDHParameters = errors.New("invalid DH parameters")
+
+// Diffie-Hellman parameters, as openssl dhparam writes them in a
+// "DH PARAMETERS" block
+type DHParameters struct {
+	P *big.Int
+	G *big.Int
+	// The DER the parameters were parsed from
+	Raw []byte
+}
+
+type dhParameters struct {
+	P                  *big.Int
+	G                  *big.Int
+	PrivateValueLength int `asn1:"optional"`
+}
+
+// Return the size of the prime in bits
+func (d *DHParameters) Bits() int {
+	return d.P.BitLen()
+}
+
+// Parse a "DH PARAMETERS" block into *DHParameters, as ParsePEMs does.
+// It is a BlockParser, for use with WithBlockType or RegisterBlockType.
+func ParseDHParametersBlock(block *pem.Block) (interface{}, error) {
+	return parseDHParameters(block.Bytes)
+}
+
+func parseDHParameters(der []byte) (*DHParameters, error) {
+	var params dhParameters
+	rest, err := asn1.Unmarshal(der, &params)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 || params.P == nil || params.G == nil || params.P.Sign() <= 0 || params.G.Sign() <= 0 {
+		return nil, ErrInvalidDHParameters
+	}
+	return &DHParameters{P: params.P, G: params.G, Raw: der}, nil
+}
@@ -0,0 +1,131 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+)
+
+// A certificate in one bundle which replaced a certificate in another
+type CertificateChange struct {
+	Old *x509.Certificate
+	New *x509.Certificate
+}
+
+// The differences between two bundles, as found by DiffBundles
+type BundleDiff struct {
+	// Objects only in the new bundle, in its order
+	Added []interface{}
+	// Objects only in the old bundle, in its order
+	Removed []interface{}
+	// Certificates replaced by one for the same public key
+	Renewed []CertificateChange
+	// Certificates replaced by one for the same subject with a new key
+	Rekeyed []CertificateChange
+}
+
+// Whether the bundles held the same objects
+func (d *BundleDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Renewed) == 0 && len(d.Rekeyed) == 0
+}
+
+// A string which is the same for two objects which should be considered
+// unchanged between bundles
+func diffIdentityOf(obj interface{}, block *pem.Block) string {
+	if _, ok := obj.(*x509.Certificate); !ok {
+		if id, ok := spkiIdentityOf(obj); ok && isPrivateKey(obj) {
+			return id
+		}
+	}
+	return identityOf(obj, block)
+}
+
+// Compare the parsed PEMs remaining to be consumed in two bundles, such
+// as a bundle before and after a certificate rotation.
+//
+// Objects found in both bundles are unchanged.  Private keys are
+// unchanged when the public key is, even if they were encoded
+// differently.  A certificate only in the new bundle renews one only in
+// the old bundle with the same public key, or re-keys one with the same
+// subject; otherwise it was added.  Whatever is left only in the old
+// bundle was removed.  Neither bundle's parsed PEMs are consumed.
+func DiffBundles(before, after ParsedPEMs) BundleDiff {
+	before, after = before.parsed(), after.parsed()
+	diff := BundleDiff{Added: []interface{}{}, Removed: []interface{}{}, Renewed: []CertificateChange{}, Rekeyed: []CertificateChange{}}
+	inBefore := map[string]bool{}
+	for i, obj := range before.objs {
+		inBefore[diffIdentityOf(obj, before.blocks[i])] = true
+	}
+	inAfter := map[string]bool{}
+	for i, obj := range after.objs {
+		inAfter[diffIdentityOf(obj, after.blocks[i])] = true
+	}
+
+	// certificates only in the old bundle, until they're matched
+	oldCerts := []*x509.Certificate{}
+	removed := []interface{}{}
+	for i, obj := range before.objs {
+		if inAfter[diffIdentityOf(obj, before.blocks[i])] {
+			continue
+		}
+		if cert, ok := obj.(*x509.Certificate); ok {
+			oldCerts = append(oldCerts, cert)
+		}
+		removed = append(removed, obj)
+	}
+	matched := map[*x509.Certificate]bool{}
+	match := func(same func(a, b *x509.Certificate) bool, cert *x509.Certificate) *x509.Certificate {
+		for _, old := range oldCerts {
+			if !matched[old] && same(old, cert) {
+				matched[old] = true
+				return old
+			}
+		}
+		return nil
+	}
+	sameKey := func(a, b *x509.Certificate) bool {
+		return bytes.Equal(a.RawSubjectPublicKeyInfo, b.RawSubjectPublicKeyInfo)
+	}
+	sameSubject := func(a, b *x509.Certificate) bool {
+		return bytes.Equal(a.RawSubject, b.RawSubject)
+	}
+
+	renewed := map[*x509.Certificate]bool{}
+	added := []interface{}{}
+	for i, obj := range after.objs {
+		if inBefore[diffIdentityOf(obj, after.blocks[i])] {
+			continue
+		}
+		if cert, ok := obj.(*x509.Certificate); ok {
+			if old := match(sameKey, cert); old != nil {
+				diff.Renewed = append(diff.Renewed, CertificateChange{Old: old, New: cert})
+				renewed[cert] = true
+			}
+		}
+		added = append(added, obj)
+	}
+	// re-keys are only looked for once every renewal has been matched so a
+	// renewal is never mistaken for one
+	for _, obj := range added {
+		cert, ok := obj.(*x509.Certificate)
+		if !ok {
+			diff.Added = append(diff.Added, obj)
+			continue
+		}
+		if renewed[cert] {
+			continue
+		}
+		if old := match(sameSubject, cert); old != nil {
+			diff.Rekeyed = append(diff.Rekeyed, CertificateChange{Old: old, New: cert})
+			continue
+		}
+		diff.Added = append(diff.Added, cert)
+	}
+	for _, obj := range removed {
+		if cert, ok := obj.(*x509.Certificate); ok && matched[cert] {
+			continue
+		}
+		diff.Removed = append(diff.Removed, obj)
+	}
+	return diff
+}
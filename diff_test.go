@@ -0,0 +1,60 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestDiffBundles(t *testing.T) {
+	c := newTestChain(t)
+	gone, _ := issueTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "gone"}}, c.intermediate, c.intermediateKey)
+	fresh, _ := issueTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "fresh"}}, c.intermediate, c.intermediateKey)
+	renewed := issueTestCertForKey(t, &x509.Certificate{
+		Subject:  c.leaf.Subject,
+		DNSNames: c.leaf.DNSNames,
+		NotAfter: time.Now().Add(48 * time.Hour),
+	}, c.intermediate, c.intermediateKey, c.leafKey)
+	rekeyed, _ := issueTestCert(t, &x509.Certificate{
+		Subject:               c.intermediate.Subject,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, c.root, c.rootKey)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(c.leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := ParsePEMs(c.pem(t, c.leaf, c.intermediate, c.root, gone, c.leafKey))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	afterPEM := append(c.pem(t, renewed, rekeyed, c.root, fresh), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})...)
+	after, err := ParsePEMs(afterPEM)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+
+	diff := DiffBundles(before, after)
+	if len(diff.Renewed) != 1 || !diff.Renewed[0].Old.Equal(c.leaf) || !diff.Renewed[0].New.Equal(renewed) {
+		t.Errorf("unexpected renewals %v", diff.Renewed)
+	}
+	if len(diff.Rekeyed) != 1 || !diff.Rekeyed[0].Old.Equal(c.intermediate) || !diff.Rekeyed[0].New.Equal(rekeyed) {
+		t.Errorf("unexpected re-keys %v", diff.Rekeyed)
+	}
+	if len(diff.Added) != 1 || !diff.Added[0].(*x509.Certificate).Equal(fresh) {
+		t.Errorf("unexpected additions %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || !diff.Removed[0].(*x509.Certificate).Equal(gone) {
+		t.Errorf("unexpected removals %v", diff.Removed)
+	}
+	if before.Length() != 5 || after.Length() != 5 {
+		t.Error("diffing consumed the parsed PEMs")
+	}
+
+	if same := DiffBundles(before, before); !same.Empty() {
+		t.Errorf("a bundle differs from itself: %#v", same)
+	}
+}
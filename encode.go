@@ -0,0 +1,200 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"sort"
+	"strings"
+)
+
+var ErrCannotEncode = errors.New("object cannot be encoded as PEM")
+var ErrInvalidHeader = errors.New("pem header names must be non-empty without colons, and names and values may not contain line breaks or start or end with whitespace")
+
+// Build the PEM block an object is conventionally stored in.
+//
+// RSA and ECDSA private keys get their traditional PKCS#1 and SEC 1
+// blocks, other private keys get PKCS#8, and public keys get PKIX.
+func blockFor(obj interface{}) (*pem.Block, error) {
+	switch v := obj.(type) {
+	case *x509.Certificate:
+		return &pem.Block{Type: "CERTIFICATE", Bytes: v.Raw}, nil
+	case *x509.CertificateRequest:
+		return &pem.Block{Type: "CERTIFICATE REQUEST", Bytes: v.Raw}, nil
+	case *x509.RevocationList:
+		return &pem.Block{Type: "X509 CRL", Bytes: v.Raw}, nil
+	case *DHParameters:
+		return &pem.Block{Type: "DH PARAMETERS", Bytes: v.Raw}, nil
+	case *rsa.PrivateKey:
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(v)}, nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(v)
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, nil
+	}
+	if der, err := x509.MarshalPKCS8PrivateKey(obj); err == nil {
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+	}
+	if der, err := x509.MarshalPKIXPublicKey(obj); err == nil {
+		return &pem.Block{Type: "PUBLIC KEY", Bytes: der}, nil
+	}
+	return nil, ErrCannotEncode
+}
+
+type encodeOptions struct {
+	lineWidth         int
+	eol               string
+	noTrailingNewline bool
+}
+
+// An option controlling how PEM is written
+type EncodeOption func(*encodeOptions)
+
+// Wrap base64 lines at n characters instead of the usual 64.
+//
+// 76 is the other common choice, from MIME.
+func WithLineWidth(n int) EncodeOption {
+	return func(o *encodeOptions) {
+		o.lineWidth = n
+	}
+}
+
+// End lines with CRLF instead of LF
+func WithCRLF() EncodeOption {
+	return func(o *encodeOptions) {
+		o.eol = "\r\n"
+	}
+}
+
+// Leave off the line ending after the final END line
+func WithoutTrailingNewline() EncodeOption {
+	return func(o *encodeOptions) {
+		o.noTrailingNewline = true
+	}
+}
+
+func newEncodeOptions(opts []EncodeOption) encodeOptions {
+	o := encodeOptions{lineWidth: 64, eol: "\n"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.lineWidth <= 0 {
+		o.lineWidth = 64
+	}
+	return o
+}
+
+// Whether a header will read back as it was written.  A line break
+// would end the header early, and could start a block of its own, while
+// surrounding whitespace is trimmed when the header is read.
+func validHeader(name, value string) bool {
+	if name == "" || strings.Contains(name, ":") {
+		return false
+	}
+	for _, s := range []string{name, value} {
+		if strings.ContainsAny(s, "\r\n") || strings.TrimSpace(s) != s {
+			return false
+		}
+	}
+	return true
+}
+
+// Write a block the way pem.Encode would, but with configurable wrapping
+// and line endings.  The final line ending is left to the caller.
+func writeBlock(buf *bytes.Buffer, block *pem.Block, o encodeOptions) error {
+	for k, v := range block.Headers {
+		if !validHeader(k, v) {
+			return ErrInvalidHeader
+		}
+	}
+	buf.WriteString("-----BEGIN " + block.Type + "-----" + o.eol)
+	if len(block.Headers) > 0 {
+		// like pem.Encode, Proc-Type must come first
+		keys := make([]string, 0, len(block.Headers))
+		for k := range block.Headers {
+			if k != "Proc-Type" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		if v, ok := block.Headers["Proc-Type"]; ok {
+			buf.WriteString("Proc-Type: " + v + o.eol)
+		}
+		for _, k := range keys {
+			buf.WriteString(k + ": " + block.Headers[k] + o.eol)
+		}
+		buf.WriteString(o.eol)
+	}
+	b64 := base64.StdEncoding.EncodeToString(block.Bytes)
+	for len(b64) > 0 {
+		n := o.lineWidth
+		if n > len(b64) {
+			n = len(b64)
+		}
+		buf.WriteString(b64[:n] + o.eol)
+		b64 = b64[n:]
+	}
+	buf.WriteString("-----END " + block.Type + "-----")
+	return nil
+}
+
+func encodeBlocks(blocks []*pem.Block, opts []EncodeOption) ([]byte, error) {
+	o := newEncodeOptions(opts)
+	var buf bytes.Buffer
+	for i, block := range blocks {
+		if err := writeBlock(&buf, block, o); err != nil {
+			return nil, err
+		}
+		if i < len(blocks)-1 || !o.noTrailingNewline {
+			buf.WriteString(o.eol)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Encode a certificate or key as PEM.
+//
+// Headers are optional and are written into the block as "Key: value"
+// lines, which ParsePEMs preserves and ParsedPEMs.Headers gives back.
+// Header names may not be empty or contain a colon, and neither names
+// nor values may contain line breaks or start or end with whitespace;
+// ErrInvalidHeader is returned for any which do.
+//
+// See the EncodeOption functions for controlling the line width and
+// line endings.
+func EncodePEM(obj interface{}, headers map[string]string, opts ...EncodeOption) ([]byte, error) {
+	block, err := blockFor(obj)
+	if err != nil {
+		return nil, err
+	}
+	block.Headers = headers
+	return encodeBlocks([]*pem.Block{block}, opts)
+}
+
+// Encode every parsed PEM remaining to be consumed back into PEM, in
+// order.
+//
+// Objects which were parsed from a block are written as that block,
+// keeping its type and headers.  The parsed PEMs are not consumed.
+func (p *ParsedPEMs) Encode(opts ...EncodeOption) ([]byte, error) {
+	v := p.parsed()
+	blocks := make([]*pem.Block, 0, len(v.objs))
+	for i, obj := range v.objs {
+		block := v.blocks[i]
+		if block == nil {
+			var err error
+			block, err = blockFor(obj)
+			if err != nil {
+				return nil, err
+			}
+		}
+		blocks = append(blocks, block)
+	}
+	return encodeBlocks(blocks, opts)
+}
@@ -0,0 +1,98 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/pem"
+	"testing"
+)
+
+func TestEncodePEMHeaders(t *testing.T) {
+	objs, err := ParsePEMs(test_eccert)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	cert := objs.MustCertificate()
+	headers := map[string]string{"friendlyName": "my cert", "X-Provenance": "unit test"}
+	encoded, err := EncodePEM(cert, headers)
+	if err != nil {
+		t.Fatalf("unexpected error encoding pem %#v", err)
+	}
+
+	objs, err = ParsePEMs(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error parsing our own pem %#v", err)
+	}
+	got := objs.Headers()
+	if len(got) != len(headers) || got["friendlyName"] != "my cert" || got["X-Provenance"] != "unit test" {
+		t.Errorf("headers did not survive the round trip: %#v", got)
+	}
+	reencoded, err := objs.Encode()
+	if err != nil {
+		t.Fatalf("unexpected error encoding parsed pems %#v", err)
+	}
+	if !bytes.Equal(encoded, reencoded) {
+		t.Errorf("re-encoding changed the pem:\n%s\n%s", encoded, reencoded)
+	}
+	if !objs.MustCertificate().Equal(cert) {
+		t.Error("certificate did not survive the round trip")
+	}
+	for _, bad := range []map[string]string{
+		{"bad:name": "x"},
+		{"": "x"},
+		{"bad\nname": "x"},
+		{"bad\rname": "x"},
+		{" padded": "x"},
+		{"padded\t": "x"},
+		{"friendlyName": "a\n-----END CERTIFICATE-----\n-----BEGIN CERTIFICATE-----"},
+		{"friendlyName": "a\rb"},
+		{"friendlyName": " padded"},
+		{"friendlyName": "padded\t"},
+	} {
+		if _, err := EncodePEM(cert, bad); err != ErrInvalidHeader {
+			t.Errorf("expected ErrInvalidHeader for %q, got %#v", bad, err)
+		}
+	}
+	if _, err := EncodePEM(cert, map[string]string{"friendlyName": ""}); err != nil {
+		t.Errorf("unexpected error for an empty header value %#v", err)
+	}
+}
+
+func TestEncodeOptions(t *testing.T) {
+	objs, err := ParsePEMs(test_eccert)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	cert := objs.MustCertificate()
+
+	std, err := EncodePEM(cert, nil)
+	if err != nil {
+		t.Fatalf("unexpected error encoding pem %#v", err)
+	}
+	if !bytes.Equal(std, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})) {
+		t.Errorf("default encoding differs from encoding/pem:\n%s", std)
+	}
+
+	wide, err := EncodePEM(cert, map[string]string{"a": "b"}, WithLineWidth(76), WithCRLF(), WithoutTrailingNewline())
+	if err != nil {
+		t.Fatalf("unexpected error encoding pem %#v", err)
+	}
+	if bytes.HasSuffix(wide, []byte("\n")) {
+		t.Error("expected no trailing newline")
+	}
+	lines := bytes.Split(wide, []byte("\r\n"))
+	for _, line := range lines {
+		if bytes.ContainsRune(line, '\n') {
+			t.Fatalf("found a bare LF in %q", wide)
+		}
+	}
+	if len(lines[3]) != 76 {
+		t.Errorf("expected 76 character lines, got %q", lines[3])
+	}
+	objs, err = ParsePEMs(wide)
+	if err != nil {
+		t.Fatalf("unexpected error parsing our own pem %#v", err)
+	}
+	if !objs.MustCertificate().Equal(cert) {
+		t.Error("certificate did not survive the round trip")
+	}
+}
@@ -0,0 +1,172 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var ErrUnsupportedEncryption = errors.New("private key is encrypted in an unsupported way")
+var ErrBadIterationCount = errors.New("private key's PBKDF2 iteration count is out of range")
+
+// The most PBKDF2 iterations an encrypted key may ask for, so a hostile
+// key can't pin a CPU on every parse.  OpenSSL writes 2048, and even
+// today's recommended counts are well below this.
+const maxPBKDF2Iterations = 10_000_000
+
+var (
+	oidPBES2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+var pbkdf2PRFs = map[string]func() hash.Hash{
+	oidHMACSHA1.String():   sha1.New,
+	oidHMACSHA256.String(): sha256.New,
+	oidHMACSHA512.String(): sha512.New,
+}
+
+var pbes2KeySizes = map[string]int{
+	oidAES128CBC.String(): 16,
+	oidAES192CBC.String(): 24,
+	oidAES256CBC.String(): 32,
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// Decrypt an "ENCRYPTED PRIVATE KEY" block's PKCS #8 contents, as written
+// by openssl pkcs8 -topk8 with PBES2, PBKDF2, and AES-CBC.  The result is
+// unencrypted PKCS #8.
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, ErrUnsupportedEncryption
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, ErrUnsupportedEncryption
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, err
+	}
+	if kdf.IterationCount <= 0 || kdf.IterationCount > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("%w: %d", ErrBadIterationCount, kdf.IterationCount)
+	}
+	prf := sha1.New
+	if len(kdf.PRF.Algorithm) > 0 {
+		var ok bool
+		if prf, ok = pbkdf2PRFs[kdf.PRF.Algorithm.String()]; !ok {
+			return nil, ErrUnsupportedEncryption
+		}
+	}
+	keySize, ok := pbes2KeySizes[params.EncryptionScheme.Algorithm.String()]
+	if !ok {
+		return nil, ErrUnsupportedEncryption
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize || len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, ErrUnsupportedEncryption
+	}
+	block, err := aes.NewCipher(pbkdf2.Key(passphrase, kdf.Salt, kdf.IterationCount, keySize, prf))
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, info.EncryptedData)
+	// a wrong passphrase almost always leaves bad padding
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(plain[len(plain)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, x509.IncorrectPasswordError
+	}
+	return plain[:len(plain)-pad], nil
+}
+
+// Whether a block holds an encrypted private key WithPassphraseFunc can
+// decrypt.  The legacy encryption x509 deprecated is still used for
+// plenty of keys, so it is supported.
+func isEncryptedBlock(block *pem.Block) bool {
+	return block.Type == "ENCRYPTED PRIVATE KEY" || x509.IsEncryptedPEMBlock(block)
+}
+
+// Decrypt a block isEncryptedBlock accepts into the unencrypted block it
+// would have been
+func decryptBlock(block *pem.Block, passphrase []byte) (*pem.Block, error) {
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		der, err := decryptPKCS8(block.Bytes, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+	}
+	der, err := x509.DecryptPEMBlock(block, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &pem.Block{Type: block.Type, Bytes: der}, nil
+}
+
+// Find the parser for a block, which first decrypts it if it is
+// encrypted and there is a passphrase function to decrypt it with.  It is
+// nil when there is none.
+func (o *parseOptions) parserFor(der *pem.Block, info BlockInfo) BlockParser {
+	if o.passphrase == nil || !isEncryptedBlock(der) {
+		return o.plainParserFor(der.Type)
+	}
+	return func(der *pem.Block) (interface{}, error) {
+		passphrase, err := o.passphrase(info)
+		if err != nil {
+			return nil, err
+		}
+		plain, err := decryptBlock(der, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		parse := o.plainParserFor(plain.Type)
+		if parse == nil {
+			return nil, &ErrUnsupportedBlockType{Type: plain.Type}
+		}
+		return parse(plain)
+	}
+}
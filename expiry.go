@@ -0,0 +1,66 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"time"
+)
+
+// When one certificate is valid, relative to the time of an ExpiryReport
+type CertificateExpiry struct {
+	Certificate *x509.Certificate
+	NotBefore   time.Time
+	NotAfter    time.Time
+	// Time left until NotAfter, negative once expired
+	Remaining    time.Duration
+	NotYetValid  bool
+	Expired      bool
+	ExpiringSoon bool
+}
+
+// The validity of every certificate in a bundle
+type ExpiryReport struct {
+	Certificates []CertificateExpiry
+	// The certificate which expires first, or nil when there are none
+	Soonest *CertificateExpiry
+}
+
+// Whether any certificate has expired, is not yet valid, or expires soon
+func (r *ExpiryReport) NeedsAttention() bool {
+	for _, c := range r.Certificates {
+		if c.Expired || c.NotYetValid || c.ExpiringSoon {
+			return true
+		}
+	}
+	return false
+}
+
+// Report on when each certificate remaining to be consumed is valid, as
+// of now, or as of the parsed PEMs' clock if now is zero; see WithClock.
+//
+// Certificates which expire within warnWithin of now, but haven't yet,
+// are marked as expiring soon.  Certificates are listed in order.  The
+// parsed PEMs are not consumed.
+func (p *ParsedPEMs) ExpiryReport(now time.Time, warnWithin time.Duration) ExpiryReport {
+	if now.IsZero() {
+		now = p.now()
+	}
+	report := ExpiryReport{Certificates: []CertificateExpiry{}}
+	for _, c := range p.certificates() {
+		remaining := c.NotAfter.Sub(now)
+		report.Certificates = append(report.Certificates, CertificateExpiry{
+			Certificate:  c,
+			NotBefore:    c.NotBefore,
+			NotAfter:     c.NotAfter,
+			Remaining:    remaining,
+			NotYetValid:  now.Before(c.NotBefore),
+			Expired:      now.After(c.NotAfter),
+			ExpiringSoon: !now.After(c.NotAfter) && remaining <= warnWithin,
+		})
+	}
+	for i := range report.Certificates {
+		if report.Soonest == nil || report.Certificates[i].NotAfter.Before(report.Soonest.NotAfter) {
+			report.Soonest = &report.Certificates[i]
+		}
+	}
+	return report
+}
@@ -0,0 +1,37 @@
+package betterpem
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryReport(t *testing.T) {
+	c := newTestChain(t)
+	objs, err := ParsePEMs(c.pem(t, c.root, c.intermediate, c.leaf))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+
+	report := objs.ExpiryReport(time.Now(), time.Hour)
+	if len(report.Certificates) != 3 || report.NeedsAttention() {
+		t.Errorf("unexpected report %#v", report)
+	}
+	if report.Soonest == nil {
+		t.Fatal("expected a soonest certificate")
+	}
+
+	report = objs.ExpiryReport(time.Now(), 48*time.Hour)
+	if !report.NeedsAttention() || !report.Certificates[2].ExpiringSoon || report.Certificates[2].Expired {
+		t.Errorf("expected the leaf to be expiring soon %#v", report.Certificates[2])
+	}
+
+	report = objs.ExpiryReport(time.Now().Add(72*time.Hour), time.Hour)
+	if !report.Certificates[0].Expired || report.Certificates[0].Remaining >= 0 || report.Certificates[0].ExpiringSoon {
+		t.Errorf("expected the root to have expired %#v", report.Certificates[0])
+	}
+
+	report = objs.ExpiryReport(time.Now().Add(-72*time.Hour), time.Hour)
+	if !report.Certificates[1].NotYetValid {
+		t.Errorf("expected the intermediate to not yet be valid %#v", report.Certificates[1])
+	}
+}
@@ -0,0 +1,26 @@
+package betterpem
+
+// Parse PEM data holding a single block with as few allocations as
+// possible, for hot paths such as loading a key for every connection.
+//
+// The block is parsed the way ParsePEMs parses it without options, but
+// only the first block is looked at and nothing around it is checked.
+// Errors are those ParsePEMs gives: ErrNoPEMFound, an
+// *ErrOnlyUnsupportedBlocks, or a *BlockError.
+func ParseOneFast(pemBytes []byte) (interface{}, error) {
+	scan := scanner{data: pemBytes, line: 1}
+	found, ok := scan.next()
+	if !ok {
+		return nil, ErrNoPEMFound
+	}
+	var o parseOptions
+	parse := o.plainParserFor(found.Type)
+	if parse == nil {
+		return nil, &ErrOnlyUnsupportedBlocks{Types: []string{found.Type}}
+	}
+	obj, err := parse(found.Block)
+	if err != nil {
+		return nil, &BlockError{Type: found.Type, Offset: found.Offset, Line: found.Line, Err: err}
+	}
+	return obj, nil
+}
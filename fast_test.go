@@ -0,0 +1,45 @@
+package betterpem
+
+import (
+	"crypto/ecdsa"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func TestParseOneFast(t *testing.T) {
+	obj, err := ParseOneFast(test_eckey)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	pems, _ := ParsePEMs(test_eckey)
+	if !obj.(*ecdsa.PrivateKey).Equal(pems.MustECPrivateKey()) {
+		t.Errorf("parsed a different key than ParsePEMs")
+	}
+
+	if _, err := ParseOneFast([]byte("nothing here")); err != ErrNoPEMFound {
+		t.Errorf("expected ErrNoPEMFound, got %#v", err)
+	}
+	var uerr *ErrOnlyUnsupportedBlocks
+	if _, err := ParseOneFast(pem.EncodeToMemory(&pem.Block{Type: "PGP SIGNATURE", Bytes: []byte{1}})); !errors.As(err, &uerr) {
+		t.Errorf("expected an *ErrOnlyUnsupportedBlocks, got %#v", err)
+	}
+	if _, err := ParseOneFast(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}})); !errors.Is(err, ErrBadBlock) {
+		t.Errorf("expected a bad block, got %#v", err)
+	}
+
+	fast := testing.AllocsPerRun(10, func() { ParseOneFast(test_rsacert) })
+	full := testing.AllocsPerRun(10, func() { ParsePEMs(test_rsacert) })
+	if fast >= full {
+		t.Errorf("expected fewer allocations than ParsePEMs's %v, got %v", full, fast)
+	}
+}
+
+func BenchmarkParseOneFast(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseOneFast(test_eckey); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/x509"
+	"net"
+	"strings"
+)
+
+// Return every certificate remaining to be consumed whose subject matches
+// pattern.
+//
+// The pattern is a glob such as "*.internal", in which * matches any
+// run of characters, slashes included, ? matches any one character, and
+// a backslash matches the character after it.  It is matched without
+// regard to case against both the common name and the whole subject,
+// such as "CN=foo,O=Example".  The parsed PEMs are not consumed.
+func (p *ParsedPEMs) FindBySubject(pattern string) []*x509.Certificate {
+	pattern = strings.ToLower(pattern)
+	ret := []*x509.Certificate{}
+	for _, c := range p.certificates() {
+		for _, s := range []string{c.Subject.CommonName, c.Subject.String()} {
+			if globMatch(pattern, strings.ToLower(s)) {
+				ret = append(ret, c)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// Whether s matches a glob pattern in which * may match across slashes,
+// unlike with path.Match
+func globMatch(pattern, s string) bool {
+	p, str := []rune(pattern), []rune(s)
+	i, j := 0, 0
+	// where to resume after the last *: the pattern just past it, and
+	// the next character of s for it to swallow
+	star, resume := -1, 0
+	for j < len(str) {
+		if i < len(p) {
+			switch c := p[i]; {
+			case c == '*':
+				star, resume = i, j
+				i++
+				continue
+			case c == '\\' && i+1 < len(p):
+				if p[i+1] == str[j] {
+					i, j = i+2, j+1
+					continue
+				}
+			case c == '?' || c == str[j]:
+				i, j = i+1, j+1
+				continue
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		resume++
+		i, j = star+1, resume
+	}
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+	return i == len(p)
+}
+
+// Whether a DNS name in a certificate, possibly a wildcard, covers name
+func dnsNameMatches(certName, name string) bool {
+	certName = normalizeServerName(certName)
+	name = normalizeServerName(name)
+	if certName == name {
+		return true
+	}
+	if strings.HasPrefix(certName, "*.") {
+		i := strings.IndexByte(name, '.')
+		return i > 0 && name[i+1:] == certName[2:]
+	}
+	return false
+}
+
+func certHasSAN(c *x509.Certificate, san string) bool {
+	if ip := net.ParseIP(san); ip != nil {
+		for _, cip := range c.IPAddresses {
+			if cip.Equal(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	if strings.Contains(san, "://") {
+		for _, u := range c.URIs {
+			if u.String() == san {
+				return true
+			}
+		}
+		return false
+	}
+	if strings.Contains(san, "@") {
+		for _, e := range c.EmailAddresses {
+			if strings.EqualFold(e, san) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, n := range c.DNSNames {
+		if dnsNameMatches(n, san) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return every certificate remaining to be consumed with a subject
+// alternative name covering san.
+//
+// san may be a DNS name, IP address, URI, or email address, which are
+// told apart by their form.  DNS names are compared without regard to
+// case, and match wildcard names in certificates the way TLS clients
+// match them.  The parsed PEMs are not consumed.
+func (p *ParsedPEMs) FindBySAN(san string) []*x509.Certificate {
+	ret := []*x509.Certificate{}
+	for _, c := range p.certificates() {
+		if certHasSAN(c, san) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Return every certificate remaining to be consumed with the subject key
+// identifier ski.
+//
+// More than one certificate can share a key identifier, such as a CA
+// certificate and its cross-signed twin.  The parsed PEMs are not
+// consumed.
+func (p *ParsedPEMs) FindBySKI(ski []byte) []*x509.Certificate {
+	ret := []*x509.Certificate{}
+	if len(ski) == 0 {
+		return ret
+	}
+	for _, c := range p.certificates() {
+		if bytes.Equal(c.SubjectKeyId, ski) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Return every certificate remaining to be consumed which could have
+// issued cert.
+//
+// A certificate is an issuer when its subject is cert's issuer, its
+// subject key identifier is cert's authority key identifier (when both
+// are present), and its key verifies cert's signature.  There is more
+// than one when the issuer has been cross-signed.  A self-signed cert is
+// its own issuer if it is among the parsed PEMs.  The parsed PEMs are not
+// consumed.
+func (p *ParsedPEMs) IssuerOf(cert *x509.Certificate) []*x509.Certificate {
+	ret := []*x509.Certificate{}
+	for _, c := range p.certificates() {
+		if issuedBy(cert, c) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,101 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	c := newTestChain(t)
+	ss, err := SelfSigned(SelfSignedOptions{
+		CommonName: "foo.internal",
+		SANs:       []string{"foo.internal", "10.0.0.1", "spiffe://example.com/foo", "foo@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := ParsePEMs(c.pem(t, c.root, c.intermediate, c.leaf, ss.Certificate))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+
+	if found := objs.FindBySubject("test *"); len(found) != 2 {
+		t.Errorf("expected the root and intermediate, got %v", found)
+	}
+	if found := objs.FindBySubject("CN=FOO.*"); len(found) != 1 || !found[0].Equal(ss.Certificate) {
+		t.Errorf("expected foo.internal, got %v", found)
+	}
+
+	for san, want := range map[string]int{
+		"foo.internal":             1,
+		"www.leaf.example.com":     1,
+		"LEAF.example.com.":        1,
+		"a.b.leaf.example.com":     0,
+		"10.0.0.1":                 1,
+		"10.0.0.2":                 0,
+		"spiffe://example.com/foo": 1,
+		"Foo@example.com":          1,
+	} {
+		if found := objs.FindBySAN(san); len(found) != want {
+			t.Errorf("expected %d certificates for %s, got %d", want, san, len(found))
+		}
+	}
+}
+
+func TestFindBySubjectSlashes(t *testing.T) {
+	c := newTestChain(t)
+	cert, _ := issueTestCert(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "spiffe://example.com/web", Organization: []string{"Example/Ops"}},
+	}, c.intermediate, c.intermediateKey)
+	objs, err := ParsePEMs(c.pem(t, cert, c.intermediate))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	for pattern, want := range map[string]int{
+		"spiffe://*":               1,
+		"*/web":                    1,
+		"cn=*,o=example/ops":       1,
+		"*o=Example/Ops":           1,
+		"spiffe://example.com/?":   0,
+		"spiffe://example.com/w?b": 1,
+		`spiffe://example.com/\*`:  0,
+	} {
+		if found := objs.FindBySubject(pattern); len(found) != want {
+			t.Errorf("pattern %q: expected %d certificates, got %d", pattern, want, len(found))
+		}
+	}
+}
+
+func TestFindBySKIAndIssuerOf(t *testing.T) {
+	c := newTestChain(t)
+	// the intermediate's key, cross-signed by another root
+	other := newTestChain(t)
+	cross := issueTestCertForKey(t, &x509.Certificate{
+		Subject:               c.intermediate.Subject,
+		SubjectKeyId:          c.intermediate.SubjectKeyId,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, other.root, other.rootKey, c.intermediateKey)
+
+	objs, err := ParsePEMs(c.pem(t, c.root, other.root, c.intermediate, cross, c.leaf))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if found := objs.FindBySKI(c.leaf.AuthorityKeyId); len(found) != 2 {
+		t.Errorf("expected the intermediate and its cross-signed twin, got %v", found)
+	}
+	if found := objs.FindBySKI(nil); len(found) != 0 {
+		t.Errorf("an empty key identifier should match nothing, got %v", found)
+	}
+	if found := objs.IssuerOf(c.leaf); len(found) != 2 {
+		t.Errorf("expected two issuers of the leaf, got %v", found)
+	}
+	if found := objs.IssuerOf(cross); len(found) != 1 || !found[0].Equal(other.root) {
+		t.Errorf("expected the other root to issue the cross-signed intermediate, got %v", found)
+	}
+	if found := objs.IssuerOf(c.root); len(found) != 1 || !found[0].Equal(c.root) {
+		t.Errorf("expected the root to issue itself, got %v", found)
+	}
+}
@@ -0,0 +1,133 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var ErrHashUnavailable = errors.New("hash function is not available")
+
+// A fingerprint of a certificate or key
+type Digest struct {
+	Hash crypto.Hash
+	Sum  []byte
+}
+
+// Return the fingerprint as lowercase hex with no separators
+func (d Digest) Hex() string {
+	return hex.EncodeToString(d.Sum)
+}
+
+// Return the fingerprint as uppercase hex bytes separated by colons, the
+// way openssl prints them
+func (d Digest) Colon() string {
+	parts := make([]string, len(d.Sum))
+	for i, b := range d.Sum {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{b}))
+	}
+	return strings.Join(parts, ":")
+}
+
+// Return the fingerprint the way ssh-keygen -l prints it, such as
+// "SHA256:" followed by unpadded base64
+func (d Digest) SSH() string {
+	name := strings.ReplaceAll(d.Hash.String(), "-", "")
+	return name + ":" + base64.RawStdEncoding.EncodeToString(d.Sum)
+}
+
+func (d Digest) String() string {
+	return d.Colon()
+}
+
+// Fingerprint a certificate or key with the given hash.
+//
+// Certificates are fingerprinted over their DER encoding, matching
+// openssl x509 -fingerprint.  SSH public keys are fingerprinted over
+// their wire encoding, matching ssh-keygen -l.  Anything else a public
+// key can be found in is fingerprinted over its DER SubjectPublicKeyInfo.
+func Fingerprint(obj interface{}, hash crypto.Hash) (Digest, error) {
+	if !hash.Available() {
+		return Digest{}, ErrHashUnavailable
+	}
+	var data []byte
+	switch v := obj.(type) {
+	case *x509.Certificate:
+		data = v.Raw
+	case ssh.PublicKey:
+		data = v.Marshal()
+	default:
+		pub, err := publicKeyOf(obj)
+		if err != nil {
+			return Digest{}, err
+		}
+		data, err = x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return Digest{}, err
+		}
+	}
+	h := hash.New()
+	h.Write(data)
+	return Digest{Hash: hash, Sum: h.Sum(nil)}, nil
+}
+
+// Fingerprint every parsed PEM remaining to be consumed, in order.
+//
+// Objects which aren't certificates and hold no public key, such as CRLs
+// and DH parameters, are left out.  The parsed PEMs are not consumed.
+func (p *ParsedPEMs) Fingerprints(hash crypto.Hash) ([]Digest, error) {
+	ret := make([]Digest, 0, len(p.objs))
+	for _, obj := range p.all() {
+		d, err := Fingerprint(obj, hash)
+		if err == ErrNoPublicKey {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, d)
+	}
+	return ret, nil
+}
+
+// Return the base64 SHA-256 hash of an object's SubjectPublicKeyInfo.
+//
+// This is the pin used by HPKP and curl --pinnedpubkey (after "sha256//").
+// Unlike Fingerprint, certificates are pinned by their public key so the
+// pin survives renewals which keep the key.
+func SPKIPin(obj interface{}) (string, error) {
+	pub, err := publicKeyOf(obj)
+	if err != nil {
+		return "", err
+	}
+	d, err := Fingerprint(pub, crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(d.Sum), nil
+}
+
+// Return the SPKI pin of every parsed PEM remaining to be consumed, in
+// order.
+//
+// Objects without a public key, such as CRLs, are left out.  The parsed
+// PEMs are not consumed.  See SPKIPin.
+func (p *ParsedPEMs) SPKIPins() ([]string, error) {
+	ret := make([]string, 0, len(p.objs))
+	for _, obj := range p.all() {
+		pin, err := SPKIPin(obj)
+		if err == ErrNoPublicKey {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, pin)
+	}
+	return ret, nil
+}
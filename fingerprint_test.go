@@ -0,0 +1,160 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestFingerprint(t *testing.T) {
+	objs, err := ParsePEMs(bytes.Join([][]byte{test_eccert, test_eckey}, []byte{'\n'}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	fps, err := objs.Fingerprints(crypto.SHA256)
+	if err != nil {
+		t.Fatalf("unexpected error fingerprinting %#v", err)
+	}
+	if len(fps) != objs.Length() {
+		t.Fatalf("expected %d fingerprints, got %d", objs.Length(), len(fps))
+	}
+	cert := objs.MustCertificate()
+	key := objs.MustECPrivateKey()
+
+	certsum := sha256.Sum256(cert.Raw)
+	if fps[0].Hex() != hex.EncodeToString(certsum[:]) {
+		t.Errorf("certificate fingerprint %s does not match its DER hash", fps[0].Hex())
+	}
+	if fps[0].Colon() != strings.ToUpper(strings.Join(splitPairs(hex.EncodeToString(certsum[:])), ":")) {
+		t.Errorf("unexpected colon form %s", fps[0].Colon())
+	}
+
+	spki, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	spkisum := sha256.Sum256(spki)
+	if !bytes.Equal(fps[1].Sum, spkisum[:]) {
+		t.Errorf("key fingerprint %s does not match its SPKI hash", fps[1].Hex())
+	}
+
+	sshpub, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshfp, err := Fingerprint(sshpub, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("unexpected error fingerprinting %#v", err)
+	}
+	if sshfp.SSH() != ssh.FingerprintSHA256(sshpub) {
+		t.Errorf("ssh fingerprint %s != %s", sshfp.SSH(), ssh.FingerprintSHA256(sshpub))
+	}
+
+	if _, err := Fingerprint(cert, crypto.Hash(0)); err != ErrHashUnavailable {
+		t.Errorf("expected ErrHashUnavailable for a bogus hash, got %#v", err)
+	}
+}
+
+func TestFingerprintsSkipsCRLs(t *testing.T) {
+	c := newTestChain(t)
+	objs, err := ParsePEMs(c.pem(t, c.intermediate, newTestCRL(t, c)))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	fps, err := objs.Fingerprints(crypto.SHA256)
+	if err != nil {
+		t.Fatalf("unexpected error fingerprinting %#v", err)
+	}
+	sum := sha256.Sum256(c.intermediate.Raw)
+	if len(fps) != 1 || !bytes.Equal(fps[0].Sum, sum[:]) {
+		t.Errorf("expected just the certificate's fingerprint, got %v", fps)
+	}
+	if _, err := Fingerprint(newTestCRL(t, c), crypto.SHA256); err != ErrNoPublicKey {
+		t.Errorf("expected ErrNoPublicKey for a CRL, got %#v", err)
+	}
+}
+
+func TestSPKIPinsX25519AndCRL(t *testing.T) {
+	c := newTestChain(t)
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spki, err := x509.MarshalPKIXPublicKey(x25519.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(spki)
+	want := base64.StdEncoding.EncodeToString(sum[:])
+	if pin, err := SPKIPin(x25519); err != nil || pin != want {
+		t.Errorf("unexpected X25519 pin %q %#v", pin, err)
+	}
+
+	objs, err := ParsePEMs(c.pem(t, c.leaf, x25519, newTestCRL(t, c)))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	pins, err := objs.SPKIPins()
+	if err != nil {
+		t.Fatalf("unexpected error pinning %#v", err)
+	}
+	leafSum := sha256.Sum256(c.leaf.RawSubjectPublicKeyInfo)
+	if len(pins) != 2 || pins[0] != base64.StdEncoding.EncodeToString(leafSum[:]) || pins[1] != want {
+		t.Errorf("expected the certificate's and X25519 key's pins, got %v", pins)
+	}
+}
+
+// Issue an empty CRL from the chain's intermediate
+func newTestCRL(t *testing.T, c *testChain) *x509.RevocationList {
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, c.intermediate, c.intermediateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crl
+}
+
+func splitPairs(s string) []string {
+	ret := []string{}
+	for i := 0; i < len(s); i += 2 {
+		ret = append(ret, s[i:i+2])
+	}
+	return ret
+}
+
+func TestSPKIPin(t *testing.T) {
+	objs, err := ParsePEMs(bytes.Join([][]byte{test_rsacert, test_rsakey}, []byte{'\n'}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	pins, err := objs.SPKIPins()
+	if err != nil {
+		t.Fatalf("unexpected error pinning %#v", err)
+	}
+	if len(pins) != 2 || pins[0] != pins[1] {
+		t.Errorf("a certificate and its key should have the same pin: %v", pins)
+	}
+	cert := objs.MustCertificate()
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	if pins[0] != base64.StdEncoding.EncodeToString(sum[:]) {
+		t.Errorf("pin %s does not match the certificate's SPKI", pins[0])
+	}
+}
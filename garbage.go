@@ -0,0 +1,39 @@
+package betterpem
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+)
+
+// Content outside of any PEM block, found because of WithErrorOnGarbage
+type GarbageError struct {
+	// The byte offset of the first character which isn't whitespace,
+	// counting from 0
+	Offset int
+	// The line number of that character, counting from 1
+	Line int
+	// A copy of the content up to the next block or the end of the input,
+	// without surrounding whitespace
+	Data []byte
+}
+
+func (e *GarbageError) Error() string {
+	snippet := e.Data
+	if len(snippet) > 32 {
+		snippet = snippet[:32]
+	}
+	return fmt.Sprintf("unexpected content outside of pem blocks at line %d, offset %d: %q", e.Line, e.Offset, snippet)
+}
+
+// Return a *GarbageError if s.data[from:to] holds anything but
+// whitespace
+func (s *scanner) garbage(from, to int) error {
+	gap := s.data[from:to]
+	start := bytes.IndexFunc(gap, func(r rune) bool { return !unicode.IsSpace(r) })
+	if start < 0 {
+		return nil
+	}
+	offset := from + start
+	return &GarbageError{Offset: offset, Line: s.lineOf(offset), Data: bytes.Clone(bytes.TrimSpace(gap))}
+}
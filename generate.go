@@ -0,0 +1,186 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Generate an RSA private key and encode it as an RSA PRIVATE KEY block
+func GenerateRSA(bits int) (*rsa.PrivateKey, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, nil, err
+	}
+	pemBytes, err := EncodePEM(key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, pemBytes, nil
+}
+
+// Generate an ECDSA private key on the given curve, such as
+// elliptic.P256(), and encode it as an EC PRIVATE KEY block
+func GenerateEC(curve elliptic.Curve) (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	pemBytes, err := EncodePEM(key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, pemBytes, nil
+}
+
+// Generate an Ed25519 private key and encode it as a PKCS#8 PRIVATE KEY
+// block
+func GenerateEd25519() (ed25519.PrivateKey, []byte, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	pemBytes, err := EncodePEM(key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, pemBytes, nil
+}
+
+// Split subject alternative names into the kinds x509 templates want.
+// Anything which parses as an IP address is an IP, anything containing
+// "://" is a URI, anything with an "@" is an email address, and the rest
+// are DNS names.
+func splitSANs(sans []string) ([]string, []net.IP, []string, []*url.URL, error) {
+	var dns, emails []string
+	var ips []net.IP
+	var uris []*url.URL
+	for _, san := range sans {
+		if ip := net.ParseIP(san); ip != nil {
+			ips = append(ips, ip)
+		} else if strings.Contains(san, "://") {
+			u, err := url.Parse(san)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			uris = append(uris, u)
+		} else if strings.Contains(san, "@") {
+			emails = append(emails, san)
+		} else {
+			dns = append(dns, san)
+		}
+	}
+	return dns, ips, emails, uris, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// The key usage a certificate for pub conventionally has
+func keyUsageFor(pub crypto.PublicKey, isCA bool) x509.KeyUsage {
+	if isCA {
+		return x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+	}
+	ku := x509.KeyUsageDigitalSignature
+	if _, ok := pub.(*rsa.PublicKey); ok {
+		ku |= x509.KeyUsageKeyEncipherment
+	}
+	return ku
+}
+
+// What to put in a certificate made by SelfSigned
+type SelfSignedOptions struct {
+	CommonName string
+	// DNS names, IP addresses, email addresses, and URIs, which are told
+	// apart by their form
+	SANs []string
+	// Defaults to now
+	NotBefore time.Time
+	// Defaults to one year
+	Validity time.Duration
+	// Defaults to a new ECDSA P-256 key
+	Key crypto.Signer
+	// Make a CA certificate which can sign others instead of a server and
+	// client certificate
+	IsCA bool
+}
+
+// A key and self-signed certificate, both parsed and as PEM
+type SelfSignedCertificate struct {
+	Key            crypto.Signer
+	Certificate    *x509.Certificate
+	KeyPEM         []byte
+	CertificatePEM []byte
+}
+
+// Make a self-signed certificate, such as for a development server or a
+// test.
+func SelfSigned(opts SelfSignedOptions) (*SelfSignedCertificate, error) {
+	key := opts.Key
+	var keyPEM []byte
+	var err error
+	if key == nil {
+		key, keyPEM, err = GenerateEC(elliptic.P256())
+	} else {
+		keyPEM, err = EncodePEM(key, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	dns, ips, emails, uris, err := splitSANs(opts.SANs)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	notBefore := opts.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now()
+	}
+	validity := opts.Validity
+	if validity == 0 {
+		validity = 365 * 24 * time.Hour
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: opts.CommonName},
+		DNSNames:              dns,
+		IPAddresses:           ips,
+		EmailAddresses:        emails,
+		URIs:                  uris,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(validity),
+		BasicConstraintsValid: true,
+		IsCA:                  opts.IsCA,
+	}
+	template.KeyUsage = keyUsageFor(key.Public(), opts.IsCA)
+	if !opts.IsCA {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, err := EncodePEM(cert, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SelfSignedCertificate{Key: key, Certificate: cert, KeyPEM: keyPEM, CertificatePEM: certPEM}, nil
+}
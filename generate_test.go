@@ -0,0 +1,93 @@
+package betterpem
+
+import (
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	rsakey, rsapem, err := GenerateRSA(2048)
+	if err != nil {
+		t.Fatalf("unexpected error generating rsa key %#v", err)
+	}
+	objs, err := ParsePEMs(rsapem)
+	if err != nil {
+		t.Fatalf("unexpected error parsing generated pem %#v", err)
+	}
+	if !rsakey.Equal(objs.MustRSAPrivateKey()) {
+		t.Error("rsa key and its pem differ")
+	}
+
+	eckey, ecpem, err := GenerateEC(elliptic.P384())
+	if err != nil {
+		t.Fatalf("unexpected error generating ec key %#v", err)
+	}
+	objs, err = ParsePEMs(ecpem)
+	if err != nil {
+		t.Fatalf("unexpected error parsing generated pem %#v", err)
+	}
+	if !eckey.Equal(objs.MustECPrivateKey()) {
+		t.Error("ec key and its pem differ")
+	}
+
+	edkey, edpem, err := GenerateEd25519()
+	if err != nil {
+		t.Fatalf("unexpected error generating ed25519 key %#v", err)
+	}
+	objs, err = ParsePEMs(edpem)
+	if err != nil {
+		t.Fatalf("unexpected error parsing generated pem %#v", err)
+	}
+	if parsed, ok := objs.Interface().(ed25519.PrivateKey); !ok || !edkey.Equal(parsed) {
+		t.Error("ed25519 key and its pem differ")
+	}
+}
+
+func TestSelfSigned(t *testing.T) {
+	ss, err := SelfSigned(SelfSignedOptions{
+		CommonName: "dev.example.com",
+		SANs:       []string{"dev.example.com", "127.0.0.1", "admin@example.com", "spiffe://example.com/dev"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error making certificate %#v", err)
+	}
+	cert := ss.Certificate
+	if cert.Subject.CommonName != "dev.example.com" || len(cert.DNSNames) != 1 || len(cert.IPAddresses) != 1 ||
+		len(cert.EmailAddresses) != 1 || len(cert.URIs) != 1 {
+		t.Errorf("unexpected certificate %#v", cert)
+	}
+	if err := cert.VerifyHostname("127.0.0.1"); err != nil {
+		t.Error(err)
+	}
+	if err := cert.CheckSignatureFrom(cert); err == nil {
+		t.Error("a leaf certificate should not be able to sign")
+	}
+
+	objs, err := ParsePEMs(append(ss.CertificatePEM, ss.KeyPEM...))
+	if err != nil {
+		t.Fatalf("unexpected error parsing generated pem %#v", err)
+	}
+	if !objs.MustCertificate().Equal(cert) || !objs.MustECPrivateKey().Equal(ss.Key) {
+		t.Error("pem does not match the parsed objects")
+	}
+
+	rsakey, _, err := GenerateRSA(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := SelfSigned(SelfSignedOptions{CommonName: "Dev CA", Key: rsakey, IsCA: true, Validity: time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error making certificate %#v", err)
+	}
+	if !ca.Certificate.IsCA {
+		t.Error("expected a CA certificate")
+	}
+	if err := ca.Certificate.CheckSignatureFrom(ca.Certificate); err != nil {
+		t.Errorf("CA certificate is not self-signed: %v", err)
+	}
+	if ca.Certificate.NotAfter.Sub(ca.Certificate.NotBefore) != time.Hour {
+		t.Error("validity was not honored")
+	}
+}
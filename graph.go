@@ -0,0 +1,69 @@
+package betterpem
+
+import (
+	"crypto/x509"
+)
+
+// One certificate in a CertificateGraph
+type CertificateNode struct {
+	Certificate *x509.Certificate
+	// Nodes whose certificates signed this one, other than itself
+	Issuers []*CertificateNode
+	// Nodes whose certificates this one signed, other than itself
+	Issued []*CertificateNode
+}
+
+// An edge from a certificate to one it signed
+type CertificateEdge struct {
+	Issuer  *CertificateNode
+	Subject *CertificateNode
+}
+
+// Which certificates in a bundle issued which
+type CertificateGraph struct {
+	// Every certificate, in order, without duplicates
+	Nodes []*CertificateNode
+	Edges []CertificateEdge
+	// Self-signed certificates
+	Roots []*CertificateNode
+	// Certificates which aren't self-signed and whose issuers aren't in
+	// the bundle
+	Orphans []*CertificateNode
+}
+
+// Build the graph of which certificates remaining to be consumed issued
+// which, for tooling which inspects cross-signed hierarchies.
+//
+// A certificate issued another when the names and key identifiers line
+// up and its key verifies the other's signature.  Cross-signed
+// certificates have several issuers, so the graph may have cycles, but
+// nothing is its own issuer.  Nodes, edges, roots, and orphans are listed
+// in order.  The parsed PEMs are not consumed.
+func BuildGraph(pems ParsedPEMs) *CertificateGraph {
+	graph := &CertificateGraph{
+		Nodes:   []*CertificateNode{},
+		Edges:   []CertificateEdge{},
+		Roots:   []*CertificateNode{},
+		Orphans: []*CertificateNode{},
+	}
+	for _, c := range uniqueCertificates(pems.certificates()) {
+		graph.Nodes = append(graph.Nodes, &CertificateNode{Certificate: c})
+	}
+	for _, issuer := range graph.Nodes {
+		for _, subject := range graph.Nodes {
+			if issuer != subject && issuedBy(subject.Certificate, issuer.Certificate) {
+				issuer.Issued = append(issuer.Issued, subject)
+				subject.Issuers = append(subject.Issuers, issuer)
+				graph.Edges = append(graph.Edges, CertificateEdge{Issuer: issuer, Subject: subject})
+			}
+		}
+	}
+	for _, n := range graph.Nodes {
+		if isSelfSigned(n.Certificate) {
+			graph.Roots = append(graph.Roots, n)
+		} else if len(n.Issuers) == 0 {
+			graph.Orphans = append(graph.Orphans, n)
+		}
+	}
+	return graph
+}
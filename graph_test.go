@@ -0,0 +1,46 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+)
+
+func TestBuildGraph(t *testing.T) {
+	c := newTestChain(t)
+	other := newTestChain(t)
+	// the intermediate's key cross-signed by another root
+	cross := issueTestCertForKey(t, &x509.Certificate{
+		Subject:               c.intermediate.Subject,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		SubjectKeyId:          c.intermediate.SubjectKeyId,
+	}, other.root, other.rootKey, c.intermediateKey)
+	orphan, _ := issueTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "orphan"}}, other.intermediate, other.intermediateKey)
+
+	objs, err := ParsePEMs(c.pem(t, c.leaf, c.intermediate, cross, c.root, other.root, orphan, c.leaf))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	graph := BuildGraph(objs)
+	if len(graph.Nodes) != 6 {
+		t.Fatalf("expected 6 nodes, got %d", len(graph.Nodes))
+	}
+	leaf, inter, crossNode := graph.Nodes[0], graph.Nodes[1], graph.Nodes[2]
+	// both versions of the intermediate issued the leaf
+	if len(leaf.Issuers) != 2 || leaf.Issuers[0] != inter || leaf.Issuers[1] != crossNode {
+		t.Errorf("unexpected issuers of the leaf %v", leaf.Issuers)
+	}
+	if len(crossNode.Issuers) != 1 || !crossNode.Issuers[0].Certificate.Equal(other.root) {
+		t.Errorf("unexpected issuers of the cross-signed intermediate %v", crossNode.Issuers)
+	}
+	if len(graph.Edges) != 4 {
+		t.Errorf("expected 4 edges, got %d", len(graph.Edges))
+	}
+	if len(graph.Roots) != 2 || !graph.Roots[0].Certificate.Equal(c.root) || !graph.Roots[1].Certificate.Equal(other.root) {
+		t.Errorf("unexpected roots %v", graph.Roots)
+	}
+	if len(graph.Orphans) != 1 || !graph.Orphans[0].Certificate.Equal(orphan) {
+		t.Errorf("unexpected orphans %v", graph.Orphans)
+	}
+}
@@ -0,0 +1,30 @@
+// Package grpccreds builds gRPC transport credentials from betterpem's
+// parsed PEMs.  It is separate from betterpem so that only programs using
+// gRPC depend on it.
+package grpccreds
+
+import (
+	"github.com/jamesandariese/betterpem"
+	"google.golang.org/grpc/credentials"
+)
+
+// Make TLS transport credentials for a gRPC server or client.
+//
+// Identity is the private key, certificate, and chain presented to the
+// other side, and may be nil for a client not using mTLS.  Roots are the
+// CAs the other side's certificate must chain to: a server given roots
+// requires a client certificate signed by one, and a client given roots
+// trusts them in place of the system roots.  Either end is built the same
+// way, so the credentials can be passed to grpc.Creds or
+// grpc.WithTransportCredentials.
+func NewGRPCCredentials(identity, roots *betterpem.ParsedPEMs) (credentials.TransportCredentials, error) {
+	cfg, err := betterpem.BuildTLSConfig(betterpem.TLSConfigOptions{
+		Identity:  identity,
+		ClientCAs: roots,
+		RootCAs:   roots,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(cfg), nil
+}
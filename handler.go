@@ -0,0 +1,156 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type bundleVariant struct {
+	body        []byte
+	contentType string
+	etag        string
+}
+
+func newBundleVariant(body []byte, contentType string) *bundleVariant {
+	sum := sha256.Sum256(body)
+	return &bundleVariant{body, contentType, `"` + hex.EncodeToString(sum[:]) + `"`}
+}
+
+type bundleHandler struct {
+	pem *bundleVariant
+	// only set when the bundle is a single certificate
+	der *bundleVariant
+}
+
+// Build an http.Handler which serves a bundle of certificates, such as
+// a CA's, for clients to download.
+//
+// Only the certificates are served; private keys and anything else in
+// the parsed PEMs are left out, and ErrNoCertificates is returned if
+// there are none.  The bundle is encoded once, up front, and served as
+// application/x-pem-file with a strong ETag so clients can cache it.  If
+// the bundle holds exactly one certificate, clients which ask for
+// application/pkix-cert in their Accept header are given the DER
+// certificate instead.  The parsed PEMs are not consumed.
+func NewBundleHandler(pems ParsedPEMs) (http.Handler, error) {
+	certs := ParsedPEMs{}
+	v := pems.parsed()
+	for i, obj := range v.objs {
+		if _, ok := obj.(*x509.Certificate); ok {
+			certs.add(obj, v.blocks[i])
+		}
+	}
+	if certs.Length() == 0 {
+		return nil, ErrNoCertificates
+	}
+	body, err := certs.Encode()
+	if err != nil {
+		return nil, err
+	}
+	h := &bundleHandler{pem: newBundleVariant(body, "application/x-pem-file")}
+	if objs := certs.all(); len(objs) == 1 {
+		h.der = newBundleVariant(objs[0].(*x509.Certificate).Raw, "application/pkix-cert")
+	}
+	return h, nil
+}
+
+// One media range from an Accept header
+type acceptRange struct {
+	mediatype string
+	q         float64
+	// 0 for */*, 1 for type/*, 2 for a full media type
+	specificity int
+}
+
+func parseAccept(accept string) []acceptRange {
+	ret := []acceptRange{}
+	for _, part := range strings.Split(accept, ",") {
+		mediatype, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		r := acceptRange{mediatype: mediatype, q: 1, specificity: 2}
+		if q, ok := params["q"]; ok {
+			if r.q, err = strconv.ParseFloat(q, 64); err != nil {
+				r.q = 0
+			}
+		}
+		if mediatype == "*/*" {
+			r.specificity = 0
+		} else if strings.HasSuffix(mediatype, "/*") {
+			r.specificity = 1
+		}
+		ret = append(ret, r)
+	}
+	// highest q first, full media types ahead of wildcards with the same
+	// q, and otherwise in the order the client listed them
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].q != ret[j].q {
+			return ret[i].q > ret[j].q
+		}
+		return ret[i].specificity > ret[j].specificity
+	})
+	return ret
+}
+
+func (h *bundleHandler) variantFor(mediatype string) *bundleVariant {
+	switch mediatype {
+	case "application/x-pem-file", "application/*", "*/*", "text/plain", "text/*":
+		return h.pem
+	case "application/pkix-cert", "application/x-x509-ca-cert":
+		return h.der
+	}
+	return nil
+}
+
+// Pick the variant for the most preferred acceptable media type in the
+// Accept header, or nil if none of them can be served.  Media types are
+// ranked by their q values, and a full media type is preferred over a
+// wildcard with the same q.  A full media type with q=0 refuses its
+// variant even if a wildcard would otherwise allow it.
+func (h *bundleHandler) negotiate(accept string) *bundleVariant {
+	if accept == "" {
+		return h.pem
+	}
+	ranges := parseAccept(accept)
+	refused := map[*bundleVariant]bool{}
+	for _, r := range ranges {
+		if r.q <= 0 && r.specificity == 2 {
+			refused[h.variantFor(r.mediatype)] = true
+		}
+	}
+	for _, r := range ranges {
+		if r.q <= 0 {
+			break
+		}
+		if v := h.variantFor(r.mediatype); v != nil && !refused[v] {
+			return v
+		}
+	}
+	return nil
+}
+
+func (h *bundleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Vary", "Accept")
+	v := h.negotiate(r.Header.Get("Accept"))
+	if v == nil {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
+	w.Header().Set("Content-Type", v.contentType)
+	w.Header().Set("ETag", v.etag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(v.body))
+}
@@ -0,0 +1,139 @@
+package betterpem
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBundleHandler(t *testing.T) {
+	objs, err := ParsePEMs(test_ca)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	h, err := NewBundleHandler(objs)
+	if err != nil {
+		t.Fatalf("unexpected error building handler %#v", err)
+	}
+	cert := objs.MustCertificate()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/ca.pem", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-pem-file" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" || etag[0] != '"' {
+		t.Errorf("expected a strong etag, got %q", etag)
+	}
+	if _, err := ParsePEMs(rec.Body.Bytes()); err != nil {
+		t.Errorf("served bundle does not parse: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/ca.pem", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching etag, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/ca.crt", nil)
+	req.Header.Set("Accept", "application/pkix-cert")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Type") != "application/pkix-cert" || !bytes.Equal(rec.Body.Bytes(), cert.Raw) {
+		t.Errorf("expected the DER certificate, got %d %v", rec.Code, rec.Header())
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Error("DER and PEM variants share an etag")
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/ca.pem", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for POST, got %d", rec.Code)
+	}
+}
+
+func TestBundleHandlerCertificatesOnly(t *testing.T) {
+	c := newTestChain(t)
+	objs, err := ParsePEMs(c.pem(t, c.leafKey, c.leaf, c.intermediate))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	h, err := NewBundleHandler(objs)
+	if err != nil {
+		t.Fatalf("unexpected error building handler %#v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/ca.pem", nil))
+	if bytes.Contains(rec.Body.Bytes(), []byte("PRIVATE KEY")) {
+		t.Errorf("private key was served:\n%s", rec.Body.Bytes())
+	}
+	served, err := ParsePEMs(rec.Body.Bytes())
+	if err != nil || served.Length() != 2 || !served.MustCertificate().Equal(c.leaf) {
+		t.Errorf("expected just the 2 certificates, got %#v", err)
+	}
+
+	keys, err := ParsePEMs(c.pem(t, c.leafKey))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if _, err := NewBundleHandler(keys); err != ErrNoCertificates {
+		t.Errorf("expected ErrNoCertificates, got %#v", err)
+	}
+}
+
+func TestBundleHandlerQuality(t *testing.T) {
+	objs, err := ParsePEMs(test_ca)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	h, err := NewBundleHandler(objs)
+	if err != nil {
+		t.Fatalf("unexpected error building handler %#v", err)
+	}
+	for accept, want := range map[string]int{
+		"application/x-pem-file;q=0":      http.StatusNotAcceptable,
+		"application/x-pem-file;q=0.0":    http.StatusNotAcceptable,
+		"application/x-pem-file;q=0.000":  http.StatusNotAcceptable,
+		"application/x-pem-file;q=bogus":  http.StatusNotAcceptable,
+		"application/x-pem-file;q=0.5":    http.StatusOK,
+		"*/*, application/x-pem-file;q=0": http.StatusNotAcceptable,
+	} {
+		req := httptest.NewRequest("GET", "/ca.pem", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Accept: %s: expected %d, got %d", accept, want, rec.Code)
+		}
+	}
+}
+
+func TestBundleHandlerPreference(t *testing.T) {
+	objs, err := ParsePEMs(test_ca)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	h, err := NewBundleHandler(objs)
+	if err != nil {
+		t.Fatalf("unexpected error building handler %#v", err)
+	}
+	for accept, want := range map[string]string{
+		"application/x-pem-file;q=0.1, application/pkix-cert": "application/pkix-cert",
+		"*/*, application/pkix-cert":                          "application/pkix-cert",
+		"application/pkix-cert;q=0.5, */*":                    "application/x-pem-file",
+		"text/plain, application/pkix-cert":                   "application/x-pem-file",
+	} {
+		req := httptest.NewRequest("GET", "/ca.pem", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Type"); got != want {
+			t.Errorf("Accept: %s: expected %s, got %s", accept, want, got)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Why a bundle can't be used for a host
+type HostMismatch struct {
+	Certificate *x509.Certificate
+	Reason      string
+}
+
+func (m HostMismatch) Error() string {
+	return m.Reason
+}
+
+// The problems ValidateForHost found, all of them
+type HostValidationError struct {
+	Host       string
+	Mismatches []HostMismatch
+}
+
+func (e *HostValidationError) Error() string {
+	reasons := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		reasons[i] = m.Reason
+	}
+	return fmt.Sprintf("bundle is not valid for %s: %s", e.Host, strings.Join(reasons, "; "))
+}
+
+// Check that a bundle's leaf certificate can be served for a host.
+//
+// The leaf, found the same way as by OrderChain, must name the host in
+// its subject alternative names, and it and every other certificate in
+// the bundle must be within their validity periods.  Names are checked
+// the way TLS clients check them, so wildcards match one label and
+// certificates with no SANs match nothing.
+//
+// Every problem found is returned in a *HostValidationError.  The parsed
+// PEMs are not consumed.
+func ValidateForHost(pems ParsedPEMs, host string) error {
+	now := pems.now()
+	certs := pems.certificates()
+	leaf, err := soleLeaf(certs)
+	if err != nil {
+		return err
+	}
+	verr := &HostValidationError{Host: host}
+	if err := leaf.VerifyHostname(host); err != nil {
+		var names []string
+		names = append(names, leaf.DNSNames...)
+		for _, ip := range leaf.IPAddresses {
+			names = append(names, ip.String())
+		}
+		reason := fmt.Sprintf("%s is not among the leaf certificate's names %v", host, names)
+		if len(names) == 0 {
+			reason = "the leaf certificate has no subject alternative names"
+		}
+		verr.Mismatches = append(verr.Mismatches, HostMismatch{leaf, reason})
+	}
+	for _, c := range uniqueCertificates(certs) {
+		if now.Before(c.NotBefore) {
+			verr.Mismatches = append(verr.Mismatches, HostMismatch{c, fmt.Sprintf("%s is not valid until %s", c.Subject, c.NotBefore.UTC().Format(time.RFC3339))})
+		}
+		if now.After(c.NotAfter) {
+			verr.Mismatches = append(verr.Mismatches, HostMismatch{c, fmt.Sprintf("%s expired at %s", c.Subject, c.NotAfter.UTC().Format(time.RFC3339))})
+		}
+	}
+	if len(verr.Mismatches) > 0 {
+		return verr
+	}
+	return nil
+}
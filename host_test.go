@@ -0,0 +1,36 @@
+package betterpem
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateForHost(t *testing.T) {
+	c := newTestChain(t)
+	objs, err := ParsePEMs(c.pem(t, c.intermediate, c.leaf, c.leafKey))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	for _, host := range []string{"leaf.example.com", "api.leaf.example.com"} {
+		if err := ValidateForHost(objs, host); err != nil {
+			t.Errorf("unexpected error for %s: %v", host, err)
+		}
+	}
+
+	later := WithClock(func() time.Time { return time.Now().Add(72 * time.Hour) })
+	objs, err = ParsePEMs(c.pem(t, c.intermediate, c.leaf, c.leafKey), later)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	err = ValidateForHost(objs, "a.b.leaf.example.com")
+	var verr *HostValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a HostValidationError, got %#v", err)
+	}
+	// the name, plus the leaf and intermediate having expired
+	if len(verr.Mismatches) != 3 || !verr.Mismatches[0].Certificate.Equal(c.leaf) {
+		t.Errorf("unexpected mismatches %v", verr)
+	}
+	t.Log(verr)
+}
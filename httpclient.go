@@ -0,0 +1,47 @@
+package betterpem
+
+import (
+	"net/http"
+	"time"
+)
+
+// Settings for NewHTTPClient.  Every field is optional.
+type HTTPClientOptions struct {
+	// The limit on a whole request, including reading the response
+	// body.  Defaults to 30 seconds; a negative timeout means none.
+	Timeout time.Duration
+	// Defaults to TLS 1.2
+	MinVersion uint16
+	// The name to check the server's certificate against, if it isn't
+	// the host in the URL
+	ServerName string
+}
+
+// Make an http.Client which presents identity as its client certificate
+// and trusts the CAs in roots.
+//
+// Identity may be nil for a client which doesn't do mTLS, and roots nil
+// to trust the system roots.  The transport is otherwise
+// http.DefaultTransport's, with its proxy settings, dial and handshake
+// timeouts, and HTTP/2.  The parsed PEMs are not consumed.
+func NewHTTPClient(identity, roots *ParsedPEMs, opts HTTPClientOptions) (*http.Client, error) {
+	cfg, err := BuildTLSConfig(TLSConfigOptions{
+		Identity:   identity,
+		RootCAs:    roots,
+		MinVersion: opts.MinVersion,
+		ServerName: opts.ServerName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	timeout := opts.Timeout
+	switch {
+	case timeout == 0:
+		timeout = 30 * time.Second
+	case timeout < 0:
+		timeout = 0
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
@@ -0,0 +1,65 @@
+package betterpem
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	c := newTestChain(t)
+	clientCert, clientKey := c.client(t)
+	parse := func(objs ...interface{}) *ParsedPEMs {
+		p, err := ParsePEMs(c.pem(t, objs...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &p
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	pool := x509.NewCertPool()
+	pool.AddCert(c.root)
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{c.leaf.Raw, c.intermediate.Raw}, PrivateKey: c.leafKey}},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	client, err := NewHTTPClient(parse(clientCert, clientKey, c.intermediate), parse(c.root), HTTPClientOptions{ServerName: "leaf.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error making client %#v", err)
+	}
+	if client.Timeout != 30*time.Second {
+		t.Errorf("expected the default timeout, got %v", client.Timeout)
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("mTLS request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "client" {
+		t.Errorf("expected the server to see the client certificate, got %q", body)
+	}
+
+	anonymous, err := NewHTTPClient(nil, parse(c.root), HTTPClientOptions{ServerName: "leaf.example.com", Timeout: -1})
+	if err != nil {
+		t.Fatalf("unexpected error making client %#v", err)
+	}
+	if anonymous.Timeout != 0 {
+		t.Errorf("expected no timeout, got %v", anonymous.Timeout)
+	}
+	if resp, err := anonymous.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Error("expected the server to require a client certificate")
+	}
+}
@@ -0,0 +1,56 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// A private key with the certificate for it and the intermediates which
+// chain that certificate towards a root
+type Identity struct {
+	Key  crypto.Signer
+	Leaf *x509.Certificate
+	// In order from the one which issued Leaf
+	Intermediates []*x509.Certificate
+}
+
+// Return the identity as a tls.Certificate
+func (i *Identity) TLSCertificate() tls.Certificate {
+	ret := tls.Certificate{
+		Certificate: [][]byte{i.Leaf.Raw},
+		PrivateKey:  i.Key,
+		Leaf:        i.Leaf,
+	}
+	for _, c := range i.Intermediates {
+		ret.Certificate = append(ret.Certificate, c.Raw)
+	}
+	return ret
+}
+
+// Find the identity held by the parsed PEMs remaining to be consumed.
+//
+// The first private key with a matching certificate is used, along with
+// that certificate as the leaf and whichever other certificates chain
+// from it, in order, just as TLSCertificate does.  Roots are left out.
+// The parsed PEMs are not consumed.
+func (p *ParsedPEMs) ExtractIdentity() (Identity, error) {
+	certs := p.certificates()
+	found := false
+	for _, obj := range p.privateKeys() {
+		key, ok := obj.(crypto.Signer)
+		if !ok {
+			continue
+		}
+		found = true
+		for _, leaf := range certs {
+			if certMatchesKey(leaf, key) {
+				return Identity{Key: key, Leaf: leaf, Intermediates: chainFrom(leaf, certs)}, nil
+			}
+		}
+	}
+	if !found {
+		return Identity{}, ErrNoPrivateKey
+	}
+	return Identity{}, ErrNoMatchingCertificate
+}
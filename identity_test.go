@@ -0,0 +1,44 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+)
+
+func TestExtractIdentity(t *testing.T) {
+	c := newTestChain(t)
+	_, strayKey := issueTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "stray"}}, c.root, c.rootKey)
+	objs, err := ParsePEMs(c.pem(t, c.root, c.intermediate, c.leaf, strayKey, c.leafKey))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	id, err := objs.ExtractIdentity()
+	if err != nil {
+		t.Fatalf("unexpected error extracting identity %#v", err)
+	}
+	if !id.Leaf.Equal(c.leaf) || !certMatchesKey(id.Leaf, id.Key) {
+		t.Errorf("unexpected leaf %v", id.Leaf.Subject)
+	}
+	if len(id.Intermediates) != 1 || !id.Intermediates[0].Equal(c.intermediate) {
+		t.Errorf("unexpected intermediates %v", id.Intermediates)
+	}
+	if tc := id.TLSCertificate(); len(tc.Certificate) != 2 || tc.Leaf != id.Leaf {
+		t.Errorf("unexpected tls certificate %#v", tc)
+	}
+
+	certs, err := ParsePEMs(c.pem(t, c.leaf))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if _, err := certs.ExtractIdentity(); err != ErrNoPrivateKey {
+		t.Errorf("expected ErrNoPrivateKey, got %#v", err)
+	}
+	keys, err := ParsePEMs(c.pem(t, c.root, strayKey))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if _, err := keys.ExtractIdentity(); err != ErrNoMatchingCertificate {
+		t.Errorf("expected ErrNoMatchingCertificate, got %#v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+)
+
+var ErrNotJKS = errors.New("data is not a java keystore")
+var ErrJKSIntegrity = errors.New("java keystore password is incorrect or the keystore is corrupt")
+
+const (
+	jksMagic           = 0xfeedfeed
+	jksPrivateKeyEntry = 1
+	jksTrustedCertTag  = 2
+)
+
+// The algorithm Sun's JKS key protector encrypts private keys with
+var oidJKSKeyProtector = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 42, 2, 17, 1, 1}
+
+// A password the way JKS hashes it: UTF-16 big-endian without a BOM
+func jksPassword(password string) []byte {
+	ret := []byte{}
+	for _, c := range utf16.Encode([]rune(password)) {
+		ret = binary.BigEndian.AppendUint16(ret, c)
+	}
+	return ret
+}
+
+// Reads the big-endian fields of a keystore, remembering the first
+// thing that went wrong
+type jksReader struct {
+	data []byte
+	err  error
+}
+
+func (r *jksReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data) {
+		r.err = ErrNotJKS
+		return nil
+	}
+	ret := r.data[:n]
+	r.data = r.data[n:]
+	return ret
+}
+
+func (r *jksReader) uint16() int {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(b))
+}
+
+func (r *jksReader) uint32() int {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(b))
+}
+
+// A certificate within an entry, preceded by its type on version 2
+// keystores
+func (r *jksReader) certificate(version int) (*x509.Certificate, error) {
+	if version == 2 {
+		if typ := r.bytes(r.uint16()); r.err == nil && string(typ) != "X.509" {
+			return nil, errors.New("java keystore holds a certificate of type " + string(typ))
+		}
+	}
+	der := r.bytes(r.uint32())
+	if r.err != nil {
+		return nil, r.err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// Undo Sun's JKS key protector, which XORs the key with a keystream of
+// chained SHA-1 hashes of the password and a salt
+func jksDecryptKey(encrypted, password []byte) ([]byte, error) {
+	var info struct {
+		Algo struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.RawValue `asn1:"optional"`
+		}
+		Data []byte
+	}
+	if _, err := asn1.Unmarshal(encrypted, &info); err != nil {
+		return nil, err
+	}
+	if !info.Algo.Algorithm.Equal(oidJKSKeyProtector) {
+		return nil, ErrUnsupportedEncryption
+	}
+	if len(info.Data) < 2*sha1.Size {
+		return nil, ErrNotJKS
+	}
+	salt := info.Data[:sha1.Size]
+	ciphertext := info.Data[sha1.Size : len(info.Data)-sha1.Size]
+	check := info.Data[len(info.Data)-sha1.Size:]
+	plain := make([]byte, len(ciphertext))
+	digest := salt
+	for i := 0; i < len(ciphertext); i += sha1.Size {
+		sum := sha1.Sum(append(append([]byte{}, password...), digest...))
+		digest = sum[:]
+		for j := 0; j < sha1.Size && i+j < len(ciphertext); j++ {
+			plain[i+j] = ciphertext[i+j] ^ digest[j]
+		}
+	}
+	sum := sha1.Sum(append(append([]byte{}, password...), plain...))
+	if subtle.ConstantTimeCompare(sum[:], check) != 1 {
+		return nil, x509.IncorrectPasswordError
+	}
+	return plain, nil
+}
+
+// Extract the private keys and certificates from a Java KeyStore (.jks)
+// file, such as when moving a service off of Java.
+//
+// Entries are kept in the keystore's order: a private key entry gives
+// the key followed by its certificate chain, and a trusted certificate
+// entry gives the certificate.  Private keys must be protected by
+// storepass too, as keytool does by default.  A keystore which fails its
+// integrity check, usually because of a wrong storepass, gives
+// ErrJKSIntegrity.  JCEKS and PKCS#12 keystores aren't JKS and give
+// ErrNotJKS; see ParsePKCS12 for the latter.
+func ParseJKS(data []byte, storepass string) (ParsedPEMs, error) {
+	password := jksPassword(storepass)
+	if len(data) < sha1.Size {
+		return ParsedPEMs{}, ErrNotJKS
+	}
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	r := &jksReader{data: body}
+	if r.uint32() != jksMagic {
+		return ParsedPEMs{}, ErrNotJKS
+	}
+	version := r.uint32()
+	if version != 1 && version != 2 {
+		return ParsedPEMs{}, ErrNotJKS
+	}
+	h := sha1.New()
+	h.Write(password)
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(body)
+	if !bytes.Equal(h.Sum(nil), digest) {
+		return ParsedPEMs{}, ErrJKSIntegrity
+	}
+
+	ret := ParsedPEMs{}
+	for count := r.uint32(); count > 0 && r.err == nil; count-- {
+		tag := r.uint32()
+		// the alias, then the creation time in milliseconds
+		r.bytes(r.uint16())
+		r.bytes(8)
+		switch tag {
+		case jksPrivateKeyEntry:
+			encrypted := r.bytes(r.uint32())
+			if r.err != nil {
+				break
+			}
+			der, err := jksDecryptKey(encrypted, password)
+			if err != nil {
+				return ParsedPEMs{}, err
+			}
+			key, err := x509.ParsePKCS8PrivateKey(der)
+			if err != nil {
+				return ParsedPEMs{}, err
+			}
+			ret.add(key, nil)
+			for n := r.uint32(); n > 0 && r.err == nil; n-- {
+				cert, err := r.certificate(version)
+				if err != nil {
+					return ParsedPEMs{}, err
+				}
+				ret.add(cert, nil)
+			}
+		case jksTrustedCertTag:
+			cert, err := r.certificate(version)
+			if err != nil {
+				return ParsedPEMs{}, err
+			}
+			ret.add(cert, nil)
+		default:
+			// secret keys only exist in JCEKS
+			return ParsedPEMs{}, ErrNotJKS
+		}
+	}
+	if r.err != nil {
+		return ParsedPEMs{}, r.err
+	}
+	return ret, nil
+}
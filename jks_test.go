@@ -0,0 +1,122 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+type jksTestEntry struct {
+	key   interface{}
+	certs []*x509.Certificate
+}
+
+// Write a version 2 keystore the way keytool would
+func encodeTestJKS(t *testing.T, entries []jksTestEntry, storepass string) []byte {
+	password := jksPassword(storepass)
+	var buf bytes.Buffer
+	u16 := func(n int) { buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n))) }
+	u32 := func(n int) { buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n))) }
+	cert := func(c *x509.Certificate) {
+		u16(5)
+		buf.WriteString("X.509")
+		u32(len(c.Raw))
+		buf.Write(c.Raw)
+	}
+	u32(jksMagic)
+	u32(2)
+	u32(len(entries))
+	for _, e := range entries {
+		tag := jksTrustedCertTag
+		if e.key != nil {
+			tag = jksPrivateKeyEntry
+		}
+		u32(tag)
+		u16(5)
+		buf.WriteString("alias")
+		buf.Write(make([]byte, 8))
+		if e.key == nil {
+			cert(e.certs[0])
+			continue
+		}
+		plain, err := x509.MarshalPKCS8PrivateKey(e.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		salt := bytes.Repeat([]byte{7}, sha1.Size)
+		data := append([]byte{}, salt...)
+		digest := salt
+		for i := 0; i < len(plain); i += sha1.Size {
+			sum := sha1.Sum(append(append([]byte{}, password...), digest...))
+			digest = sum[:]
+			for j := 0; j < sha1.Size && i+j < len(plain); j++ {
+				data = append(data, plain[i+j]^digest[j])
+			}
+		}
+		check := sha1.Sum(append(append([]byte{}, password...), plain...))
+		data = append(data, check[:]...)
+		encrypted, err := asn1.Marshal(struct {
+			Algo pkix.AlgorithmIdentifier
+			Data []byte
+		}{pkix.AlgorithmIdentifier{Algorithm: oidJKSKeyProtector, Parameters: asn1.NullRawValue}, data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		u32(len(encrypted))
+		buf.Write(encrypted)
+		u32(len(e.certs))
+		for _, c := range e.certs {
+			cert(c)
+		}
+	}
+	h := sha1.New()
+	h.Write(password)
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(buf.Bytes())
+	return h.Sum(buf.Bytes())
+}
+
+func TestParseJKS(t *testing.T) {
+	c := newTestChain(t)
+	jks := encodeTestJKS(t, []jksTestEntry{
+		{key: c.leafKey, certs: []*x509.Certificate{c.leaf, c.intermediate}},
+		{certs: []*x509.Certificate{c.root}},
+	}, "changeit")
+	pems, err := ParseJKS(jks, "changeit")
+	if err != nil {
+		t.Fatalf("unexpected error parsing jks %#v", err)
+	}
+	if pems.Length() != 4 {
+		t.Fatalf("expected a key and 3 certificates, got %d objects", pems.Length())
+	}
+	if !pems.MustECPrivateKey().Equal(c.leafKey) {
+		t.Error("expected the key first")
+	}
+	for _, want := range []*x509.Certificate{c.leaf, c.intermediate, c.root} {
+		if !pems.MustCertificate().Equal(want) {
+			t.Errorf("expected %s next", want.Subject)
+		}
+	}
+
+	if _, err := ParseJKS(jks, "wrong"); err != ErrJKSIntegrity {
+		t.Errorf("expected ErrJKSIntegrity, got %#v", err)
+	}
+	if _, err := ParseJKS(test_ca, "changeit"); err != ErrNotJKS {
+		t.Errorf("expected ErrNotJKS, got %#v", err)
+	}
+	truncated := encodeTestJKS(t, []jksTestEntry{{certs: []*x509.Certificate{c.root}}}, "changeit")
+	binary.BigEndian.PutUint32(truncated[8:], 2)
+	h := sha1.New()
+	h.Write(jksPassword("changeit"))
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(truncated[:len(truncated)-sha1.Size])
+	copy(truncated[len(truncated)-sha1.Size:], h.Sum(nil))
+	if _, err := ParseJKS(truncated, "changeit"); !errors.Is(err, ErrNotJKS) {
+		t.Errorf("expected ErrNotJKS for a keystore missing an entry, got %#v", err)
+	}
+}
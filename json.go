@@ -0,0 +1,170 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// The subject alternative names of a certificate, by kind
+type SANDetails struct {
+	DNS   []string `json:"dns"`
+	IP    []string `json:"ip"`
+	Email []string `json:"email"`
+	URI   []string `json:"uri"`
+}
+
+func newSANDetails(dns []string, ips []net.IP, emails []string, uris []*url.URL) SANDetails {
+	d := SANDetails{DNS: []string{}, IP: []string{}, Email: []string{}, URI: []string{}}
+	d.DNS = append(d.DNS, dns...)
+	for _, ip := range ips {
+		d.IP = append(d.IP, ip.String())
+	}
+	d.Email = append(d.Email, emails...)
+	for _, u := range uris {
+		d.URI = append(d.URI, u.String())
+	}
+	return d
+}
+
+// A certificate extension, identified by OID
+type ExtensionDetails struct {
+	OID      string `json:"oid"`
+	Name     string `json:"name"`
+	Critical bool   `json:"critical"`
+}
+
+// The fields of a certificate worth auditing or displaying.
+//
+// This is the schema produced by CertificateJSON.  Fields may be added
+// but existing fields will keep their names and meanings.  Digests and
+// identifiers are lowercase hex.
+type CertificateDetails struct {
+	Subject            string             `json:"subject"`
+	Issuer             string             `json:"issuer"`
+	SerialNumber       string             `json:"serial_number"`
+	NotBefore          time.Time          `json:"not_before"`
+	NotAfter           time.Time          `json:"not_after"`
+	SANs               SANDetails         `json:"sans"`
+	IsCA               bool               `json:"is_ca"`
+	Key                KeyInfo            `json:"key"`
+	SignatureAlgorithm string             `json:"signature_algorithm"`
+	SHA1Fingerprint    string             `json:"sha1_fingerprint"`
+	SHA256Fingerprint  string             `json:"sha256_fingerprint"`
+	SPKISHA256         string             `json:"spki_sha256"`
+	SubjectKeyID       string             `json:"subject_key_id,omitempty"`
+	AuthorityKeyID     string             `json:"authority_key_id,omitempty"`
+	KeyUsage           []string           `json:"key_usage"`
+	ExtKeyUsage        []string           `json:"ext_key_usage"`
+	Extensions         []ExtensionDetails `json:"extensions"`
+}
+
+func newCertificateDetails(cert *x509.Certificate) (CertificateDetails, error) {
+	d := CertificateDetails{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       hex.EncodeToString(cert.SerialNumber.Bytes()),
+		NotBefore:          cert.NotBefore.UTC(),
+		NotAfter:           cert.NotAfter.UTC(),
+		SANs:               newSANDetails(cert.DNSNames, cert.IPAddresses, cert.EmailAddresses, cert.URIs),
+		IsCA:               cert.IsCA,
+		Key:                keyInfoOf(cert.PublicKey),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		SubjectKeyID:       hex.EncodeToString(cert.SubjectKeyId),
+		AuthorityKeyID:     hex.EncodeToString(cert.AuthorityKeyId),
+		KeyUsage:           describeKeyUsage(cert.KeyUsage),
+		ExtKeyUsage:        describeExtKeyUsage(cert),
+		Extensions:         []ExtensionDetails{},
+	}
+	for _, ext := range cert.Extensions {
+		d.Extensions = append(d.Extensions, ExtensionDetails{
+			OID:      ext.Id.String(),
+			Name:     extensionName(ext.Id),
+			Critical: ext.Critical,
+		})
+	}
+
+	sha1fp, err := Fingerprint(cert, crypto.SHA1)
+	if err != nil {
+		return CertificateDetails{}, err
+	}
+	d.SHA1Fingerprint = sha1fp.Hex()
+	sha256fp, err := Fingerprint(cert, crypto.SHA256)
+	if err != nil {
+		return CertificateDetails{}, err
+	}
+	d.SHA256Fingerprint = sha256fp.Hex()
+	spkifp, err := Fingerprint(cert.PublicKey, crypto.SHA256)
+	if err != nil {
+		return CertificateDetails{}, err
+	}
+	d.SPKISHA256 = spkifp.Hex()
+	return d, nil
+}
+
+// Serialize a certificate's details as JSON.
+//
+// See CertificateDetails for the schema.
+func CertificateJSON(cert *x509.Certificate) ([]byte, error) {
+	d, err := newCertificateDetails(cert)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(d)
+}
+
+// One entry in the JSON produced by ParsedPEMs.ToJSON
+type ObjectDetails struct {
+	// "certificate", "private_key", "public_key", or the Go type of
+	// anything else
+	Type        string              `json:"type"`
+	Certificate *CertificateDetails `json:"certificate,omitempty"`
+	Key         *KeyInfo            `json:"key,omitempty"`
+}
+
+func newObjectDetails(obj interface{}) (ObjectDetails, error) {
+	switch v := obj.(type) {
+	case *x509.Certificate:
+		d, err := newCertificateDetails(v)
+		if err != nil {
+			return ObjectDetails{}, err
+		}
+		return ObjectDetails{Type: "certificate", Certificate: &d}, nil
+	case crypto.Signer:
+		k := keyInfoOf(v.Public())
+		return ObjectDetails{Type: "private_key", Key: &k}, nil
+	case *ecdh.PrivateKey:
+		k := keyInfoOf(v.PublicKey())
+		return ObjectDetails{Type: "private_key", Key: &k}, nil
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, *ecdh.PublicKey:
+		k := keyInfoOf(v)
+		return ObjectDetails{Type: "public_key", Key: &k}, nil
+	}
+	return ObjectDetails{Type: fmt.Sprintf("%T", obj)}, nil
+}
+
+// Serialize every parsed PEM remaining to be consumed as a JSON array of
+// ObjectDetails, in order.
+//
+// The parsed PEMs are not consumed.
+func (p *ParsedPEMs) ToJSON() ([]byte, error) {
+	ret := make([]ObjectDetails, 0, len(p.objs))
+	for _, obj := range p.all() {
+		d, err := newObjectDetails(obj)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, d)
+	}
+	return json.Marshal(ret)
+}
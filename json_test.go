@@ -0,0 +1,56 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestCertificateJSON(t *testing.T) {
+	objs, err := ParsePEMs(test_rsacert)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	cert := objs.MustCertificate()
+	j, err := CertificateJSON(cert)
+	if err != nil {
+		t.Fatalf("unexpected error serializing certificate %#v", err)
+	}
+	var d CertificateDetails
+	if err := json.Unmarshal(j, &d); err != nil {
+		t.Fatalf("could not read our own json: %v", err)
+	}
+	sum := sha256.Sum256(cert.Raw)
+	if d.SHA256Fingerprint != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected fingerprint %s", d.SHA256Fingerprint)
+	}
+	if d.Subject != cert.Subject.String() || d.Key.Algorithm != "RSA" || d.Key.Bits != 512 {
+		t.Errorf("unexpected details %s", j)
+	}
+	if !d.NotAfter.Equal(cert.NotAfter) {
+		t.Errorf("not after %v != %v", d.NotAfter, cert.NotAfter)
+	}
+}
+
+func TestParsedPEMsToJSON(t *testing.T) {
+	objs, err := ParsePEMs(bytes.Join([][]byte{test_eccert, test_eckey}, []byte{'\n'}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	j, err := objs.ToJSON()
+	if err != nil {
+		t.Fatalf("unexpected error serializing %#v", err)
+	}
+	var ds []ObjectDetails
+	if err := json.Unmarshal(j, &ds); err != nil {
+		t.Fatalf("could not read our own json: %v", err)
+	}
+	if len(ds) != 2 || ds[0].Type != "certificate" || ds[1].Type != "private_key" {
+		t.Fatalf("unexpected json %s", j)
+	}
+	if ds[1].Key.Curve != "P-521" {
+		t.Errorf("unexpected key details %#v", ds[1].Key)
+	}
+}
@@ -0,0 +1,309 @@
+package betterpem
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidJWK = errors.New("invalid JWK")
+var ErrUnsupportedJWK = errors.New("JWK is of an unsupported key type")
+
+// The members of an RFC 7517 JSON Web Key betterpem uses
+type jwk struct {
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid"`
+	Use string   `json:"use"`
+	Alg string   `json:"alg"`
+	Crv string   `json:"crv"`
+	X5c []string `json:"x5c"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	D string `json:"d"`
+	P string `json:"p"`
+	Q string `json:"q"`
+	// EC and OKP
+	X string `json:"x"`
+	Y string `json:"y"`
+}
+
+var jwkCurves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+var jwkECDHCurves = map[string]ecdh.Curve{
+	"P-256": ecdh.P256(),
+	"P-384": ecdh.P384(),
+	"P-521": ecdh.P521(),
+}
+
+// Decode a base64url member, which should be unpadded but sometimes isn't
+func jwkBytes(name, value string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("%w: bad %q member", ErrInvalidJWK, name)
+	}
+	return b, nil
+}
+
+func jwkInt(name, value string) (*big.Int, error) {
+	b, err := jwkBytes(name, value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// Decode a member holding a fixed size value, left padding it if leading
+// zeroes were dropped
+func jwkFixed(name, value string, size int) ([]byte, error) {
+	b, err := jwkBytes(name, value)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > size {
+		return nil, fmt.Errorf("%w: %q member is too long", ErrInvalidJWK, name)
+	}
+	return append(make([]byte, size-len(b)), b...), nil
+}
+
+func (k *jwk) rsaKey() (interface{}, error) {
+	n, err := jwkInt("n", k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := jwkInt("e", k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("%w: RSA exponent is too large", ErrInvalidJWK)
+	}
+	pub := rsa.PublicKey{N: n, E: int(e.Int64())}
+	if k.D == "" {
+		return &pub, nil
+	}
+	if k.P == "" || k.Q == "" {
+		return nil, fmt.Errorf("%w: RSA private key has no primes", ErrInvalidJWK)
+	}
+	d, err := jwkInt("d", k.D)
+	if err != nil {
+		return nil, err
+	}
+	p, err := jwkInt("p", k.P)
+	if err != nil {
+		return nil, err
+	}
+	q, err := jwkInt("q", k.Q)
+	if err != nil {
+		return nil, err
+	}
+	key := &rsa.PrivateKey{PublicKey: pub, D: d, Primes: []*big.Int{p, q}}
+	if err := key.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+	// dp, dq and qi are computed rather than trusted
+	key.Precompute()
+	return key, nil
+}
+
+func (k *jwk) ecKey() (interface{}, error) {
+	curve, ok := jwkCurves[k.Crv]
+	if !ok {
+		return nil, fmt.Errorf("%w: EC curve %q", ErrUnsupportedJWK, k.Crv)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	x, err := jwkFixed("x", k.X, size)
+	if err != nil {
+		return nil, err
+	}
+	y, err := jwkFixed("y", k.Y, size)
+	if err != nil {
+		return nil, err
+	}
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := jwkECDHCurves[k.Crv].NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("%w: point is not on the curve", ErrInvalidJWK)
+	}
+	pub := ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if k.D == "" {
+		return &pub, nil
+	}
+	d, err := jwkFixed("d", k.D, size)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := jwkECDHCurves[k.Crv].NewPrivateKey(d)
+	if err != nil || string(priv.PublicKey().Bytes()) != string(point) {
+		return nil, fmt.Errorf("%w: private key does not match its public key", ErrInvalidJWK)
+	}
+	return &ecdsa.PrivateKey{PublicKey: pub, D: new(big.Int).SetBytes(d)}, nil
+}
+
+func (k *jwk) okpKey() (interface{}, error) {
+	switch k.Crv {
+	case "Ed25519":
+		x, err := jwkBytes("x", k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: bad %q member", ErrInvalidJWK, "x")
+		}
+		if k.D == "" {
+			return ed25519.PublicKey(x), nil
+		}
+		d, err := jwkBytes("d", k.D)
+		if err != nil || len(d) != ed25519.SeedSize {
+			return nil, fmt.Errorf("%w: bad %q member", ErrInvalidJWK, "d")
+		}
+		key := ed25519.NewKeyFromSeed(d)
+		if !key.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+			return nil, fmt.Errorf("%w: private key does not match its public key", ErrInvalidJWK)
+		}
+		return key, nil
+	case "X25519":
+		x, err := jwkBytes("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := ecdh.X25519().NewPublicKey(x)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad %q member", ErrInvalidJWK, "x")
+		}
+		if k.D == "" {
+			return pub, nil
+		}
+		d, err := jwkBytes("d", k.D)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ecdh.X25519().NewPrivateKey(d)
+		if err != nil || !key.PublicKey().Equal(pub) {
+			return nil, fmt.Errorf("%w: private key does not match its public key", ErrInvalidJWK)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: OKP curve %q", ErrUnsupportedJWK, k.Crv)
+}
+
+// Add the key and its x5c chain to pems
+func (k *jwk) addTo(pems *ParsedPEMs) error {
+	var key interface{}
+	var err error
+	switch k.Kty {
+	case "RSA":
+		key, err = k.rsaKey()
+	case "EC":
+		key, err = k.ecKey()
+	case "OKP":
+		key, err = k.okpKey()
+	case "":
+		return fmt.Errorf("%w: no %q member", ErrInvalidJWK, "kty")
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedJWK, k.Kty)
+	}
+	if err != nil {
+		return err
+	}
+	certs := []*x509.Certificate{}
+	for _, s := range k.X5c {
+		// unlike the rest of the key, x5c is standard base64
+		der, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("%w: bad %q member", ErrInvalidJWK, "x5c")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) > 0 {
+		pub, err := publicKeyOf(key)
+		if err != nil || !publicKeysEqual(pub, certs[0].PublicKey) {
+			return fmt.Errorf("%w: the first x5c certificate is for a different key", ErrInvalidJWK)
+		}
+	}
+
+	headers := map[string]string{}
+	for name, value := range map[string]string{"kid": k.Kid, "use": k.Use, "alg": k.Alg} {
+		if value != "" {
+			headers[name] = value
+		}
+	}
+	if len(headers) == 0 {
+		pems.add(key, nil)
+	} else if block, err := blockFor(key); err == nil {
+		block.Headers = headers
+		pems.add(key, block)
+	} else {
+		pems.add(key, nil)
+	}
+	for _, cert := range certs {
+		pems.add(cert, nil)
+	}
+	return nil
+}
+
+// Convert an RFC 7517 JSON Web Key into the objects ParsePEMs would give
+// for the same key in PEM: the key, then the certificates from its x5c
+// chain.
+//
+// RSA, EC, and OKP keys are supported, public or private.  The key's
+// kid, use, and alg members are its Headers, and are written as headers
+// by Encode.  Other key types give an error matching ErrUnsupportedJWK,
+// and malformed keys one matching ErrInvalidJWK.
+func ParseJWK(data []byte) (ParsedPEMs, error) {
+	var k jwk
+	if err := json.Unmarshal(data, &k); err != nil {
+		return ParsedPEMs{}, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+	ret := ParsedPEMs{}
+	if err := k.addTo(&ret); err != nil {
+		return ParsedPEMs{}, err
+	}
+	return ret, nil
+}
+
+// Convert an RFC 7517 JSON Web Key Set, such as the one an OIDC provider
+// publishes at its jwks_uri, into parsed objects in order, as ParseJWK
+// does for each key.
+//
+// Keys of unsupported types are skipped, as ParsePEMs skips unsupported
+// blocks, unless there are no others.  A set with no keys gives empty
+// ParsedPEMs.
+func ParseJWKS(data []byte) (ParsedPEMs, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return ParsedPEMs{}, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+	ret := ParsedPEMs{}
+	var unsupported error
+	for _, raw := range set.Keys {
+		var k jwk
+		if err := json.Unmarshal(raw, &k); err != nil {
+			return ParsedPEMs{}, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+		}
+		if err := k.addTo(&ret); errors.Is(err, ErrUnsupportedJWK) {
+			unsupported = err
+		} else if err != nil {
+			return ParsedPEMs{}, err
+		}
+	}
+	if ret.Length() == 0 && unsupported != nil {
+		return ParsedPEMs{}, unsupported
+	}
+	return ret, nil
+}
@@ -0,0 +1,106 @@
+package betterpem
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func b64url(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func testJWK(t *testing.T, members map[string]interface{}) []byte {
+	b, err := json.Marshal(members)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseJWK(t *testing.T) {
+	c := newTestChain(t)
+	key := c.leafKey.(*ecdsa.PrivateKey)
+	ec := map[string]interface{}{
+		"kty": "EC", "crv": "P-256", "kid": "leaf",
+		"x":   b64url(key.X.FillBytes(make([]byte, 32))),
+		"y":   b64url(key.Y.FillBytes(make([]byte, 32))),
+		"d":   b64url(key.D.FillBytes(make([]byte, 32))),
+		"x5c": []string{base64.StdEncoding.EncodeToString(c.leaf.Raw), base64.StdEncoding.EncodeToString(c.intermediate.Raw)},
+	}
+	pems, err := ParseJWK(testJWK(t, ec))
+	if err != nil {
+		t.Fatalf("unexpected error parsing jwk %#v", err)
+	}
+	if pems.Length() != 3 || pems.Headers()["kid"] != "leaf" {
+		t.Fatalf("expected the key with its kid and 2 certificates, got %d objects", pems.Length())
+	}
+	if !pems.MustECPrivateKey().Equal(key) || !pems.MustCertificate().Equal(c.leaf) || !pems.MustCertificate().Equal(c.intermediate) {
+		t.Errorf("unexpected objects from the jwk")
+	}
+
+	ec["x5c"] = []string{base64.StdEncoding.EncodeToString(c.root.Raw)}
+	if _, err := ParseJWK(testJWK(t, ec)); !errors.Is(err, ErrInvalidJWK) {
+		t.Errorf("expected ErrInvalidJWK for an x5c certificate for another key, got %#v", err)
+	}
+	delete(ec, "x5c")
+	ec["y"] = ec["x"]
+	if _, err := ParseJWK(testJWK(t, ec)); !errors.Is(err, ErrInvalidJWK) {
+		t.Errorf("expected ErrInvalidJWK for a point not on the curve, got %#v", err)
+	}
+	if _, err := ParseJWK(testJWK(t, map[string]interface{}{"kty": "oct", "k": "AAAA"})); !errors.Is(err, ErrUnsupportedJWK) {
+		t.Errorf("expected ErrUnsupportedJWK, got %#v", err)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	pems, err := ParsePEMs(test_rsakey)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	rsaKey := pems.MustRSAPrivateKey()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := json.Marshal(map[string]interface{}{"keys": []map[string]interface{}{
+		{"kty": "RSA", "n": b64url(rsaKey.N.Bytes()), "e": b64url(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "oct", "k": "AAAA"},
+		{"kty": "OKP", "crv": "Ed25519", "x": b64url(pub), "d": b64url(priv.Seed())},
+		{"kty": "RSA", "n": b64url(rsaKey.N.Bytes()), "e": b64url(big.NewInt(int64(rsaKey.E)).Bytes()),
+			"d": b64url(rsaKey.D.Bytes()), "p": b64url(rsaKey.Primes[0].Bytes()), "q": b64url(rsaKey.Primes[1].Bytes())},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := ParseJWKS(set)
+	if err != nil {
+		t.Fatalf("unexpected error parsing jwks %#v", err)
+	}
+	if jwks.Length() != 3 {
+		t.Fatalf("expected the unsupported key to be skipped, got %d objects", jwks.Length())
+	}
+	if obj, _ := jwks.Next(); !obj.(*rsa.PublicKey).Equal(&rsaKey.PublicKey) {
+		t.Errorf("expected the RSA public key first")
+	}
+	if obj, _ := jwks.Next(); !obj.(ed25519.PrivateKey).Equal(priv) {
+		t.Errorf("expected the Ed25519 key second")
+	}
+	if !jwks.MustRSAPrivateKey().Equal(rsaKey) {
+		t.Errorf("expected the RSA private key last")
+	}
+	if der, err := x509.MarshalPKCS8PrivateKey(priv); err != nil || len(der) == 0 {
+		t.Errorf("Ed25519 key from the jwks could not be re-encoded")
+	}
+
+	if _, err := ParseJWKS([]byte(`{"keys": [{"kty": "oct", "k": "AAAA"}]}`)); !errors.Is(err, ErrUnsupportedJWK) {
+		t.Errorf("expected ErrUnsupportedJWK for a set of only unsupported keys, got %#v", err)
+	}
+}
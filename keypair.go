@@ -0,0 +1,71 @@
+package betterpem
+
+import (
+	"crypto/tls"
+	"sync/atomic"
+)
+
+// A thread-safe holder for a tls.Certificate which can be swapped out
+// while it's in use, such as when rotating a server's identity.
+//
+// The zero value holds no certificate.  Its GetCertificate and
+// GetClientCertificate methods can be used directly in a tls.Config.
+type KeyPair struct {
+	cert atomic.Pointer[tls.Certificate]
+}
+
+// Make a KeyPair holding the identity in PEM data.
+//
+// See ReloadFrom.
+func NewKeyPair(pemInt interface{}) (*KeyPair, error) {
+	kp := &KeyPair{}
+	if err := kp.ReloadFrom(pemInt); err != nil {
+		return nil, err
+	}
+	return kp, nil
+}
+
+// Return the current certificate, or nil if none has been stored
+func (kp *KeyPair) Load() *tls.Certificate {
+	return kp.cert.Load()
+}
+
+// Replace the current certificate
+func (kp *KeyPair) Store(cert *tls.Certificate) {
+	kp.cert.Store(cert)
+}
+
+// Parse PEM data and replace the current certificate with the identity
+// found in it.
+//
+// See ParsePEMs for the kinds of input accepted and
+// ParsedPEMs.TLSCertificate for how the identity is found.  If anything
+// goes wrong, the current certificate is kept.
+func (kp *KeyPair) ReloadFrom(pemInt interface{}) error {
+	pems, err := ParsePEMs(pemInt)
+	if err != nil {
+		return err
+	}
+	cert, err := pems.TLSCertificate()
+	if err != nil {
+		return err
+	}
+	kp.Store(&cert)
+	return nil
+}
+
+// Return the current certificate, for use as tls.Config.GetCertificate
+func (kp *KeyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return kp.Load(), nil
+}
+
+// Return the current certificate, for use as
+// tls.Config.GetClientCertificate
+func (kp *KeyPair) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert := kp.Load()
+	if cert == nil {
+		// tls wants an empty certificate rather than nil to send none
+		return &tls.Certificate{}, nil
+	}
+	return cert, nil
+}
@@ -0,0 +1,50 @@
+package betterpem
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestKeyPair(t *testing.T) {
+	var empty KeyPair
+	if empty.Load() != nil {
+		t.Error("zero KeyPair should hold no certificate")
+	}
+	if c, err := empty.GetClientCertificate(nil); err != nil || c == nil || len(c.Certificate) != 0 {
+		t.Error("zero KeyPair should send no client certificate")
+	}
+
+	first := newTestChain(t)
+	kp, err := NewKeyPair(first.pem(t, first.leaf, first.leafKey))
+	if err != nil {
+		t.Fatalf("unexpected error making key pair %#v", err)
+	}
+	second := newTestChain(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if c, _ := kp.GetCertificate(nil); c == nil || c.Leaf == nil {
+					t.Error("unexpected empty certificate")
+					return
+				}
+			}
+		}()
+	}
+	if err := kp.ReloadFrom(second.pem(t, second.leaf, second.leafKey)); err != nil {
+		t.Fatalf("unexpected error reloading %#v", err)
+	}
+	wg.Wait()
+	if !kp.Load().Leaf.Equal(second.leaf) {
+		t.Error("reload did not replace the certificate")
+	}
+
+	if err := kp.ReloadFrom(first.pem(t, first.leaf)); err != ErrNoPrivateKey {
+		t.Errorf("expected ErrNoPrivateKey, got %#v", err)
+	}
+	if !kp.Load().Leaf.Equal(second.leaf) {
+		t.Error("a failed reload replaced the certificate")
+	}
+}
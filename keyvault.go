@@ -0,0 +1,50 @@
+package betterpem
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+var ErrUnknownContentType = errors.New("secret has an unknown content type")
+
+// The content types Key Vault gives certificate secrets
+const (
+	KeyVaultPEM    = "application/x-pem-file"
+	KeyVaultPKCS12 = "application/x-pkcs12"
+)
+
+// Parse the value of an Azure Key Vault secret backing a certificate, as
+// it is returned with its contentType, into ParsedPEMs.
+//
+// A certificate created with the PEM content type gives its key and
+// certificates as PEM, which is parsed with ParsePEMs.  One created with
+// the PKCS #12 content type gives a base64 encoded PFX with an empty
+// password, which is parsed with ParsePKCS12.  If contentType is empty,
+// the kind is guessed from the value.  Other content types give an error
+// matching ErrUnknownContentType.
+func ParseKeyVaultSecret(value, contentType string) (ParsedPEMs, error) {
+	if contentType == "" {
+		contentType = KeyVaultPKCS12
+		if strings.Contains(value, string(beginMarker)) {
+			contentType = KeyVaultPEM
+		}
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ParsedPEMs{}, fmt.Errorf("%w: %q", ErrUnknownContentType, contentType)
+	}
+	switch mediaType {
+	case KeyVaultPEM:
+		return ParsePEMs([]byte(value))
+	case KeyVaultPKCS12:
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+		if err != nil {
+			return ParsedPEMs{}, err
+		}
+		return ParsePKCS12(der, "")
+	}
+	return ParsedPEMs{}, fmt.Errorf("%w: %q", ErrUnknownContentType, contentType)
+}
@@ -0,0 +1,42 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestParseKeyVaultSecret(t *testing.T) {
+	c := newTestChain(t)
+	pfx, err := EncodePKCS12(c.leafKey, c.leaf, []*x509.Certificate{c.intermediate}, "")
+	if err != nil {
+		t.Fatalf("unexpected error encoding pkcs12 %#v", err)
+	}
+	pemValue := string(c.pem(t, c.leafKey, c.leaf, c.intermediate))
+	pfxValue := base64.StdEncoding.EncodeToString(pfx)
+
+	for _, tc := range []struct {
+		value, contentType string
+	}{
+		{pemValue, KeyVaultPEM},
+		{pfxValue, KeyVaultPKCS12},
+		{pemValue, ""},
+		{pfxValue, ""},
+	} {
+		pems, err := ParseKeyVaultSecret(tc.value, tc.contentType)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q secret %#v", tc.contentType, err)
+		}
+		if pems.Length() != 3 {
+			t.Fatalf("expected a key and 2 certificates, got %d objects", pems.Length())
+		}
+		if !pems.MustECPrivateKey().Equal(c.leafKey) || !pems.MustCertificate().Equal(c.leaf) {
+			t.Errorf("expected the key and leaf from a %q secret", tc.contentType)
+		}
+	}
+
+	if _, err := ParseKeyVaultSecret(pemValue, "text/plain"); !errors.Is(err, ErrUnknownContentType) {
+		t.Errorf("expected ErrUnknownContentType, got %#v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package betterpem
+
+import (
+	"bytes"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// A line of an OpenSSH known_hosts file
+type KnownHost struct {
+	// The host patterns the key is for, such as "example.com",
+	// "[example.com]:2222", "*.example.com", or a hashed "|1|..." name.
+	// Negated patterns start with "!".
+	Hosts []string
+	Key   ssh.PublicKey
+	// Whether the line is marked @cert-authority, so Key signs host
+	// certificates rather than being a host key
+	CertAuthority bool
+	// Whether the line is marked @revoked, so Key must never be accepted
+	Revoked bool
+	Comment string
+	// The line number, counting from 1
+	Line int
+}
+
+// A known_hosts line which couldn't be parsed
+type KnownHostsError struct {
+	// The line number, counting from 1
+	Line int
+	Err  error
+}
+
+func (e *KnownHostsError) Error() string {
+	return fmt.Sprintf("known_hosts line %d: %v", e.Line, e.Err)
+}
+
+func (e *KnownHostsError) Unwrap() error {
+	return e.Err
+}
+
+// Parse an OpenSSH known_hosts file, or anything else in its format such
+// as a ssh_known_hosts file, for auditing SSH trust alongside TLS trust.
+//
+// Input may be anything ParsePEMs accepts.  Every host key line is
+// returned in order, including @cert-authority and @revoked lines;
+// comments and blank lines are skipped.  A line which can't be parsed
+// gives a *KnownHostsError saying which line it was.
+func ParseKnownHosts(data interface{}) ([]KnownHost, error) {
+	input, err := intoBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	hosts := []KnownHost{}
+	for n := 1; len(input) > 0; n++ {
+		line := input
+		if eol := bytes.IndexByte(input, '\n'); eol >= 0 {
+			line, input = input[:eol], input[eol+1:]
+		} else {
+			input = nil
+		}
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] == '#' {
+			continue
+		}
+		marker, patterns, key, comment, _, err := ssh.ParseKnownHosts(trimmed)
+		if err != nil {
+			return nil, &KnownHostsError{Line: n, Err: err}
+		}
+		hosts = append(hosts, KnownHost{
+			Hosts:         patterns,
+			Key:           key,
+			CertAuthority: marker == "cert-authority",
+			Revoked:       marker == "revoked",
+			Comment:       comment,
+			Line:          n,
+		})
+	}
+	return hosts, nil
+}
@@ -0,0 +1,50 @@
+package betterpem
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseKnownHosts(t *testing.T) {
+	pems, err := ParsePEMs(test_eckey)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	key, err := ToAuthorizedKey(pems.MustECPrivateKey(), "")
+	if err != nil {
+		t.Fatalf("unexpected error encoding key %#v", err)
+	}
+	key = strings.TrimSpace(key)
+	data := strings.Join([]string{
+		"# a comment",
+		"example.com,[example.com]:2222 " + key + " host key",
+		"",
+		"@cert-authority *.example.com " + key,
+		"@revoked |1|c2FsdA==|aGFzaA== " + key,
+	}, "\n")
+	hosts, err := ParseKnownHosts(data)
+	if err != nil {
+		t.Fatalf("unexpected error parsing known_hosts %#v", err)
+	}
+	if len(hosts) != 3 {
+		t.Fatalf("expected 3 host keys, got %d", len(hosts))
+	}
+	if h := hosts[0]; len(h.Hosts) != 2 || h.Hosts[1] != "[example.com]:2222" || h.Comment != "host key" || h.Line != 2 || h.CertAuthority || h.Revoked {
+		t.Errorf("unexpected first host key %#v", h)
+	}
+	if h := hosts[1]; !h.CertAuthority || h.Hosts[0] != "*.example.com" || h.Line != 4 {
+		t.Errorf("expected a cert authority, got %#v", h)
+	}
+	if h := hosts[2]; !h.Revoked || h.Hosts[0] != "|1|c2FsdA==|aGFzaA==" {
+		t.Errorf("expected a revoked key, got %#v", h)
+	}
+	if string(hosts[0].Key.Marshal()) != string(hosts[1].Key.Marshal()) {
+		t.Errorf("expected the same key on every line")
+	}
+
+	var kerr *KnownHostsError
+	if _, err := ParseKnownHosts("example.com\nexample.com ssh-ed25519 !!!!\n"); !errors.As(err, &kerr) || kerr.Line != 1 {
+		t.Errorf("expected an error for line 1, got %#v", err)
+	}
+}
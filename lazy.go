@@ -0,0 +1,98 @@
+package betterpem
+
+import (
+	"encoding/pem"
+	"sync"
+)
+
+// An object WithLazy hasn't parsed yet
+type lazyObject struct {
+	once  sync.Once
+	parse func() (interface{}, error)
+	obj   interface{}
+	err   error
+}
+
+func newLazyObject(parse BlockParser, der *pem.Block, info BlockInfo) *lazyObject {
+	return &lazyObject{parse: func() (interface{}, error) {
+		r, err := parse(der)
+		if err != nil {
+			return nil, &BlockError{Index: info.Index, Type: info.Type, Offset: info.Offset, Line: info.Line, Err: err}
+		}
+		return r, nil
+	}}
+}
+
+func (l *lazyObject) resolve() (interface{}, error) {
+	l.once.Do(func() {
+		l.obj, l.err = l.parse()
+		l.parse = nil
+	})
+	return l.obj, l.err
+}
+
+// Only scan the PEM data for blocks and their types, leaving each block
+// to be parsed when its object is first used, so pulling one key out of
+// a large bundle doesn't parse everything else.
+//
+// Parse errors come from the accessors instead of ParsePEMs: Next and
+// the typed accessors return the *BlockError, while Interface and the
+// Must functions panic with it.  Functions which use every object, such
+// as TLSCertificate or Audit, parse them all and leave out any which
+// fail, as WithSkipInvalid would.  Warnings aren't collected.
+func WithLazy() Option {
+	return func(o *parseOptions) {
+		o.lazy = true
+	}
+}
+
+// Parse the object at i if it hasn't been yet.
+//
+// The lazy object is left in place rather than replaced by its result,
+// since copies of the parsed PEMs share p.objs and may be read
+// concurrently; its sync.Once keeps the result.
+func (p *ParsedPEMs) resolve(i int) (interface{}, error) {
+	l, ok := p.objs[i].(*lazyObject)
+	if !ok {
+		return p.objs[i], nil
+	}
+	return l.resolve()
+}
+
+// Return the parsed PEMs remaining to be consumed with every object
+// parsed, leaving out any which fail to parse
+func (p *ParsedPEMs) parsed() ParsedPEMs {
+	lazy := false
+	for _, obj := range p.objs {
+		if _, ok := obj.(*lazyObject); ok {
+			lazy = true
+			break
+		}
+	}
+	if !lazy {
+		return *p
+	}
+	ret := *p
+	ret.objs, ret.blocks = nil, nil
+	for i := range p.objs {
+		if obj, err := p.resolve(i); err == nil {
+			ret.add(obj, p.blocks[i])
+		}
+	}
+	return ret
+}
+
+// Return every object remaining to be consumed, parsing any which haven't
+// been and leaving out any which fail to parse
+func (p *ParsedPEMs) all() []interface{} {
+	return p.parsed().objs
+}
+
+// Parse the object at the front, panicking if it fails to parse
+func (p *ParsedPEMs) mustResolveFront() interface{} {
+	obj, err := p.resolve(0)
+	if err != nil {
+		panic(err)
+	}
+	return obj
+}
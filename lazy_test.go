@@ -0,0 +1,90 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/pem"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestWithLazy(t *testing.T) {
+	parsed := 0
+	counting := func(block *pem.Block) (interface{}, error) {
+		parsed++
+		return builtinParsers["CERTIFICATE"](block)
+	}
+	bad := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")})
+	bundle := bytes.Join([][]byte{test_rsakey, test_ca, bad, test_eccert}, nil)
+
+	objs, err := ParsePEMs(bundle, WithLazy(), WithBlockType("CERTIFICATE", counting))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if objs.Length() != 4 || parsed != 0 {
+		t.Fatalf("expected 4 unparsed objects, got %d with %d parsed", objs.Length(), parsed)
+	}
+	objs.MustRSAPrivateKey()
+	if parsed != 0 {
+		t.Errorf("taking the key parsed %d certificates", parsed)
+	}
+	if _, err := objs.Certificate(); err != nil || parsed != 1 {
+		t.Errorf("unexpected error %#v after parsing %d certificates", err, parsed)
+	}
+	if _, err := objs.Certificate(); !errors.Is(err, ErrBadBlock) || objs.Length() != 1 {
+		t.Errorf("expected the bad block's error and for it to be consumed, got %#v", err)
+	}
+	objs.MustCertificate()
+
+	again, err := ParsePEMs(bundle, WithLazy())
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	again.MustRSAPrivateKey()
+	again.MustCertificate()
+	if _, err := again.Next(); !errors.Is(err, ErrBadBlock) || again.Length() != 1 {
+		t.Errorf("expected Next to consume the bad block, got %#v", err)
+	}
+
+	all, err := ParsePEMs(bundle, WithLazy())
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	// the bad block is left out
+	if fps, err := all.Fingerprints(crypto.SHA256); err != nil || len(fps) != 3 {
+		t.Errorf("expected 3 fingerprints, got %d: %v", len(fps), err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected Interface to panic on the bad block")
+			}
+		}()
+		all.Interface()
+		all.Interface()
+		all.Interface()
+	}()
+}
+
+// Run with -race
+func TestWithLazyConcurrent(t *testing.T) {
+	c := newTestChain(t)
+	bundle, err := ParsePEMs(c.pem(t, c.leaf, c.intermediate, c.root), WithLazy())
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	collector := NewExpiryCollector()
+	collector.AddBundle("chain", bundle)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if certs, _ := collector.Collect(); len(certs) != 3 {
+				t.Errorf("expected 3 certificates, got %d", len(certs))
+			}
+		}()
+	}
+	wg.Wait()
+}
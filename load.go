@@ -0,0 +1,70 @@
+package betterpem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// Read and parse a file with the Parser's options.  Errors say which file
+// they're for.
+func (p *Parser) loadFile(path string) (ParsedPEMs, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ParsedPEMs{}, err
+	}
+	defer f.Close()
+	pems, err := p.Parse(f)
+	if err != nil {
+		return pems, &os.PathError{Op: "parse", Path: path, Err: err}
+	}
+	return pems, nil
+}
+
+// Read and parse many files at once, such as a directory of certificates
+// loaded at startup, with at most concurrency files being loaded at a
+// time.  concurrency of zero or less loads one file per CPU.
+//
+// The results are in the same order as the paths.  Every file is loaded
+// even if some fail; the errors for those which failed are joined
+// together, each an *os.PathError saying which file it was for, and
+// their results are empty.  Once ctx is done no more files are started,
+// and its error is included.
+func LoadAll(ctx context.Context, paths []string, concurrency int, opts ...Option) ([]ParsedPEMs, error) {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	parser := NewParser(opts...)
+	results := make([]ParsedPEMs, len(paths))
+	errs := make([]error, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(paths); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i], errs[i] = parser.loadFile(paths[i])
+			}
+		}()
+	}
+feed:
+	for i := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return results, errors.Join(errs...)
+}
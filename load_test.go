@@ -0,0 +1,47 @@
+package betterpem
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAll(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{}
+	for i, contents := range [][]byte{test_ca, test_rsakey, test_eccert, []byte("nothing here")} {
+		path := filepath.Join(dir, string(rune('a'+i))+".pem")
+		if err := os.WriteFile(path, contents, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	paths = append(paths, filepath.Join(dir, "missing.pem"))
+
+	results, err := LoadAll(context.Background(), paths, 2)
+	if len(results) != len(paths) {
+		t.Fatalf("expected a result per path, got %d", len(results))
+	}
+	for i := 0; i < 3; i++ {
+		if results[i].Length() != 1 {
+			t.Errorf("expected an object from %s, got %d", paths[i], results[i].Length())
+		}
+	}
+	if _, ok := results[1].Interface().(interface{ Public() crypto.PublicKey }); !ok {
+		t.Errorf("results are out of order")
+	}
+	var perr *os.PathError
+	if !errors.As(err, &perr) || !errors.Is(err, ErrNoPEMFound) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected errors for the last two files, got %#v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err = LoadAll(ctx, paths[:3], 2)
+	if !errors.Is(err, context.Canceled) || results[0].Length() != 0 {
+		t.Errorf("expected nothing to be loaded once canceled, got %#v", err)
+	}
+}
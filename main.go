@@ -15,17 +15,76 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"time"
+	"unsafe"
 )
 
 var ErrPemUnderlyingFormatError = errors.New("pem passed was not a string, []byte, or io.Reader")
 var ErrPemIsUnsupportedType = errors.New("pem is an unsupported type")
 
+// Matched by every *BlockError with errors.Is
+var ErrBadBlock = errors.New("pem block could not be parsed")
+
+// Returned when the input holds no PEM blocks at all.  For compatibility
+// it matches ErrPemIsUnsupportedType with errors.Is.
+var ErrNoPEMFound error = noPEMData{}
+
+// The same as ErrNoPEMFound
+var ErrNoPEMData = ErrNoPEMFound
+
+type noPEMData struct{}
+
+func (noPEMData) Error() string {
+	return "no pem data was found"
+}
+
+func (noPEMData) Is(target error) bool {
+	return target == ErrPemIsUnsupportedType
+}
+
+// A type of block ParsePEMs doesn't support.  For compatibility it
+// matches ErrPemIsUnsupportedType with errors.Is.
+type ErrUnsupportedBlockType struct {
+	// The block's type label
+	Type string
+}
+
+func (e *ErrUnsupportedBlockType) Error() string {
+	return fmt.Sprintf("pem block type %q is unsupported", e.Type)
+}
+
+func (e *ErrUnsupportedBlockType) Is(target error) bool {
+	return target == ErrPemIsUnsupportedType
+}
+
+// Returned when the input holds PEM blocks, but none of a supported type.
+// It wraps an *ErrUnsupportedBlockType for each type, so it also matches
+// ErrPemIsUnsupportedType with errors.Is.
+type ErrOnlyUnsupportedBlocks struct {
+	// The type labels found, in order, without duplicates
+	Types []string
+}
+
+func (e *ErrOnlyUnsupportedBlocks) Error() string {
+	return "only unsupported pem blocks were found: " + strings.Join(e.Types, ", ")
+}
+
+func (e *ErrOnlyUnsupportedBlocks) Unwrap() []error {
+	ret := []error{}
+	for _, t := range e.Types {
+		ret = append(ret, &ErrUnsupportedBlockType{Type: t})
+	}
+	return ret
+}
+
 func intoBytes(pemInt interface{}) ([]byte, error) {
 	switch v := pemInt.(type) {
 	case []byte:
 		return v, nil
 	case string:
-		return []byte(v), nil
+		// only ever read, so the string's memory can be used as it is
+		return unsafe.Slice(unsafe.StringData(v), len(v)), nil
 	case io.Reader:
 		pemBytes, err := io.ReadAll(v)
 		if err != nil {
@@ -35,12 +94,67 @@ func intoBytes(pemInt interface{}) ([]byte, error) {
 	default:
 		return nil, ErrPemUnderlyingFormatError
 	}
-	panic("unreachable")
 }
 
 // Parsing a PEM results in a ParsedPEM object being returned
 type ParsedPEMs struct {
 	objs []interface{}
+	// the block each object was parsed from, or nil if it wasn't
+	blocks []*pem.Block
+	// blocks skipped by WithSkipInvalid
+	invalid []*BlockError
+	// blocks of unsupported types
+	skipped []SkippedBlock
+	// see Warnings
+	warnings []Finding
+	// from WithClock, or nil for time.Now
+	clock func() time.Time
+}
+
+func (p *ParsedPEMs) add(obj interface{}, block *pem.Block) {
+	p.objs = append(p.objs, obj)
+	p.blocks = append(p.blocks, block)
+}
+
+// Return the errors for blocks which failed to parse and were skipped
+// because of WithSkipInvalid, in order.
+//
+// They are unaffected by consuming objects.
+func (p *ParsedPEMs) Invalid() []*BlockError {
+	return p.invalid
+}
+
+// Return the errors for every block skipped because of WithSkipInvalid
+// joined together with errors.Join, so they can all be logged at once, or
+// nil if none were.
+func (p *ParsedPEMs) InvalidErr() error {
+	errs := []error{}
+	for _, e := range p.invalid {
+		errs = append(errs, e)
+	}
+	return errors.Join(errs...)
+}
+
+// Return the blocks which were ignored because ParsePEMs doesn't support
+// their type or they were filtered out, in order.
+//
+// They are unaffected by consuming objects.
+func (p *ParsedPEMs) Skipped() []SkippedBlock {
+	return p.skipped
+}
+
+// Drop the object at the front once it has been consumed
+func (p *ParsedPEMs) advance() {
+	p.objs = p.objs[1:]
+	p.blocks = p.blocks[1:]
+}
+
+// The current time according to the clock from WithClock
+func (p *ParsedPEMs) now() time.Time {
+	if p.clock != nil {
+		return p.clock()
+	}
+	return time.Now()
 }
 
 // Return the number of parsed PEMs remaining to be consumed
@@ -50,20 +164,31 @@ func (p *ParsedPEMs) Length() int {
 
 // Give the object back in its typeless form
 func (p *ParsedPEMs) Interface() interface{} {
-	ret := p.objs[0]
-	p.objs = p.objs[1:]
+	ret := p.mustResolveFront()
+	p.advance()
 	return ret
 }
 
+// Return the PEM headers of the next object, or nil if it had none.
+//
+// The object is not consumed.
+func (p *ParsedPEMs) Headers() map[string]string {
+	if p.blocks[0] == nil || len(p.blocks[0].Headers) == 0 {
+		return nil
+	}
+	return p.blocks[0].Headers
+}
+
 // Return the ParsedPEM's object as a *x509.Certificate.
 //
 // Panics if the object wasn't an x.509 certificate
 func (p *ParsedPEMs) MustCertificate() *x509.Certificate {
-	r, ok := p.objs[0].(*x509.Certificate)
+	obj := p.mustResolveFront()
+	r, ok := obj.(*x509.Certificate)
 	if !ok {
-		panic(fmt.Sprintf("%#v is not an *x509.Certificate", p.objs[0]))
+		panic(fmt.Sprintf("%#v is not an *x509.Certificate", obj))
 	}
-	p.objs = p.objs[1:]
+	p.advance()
 	return r
 }
 
@@ -71,11 +196,12 @@ func (p *ParsedPEMs) MustCertificate() *x509.Certificate {
 //
 // Panics if the object wasn't an RSA private key
 func (p *ParsedPEMs) MustRSAPrivateKey() *rsa.PrivateKey {
-	r, ok := p.objs[0].(*rsa.PrivateKey)
+	obj := p.mustResolveFront()
+	r, ok := obj.(*rsa.PrivateKey)
 	if !ok {
-		panic(fmt.Sprintf("%#v is not an rsa.PrivateKey", p.objs[0]))
+		panic(fmt.Sprintf("%#v is not an rsa.PrivateKey", obj))
 	}
-	p.objs = p.objs[1:]
+	p.advance()
 	return r
 }
 
@@ -83,14 +209,111 @@ func (p *ParsedPEMs) MustRSAPrivateKey() *rsa.PrivateKey {
 //
 // Panics if the object wasn't an ECDSA private key
 func (p *ParsedPEMs) MustECPrivateKey() *ecdsa.PrivateKey {
-	r, ok := p.objs[0].(*ecdsa.PrivateKey)
+	obj := p.mustResolveFront()
+	r, ok := obj.(*ecdsa.PrivateKey)
 	if !ok {
-		panic(fmt.Sprintf("%#v is not an ecdsa.PrivateKey", p.objs[0]))
+		panic(fmt.Sprintf("%#v is not an ecdsa.PrivateKey", obj))
 	}
-	p.objs = p.objs[1:]
+	p.advance()
+	return r
+}
+
+// Returns the ParsedPEM's object as a *x509.CertificateRequest
+//
+// Panics if the object wasn't a certificate signing request
+func (p *ParsedPEMs) MustCertificateRequest() *x509.CertificateRequest {
+	obj := p.mustResolveFront()
+	r, ok := obj.(*x509.CertificateRequest)
+	if !ok {
+		panic(fmt.Sprintf("%#v is not an *x509.CertificateRequest", obj))
+	}
+	p.advance()
 	return r
 }
 
+// Returns the ParsedPEM's object as a *x509.RevocationList
+//
+// Panics if the object wasn't a certificate revocation list
+func (p *ParsedPEMs) MustRevocationList() *x509.RevocationList {
+	obj := p.mustResolveFront()
+	r, ok := obj.(*x509.RevocationList)
+	if !ok {
+		panic(fmt.Sprintf("%#v is not an *x509.RevocationList", obj))
+	}
+	p.advance()
+	return r
+}
+
+// Where in the input a block that failed to parse was, and why it failed
+type BlockError struct {
+	// The block's position among all the blocks found, counting from 0
+	Index int
+	// The type label of the block, such as "CERTIFICATE"
+	Type string
+	// The byte offset of the block's BEGIN line, counting from 0
+	Offset int
+	// The line number of the block's BEGIN line, counting from 1
+	Line int
+	Err  error
+}
+
+// A block ParsePEMs ignored because it doesn't support its type, or
+// because it was filtered out
+type SkippedBlock struct {
+	// The block's position among all the blocks found, counting from 0
+	Index int
+	// The type label of the block, such as "PGP PUBLIC KEY BLOCK"
+	Type string
+	// The byte offset of the block's BEGIN line, counting from 0
+	Offset int
+	// The line number of the block's BEGIN line, counting from 1
+	Line int
+	// Whether the block was ignored because of WithTypes or WithoutTypes
+	// rather than because its type is unsupported
+	Filtered bool
+}
+
+func (e *BlockError) Error() string {
+	return fmt.Sprintf("block %d (%s) at line %d, offset %d: %v", e.Index, e.Type, e.Line, e.Offset, e.Err)
+}
+
+func (e *BlockError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BlockError) Is(target error) bool {
+	return target == ErrBadBlock
+}
+
+// The parsers for the types ParsePEMs supports without any help
+var builtinParsers = map[string]BlockParser{
+	"CERTIFICATE": func(der *pem.Block) (interface{}, error) {
+		return x509.ParseCertificate(der.Bytes)
+	},
+	"RSA PRIVATE KEY": func(der *pem.Block) (interface{}, error) {
+		return x509.ParsePKCS1PrivateKey(der.Bytes)
+	},
+	"EC PRIVATE KEY": func(der *pem.Block) (interface{}, error) {
+		return x509.ParseECPrivateKey(der.Bytes)
+	},
+	"PRIVATE KEY": func(der *pem.Block) (interface{}, error) {
+		return x509.ParsePKCS8PrivateKey(der.Bytes)
+	},
+	"CERTIFICATE REQUEST":     parseCertificateRequest,
+	"NEW CERTIFICATE REQUEST": parseCertificateRequest,
+	"X509 CRL": func(der *pem.Block) (interface{}, error) {
+		return x509.ParseRevocationList(der.Bytes)
+	},
+	"PUBLIC KEY": func(der *pem.Block) (interface{}, error) {
+		return x509.ParsePKIXPublicKey(der.Bytes)
+	},
+	"DH PARAMETERS": ParseDHParametersBlock,
+}
+
+func parseCertificateRequest(der *pem.Block) (interface{}, error) {
+	return x509.ParseCertificateRequest(der.Bytes)
+}
+
 // Parse PEM data into a slice of ParsedPEM objects
 //
 // This function will parse all discovered PEM blocks
@@ -99,51 +322,139 @@ func (p *ParsedPEMs) MustECPrivateKey() *ecdsa.PrivateKey {
 //
 // See ParsedPEM for details on extracting the object.
 //
-// Produces an error if there is no PEM data found.
+// Produces ErrNoPEMFound if there is no PEM data found, and an
+// *ErrOnlyUnsupportedBlocks if none of it was of a supported type.  A
+// block which fails to parse produces a *BlockError saying where it was,
+// which wraps the error from parsing it and matches ErrBadBlock.  A
+// BEGIN line without an END line produces a *TruncatedBlockError.
+//
+// See Option for ways to change how the PEM data is parsed.
 //
-func ParsePEMs(pemInt interface{}) (ParsedPEMs, error) {
-	objs := []interface{}{}
-	pemBytes, err := intoBytes(pemInt)
-	if err != nil {
+func ParsePEMs(pemInt interface{}, opts ...Option) (ParsedPEMs, error) {
+	return NewParser(opts...).Parse(pemInt)
+}
+
+// Parse PEM data which has been read into pemBytes
+func (o *parseOptions) parse(pemBytes []byte, inPlace bool) (ParsedPEMs, error) {
+	objs := ParsedPEMs{clock: o.clock}
+	scan := newScanner(pemBytes, inPlace)
+	index := 0
+	// the block each object came from, for warnings
+	indices := []int{}
+	fail := func(err error) (ParsedPEMs, error) {
+		if o.partialResults {
+			objs.warnings = warningsFor(objs.objs, objs.blocks, indices, objs.now())
+			return objs, err
+		}
 		return ParsedPEMs{}, err
 	}
-	var der *pem.Block
-	var rest []byte = pemBytes
-	for {
-		der, rest = pem.Decode(rest)
-		if der == nil {
-			break
+	// check what was skipped over between blocks
+	checkGap := func(from, to int) error {
+		if err := scan.truncated(from, to); err != nil {
+			return err
 		}
-		switch der.Type {
-		case "CERTIFICATE":
-			r, err := x509.ParseCertificate(der.Bytes)
-			if err != nil {
-				return ParsedPEMs{}, err
-			}
-			objs = append(objs, r)
-		case "RSA PRIVATE KEY":
-			r, err := x509.ParsePKCS1PrivateKey(der.Bytes)
-			if err != nil {
-				return ParsedPEMs{}, err
-			}
-			objs = append(objs, r)
-		case "EC PRIVATE KEY":
-			r, err := x509.ParseECPrivateKey(der.Bytes)
-			if err != nil {
-				return ParsedPEMs{}, err
+		if o.errorOnGarbage {
+			return scan.garbage(from, to)
+		}
+		return nil
+	}
+	record := func(b *pendingBlock) error {
+		if b.err != nil {
+			berr := &BlockError{Index: b.info.Index, Type: b.info.Type, Offset: b.info.Offset, Line: b.info.Line, Err: b.err}
+			if o.skipInvalid {
+				o.logBlock(b.info.Index, b.der, "invalid", b.err)
+				objs.invalid = append(objs.invalid, berr)
+				return nil
 			}
-			objs = append(objs, r)
-		case "PRIVATE KEY":
-			r, err := x509.ParsePKCS8PrivateKey(der.Bytes)
-			if err != nil {
-				return ParsedPEMs{}, err
+			o.logBlock(b.info.Index, b.der, "failed", b.err)
+			return berr
+		}
+		o.logBlock(b.info.Index, b.der, "parsed", nil)
+		objs.add(b.obj, b.der)
+		indices = append(indices, b.info.Index)
+		return nil
+	}
+	// blocks left for WithWorkers to parse, and what stopped the scan
+	pending := []*pendingBlock{}
+	var scanErr error
+	for ; ; index++ {
+		from := scan.pos
+		found, ok := scan.next()
+		if !ok {
+			scanErr = checkGap(from, len(pemBytes))
+			break
+		}
+		der, offset, line := found.Block, found.Offset, found.Line
+		if o.maxBlocks > 0 && index >= o.maxBlocks {
+			scanErr = &TooManyBlocksError{Max: o.maxBlocks}
+			break
+		}
+		if err := checkGap(from, offset); err != nil {
+			scanErr = err
+			break
+		}
+		if !o.wanted(der.Type) {
+			o.logBlock(index, der, "filtered", nil)
+			objs.skipped = append(objs.skipped, SkippedBlock{Index: index, Type: der.Type, Offset: offset, Line: line, Filtered: true})
+			continue
+		}
+		info := BlockInfo{Index: index, Type: der.Type, Offset: offset, Line: line, Size: len(der.Bytes), Headers: der.Headers}
+		parse := o.parserFor(der, info)
+		if parse == nil {
+			if o.errorOnUnknown {
+				scanErr = &BlockError{Index: index, Type: der.Type, Offset: offset, Line: line, Err: &ErrUnsupportedBlockType{Type: der.Type}}
+				break
 			}
-			objs = append(objs, r)
-		default:
+			o.logBlock(index, der, "skipped", nil)
+			objs.skipped = append(objs.skipped, SkippedBlock{Index: index, Type: der.Type, Offset: offset, Line: line})
+			continue
+		}
+		if o.lazy {
+			o.logBlock(index, der, "deferred", nil)
+			objs.add(newLazyObject(parse, der, info), der)
+			continue
+		}
+		b := &pendingBlock{der: der, info: info, parse: parse}
+		if o.workers > 1 {
+			pending = append(pending, b)
+			continue
+		}
+		b.obj, b.err = parse(der)
+		if err := record(b); err != nil {
+			return fail(err)
+		}
+	}
+	parseAll(pending, o.workers)
+	for _, b := range pending {
+		if err := record(b); err != nil {
+			// as if the blocks after this one had never been scanned
+			objs.skipped = skippedBefore(objs.skipped, b.info.Index)
+			return fail(err)
 		}
 	}
-	if len(objs) > 0 {
-		return ParsedPEMs{objs}, nil
+	if scanErr != nil {
+		return fail(scanErr)
+	}
+	if objs.Length() > 0 {
+		if !o.lazy {
+			objs.warnings = warningsFor(objs.objs, objs.blocks, indices, objs.now())
+		}
+		return objs, nil
+	}
+	if len(objs.invalid) > 0 {
+		// nothing parsed, so there's nothing to be lenient for
+		return ParsedPEMs{}, objs.InvalidErr()
+	}
+	if index == 0 {
+		return ParsedPEMs{}, ErrNoPEMFound
+	}
+	uerr := &ErrOnlyUnsupportedBlocks{}
+	seen := map[string]bool{}
+	for _, b := range objs.skipped {
+		if !seen[b.Type] {
+			seen[b.Type] = true
+			uerr.Types = append(uerr.Types, b.Type)
+		}
 	}
-	return ParsedPEMs{}, ErrPemIsUnsupportedType
+	return ParsedPEMs{}, uerr
 }
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"embed"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"testing"
@@ -112,14 +115,14 @@ func TestLoadManyPem(t *testing.T) {
 	if err != nil {
 		t.Errorf("error while reading PEMs %#v", err)
 	}
-	if objs.Length() != len(pembyteblocks)-1 {
-		// except for the csr which we don't parse and _should_ be missing
+	if objs.Length() != len(pembyteblocks) {
 		t.Error("ParsePEM did not parse all the expected blocks properly")
 	}
 	rsacert := objs.MustCertificate()
 	rsakey := objs.MustRSAPrivateKey()
 	cacert := objs.MustCertificate()
 	cakey := objs.MustRSAPrivateKey()
+	rsareq := objs.MustCertificateRequest()
 	eccert := objs.MustCertificate()
 	eckey := objs.MustECPrivateKey()
 	if !rsakey.PublicKey.Equal(rsacert.PublicKey) {
@@ -128,6 +131,9 @@ func TestLoadManyPem(t *testing.T) {
 	if !cakey.PublicKey.Equal(cacert.PublicKey) {
 		t.Error("wait what?")
 	}
+	if !rsakey.PublicKey.Equal(rsareq.PublicKey) {
+		t.Error("the csr is for some other key")
+	}
 	if !eckey.PublicKey.Equal(eccert.PublicKey) {
 		t.Errorf("%#v != %#v", eckey.PublicKey, eccert.PublicKey)
 	}
@@ -170,3 +176,76 @@ func TestLoadPemWrongType(t *testing.T) {
 		t.Error("Expected an error from trying to coerce an EC to RSA but there was no panic")
 	}
 }
+
+func TestLoadPemBadBlock(t *testing.T) {
+	bad := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")})
+	bundle := append(append([]byte("# leading comment\n"), test_ca...), bad...)
+	_, err := ParsePEMs(bundle)
+	var berr *BlockError
+	if !errors.As(err, &berr) {
+		t.Fatalf("expected a *BlockError, got %#v", err)
+	}
+	offset := len("# leading comment\n") + len(test_ca)
+	if berr.Index != 1 || berr.Type != "CERTIFICATE" || berr.Offset != offset || berr.Line != bytes.Count(bundle[:offset], []byte{'\n'})+1 {
+		t.Errorf("unexpected block error %#v", berr)
+	}
+	if berr.Err == nil || errors.Unwrap(err) != berr.Err {
+		t.Errorf("block error does not unwrap to the parse error")
+	}
+}
+
+func TestLoadPemErrors(t *testing.T) {
+	if _, err := ParsePEMs("no pem here"); err != ErrNoPEMFound || !errors.Is(err, ErrPemIsUnsupportedType) {
+		t.Errorf("expected ErrNoPEMFound, got %#v", err)
+	}
+	pgp := pem.EncodeToMemory(&pem.Block{Type: "PGP PUBLIC KEY BLOCK", Bytes: []byte{1}})
+	ssh := pem.EncodeToMemory(&pem.Block{Type: "SSH2 PUBLIC KEY", Bytes: []byte{1}})
+	_, err := ParsePEMs(bytes.Join([][]byte{pgp, ssh, pgp}, nil))
+	var oerr *ErrOnlyUnsupportedBlocks
+	if !errors.As(err, &oerr) || len(oerr.Types) != 2 || oerr.Types[0] != "PGP PUBLIC KEY BLOCK" || oerr.Types[1] != "SSH2 PUBLIC KEY" {
+		t.Errorf("expected an *ErrOnlyUnsupportedBlocks, got %#v", err)
+	}
+	var uerr *ErrUnsupportedBlockType
+	if !errors.As(err, &uerr) || uerr.Type != "PGP PUBLIC KEY BLOCK" || !errors.Is(err, ErrPemIsUnsupportedType) {
+		t.Errorf("expected an *ErrUnsupportedBlockType, got %#v", err)
+	}
+	_, err = ParsePEMs(pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: []byte{1}}))
+	var serr asn1.SyntaxError
+	if !errors.Is(err, ErrBadBlock) || !errors.As(err, &serr) {
+		t.Errorf("expected a bad block wrapping the asn1 error, got %#v", err)
+	}
+}
+
+func TestLoadPemSkipped(t *testing.T) {
+	pgp := pem.EncodeToMemory(&pem.Block{Type: "PGP PUBLIC KEY BLOCK", Bytes: []byte{1}})
+	bundle := bytes.Join([][]byte{test_ca, pgp}, nil)
+	objs, err := ParsePEMs(bundle)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	skipped := objs.Skipped()
+	if len(skipped) != 1 || skipped[0].Index != 1 || skipped[0].Type != "PGP PUBLIC KEY BLOCK" || skipped[0].Offset != len(test_ca) || skipped[0].Line != bytes.Count(test_ca, []byte{'\n'})+1 {
+		t.Errorf("unexpected skipped blocks %#v", skipped)
+	}
+	objs.Interface()
+	if len(objs.Skipped()) != 1 {
+		t.Error("consuming an object changed the skipped blocks")
+	}
+}
+
+func TestLoadPemTruncated(t *testing.T) {
+	lines := bytes.Split(test_rsakey, []byte{'\n'})
+	truncated := bytes.Join(lines[:len(lines)/2], []byte{'\n'})
+	for _, bundle := range [][]byte{
+		truncated,
+		bytes.Join([][]byte{test_ca, truncated}, nil),
+		bytes.Join([][]byte{truncated, []byte("\n"), test_ca}, nil),
+	} {
+		_, err := ParsePEMs(bundle)
+		var terr *TruncatedBlockError
+		offset := bytes.Index(bundle, truncated)
+		if !errors.As(err, &terr) || terr.Type != "RSA PRIVATE KEY" || terr.Offset != offset || terr.Line != newScanner(bundle, false).lineOf(offset) {
+			t.Errorf("expected a truncated block error, got %#v", err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package betterpem
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidACMEStore = errors.New("invalid acme.json")
+
+// A certificate managed by a proxy which obtains them itself, such as
+// Caddy or Traefik, with its key
+type ManagedCertificate struct {
+	// The names the proxy obtained the certificate for, as it recorded
+	// them
+	Domains []string
+	// Where the certificate came from: the issuer's storage key for
+	// Caddy, such as acme-v02.api.letsencrypt.org-directory, or the
+	// certificate resolver for Traefik
+	Issuer   string
+	Identity Identity
+}
+
+// Parse a certificate and key as a proxy stores them into an Identity
+func managedIdentity(cert, key []byte) (Identity, error) {
+	pems, err := ParsePEMs(append(append(append([]byte{}, cert...), '\n'), key...))
+	if err != nil {
+		return Identity{}, err
+	}
+	return pems.ExtractIdentity()
+}
+
+// Load the certificates and keys from Traefik's ACME storage, the
+// acme.json file named by a certificate resolver's storage option.
+//
+// Both the current layout, with a section per certificate resolver, and
+// Traefik 1's, with a single unnamed section, are read.  Certificates are
+// in the order Traefik stored them, with resolvers sorted by name.
+func LoadTraefikACME(data []byte) ([]ManagedCertificate, error) {
+	type section struct {
+		Certificates []struct {
+			Domain struct {
+				Main string
+				SANs []string
+			}
+			// base64 encoded PEM
+			Certificate, Key string
+		}
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidACMEStore, err)
+	}
+	sections := map[string]section{}
+	if _, ok := raw["Certificates"]; ok {
+		// Traefik 1
+		var s section
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidACMEStore, err)
+		}
+		sections[""] = s
+	} else {
+		for name, msg := range raw {
+			var s section
+			if err := json.Unmarshal(msg, &s); err != nil {
+				return nil, fmt.Errorf("%w: resolver %s: %v", ErrInvalidACMEStore, name, err)
+			}
+			sections[name] = s
+		}
+	}
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := []ManagedCertificate{}
+	for _, name := range names {
+		for _, c := range sections[name].Certificates {
+			domains := append([]string{c.Domain.Main}, c.Domain.SANs...)
+			cert, err := base64.StdEncoding.DecodeString(c.Certificate)
+			if err != nil {
+				return nil, fmt.Errorf("%w: certificate for %s: %v", ErrInvalidACMEStore, domains[0], err)
+			}
+			key, err := base64.StdEncoding.DecodeString(c.Key)
+			if err != nil {
+				return nil, fmt.Errorf("%w: key for %s: %v", ErrInvalidACMEStore, domains[0], err)
+			}
+			identity, err := managedIdentity(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("certificate for %s: %w", domains[0], err)
+			}
+			ret = append(ret, ManagedCertificate{Domains: domains, Issuer: name, Identity: identity})
+		}
+	}
+	return ret, nil
+}
+
+// Load the certificates and keys from Caddy's storage, with fsys at its
+// root, such as os.DirFS of Caddy's data directory.
+//
+// Caddy keeps each certificate in certificates/<issuer>/<name>/, as
+// <name>.crt and <name>.key with its names in <name>.json.  Those which
+// don't have all three files are skipped, as Caddy may be partway through
+// writing them.  Certificates are in the order of their paths.
+func LoadCaddyStorage(fsys fs.FS) ([]ManagedCertificate, error) {
+	metas, err := fs.Glob(fsys, "certificates/*/*/*.json")
+	if err != nil {
+		return nil, err
+	}
+	ret := []ManagedCertificate{}
+	for _, metaPath := range metas {
+		dir, file := path.Split(metaPath)
+		name := strings.TrimSuffix(file, ".json")
+		if path.Base(dir) != name {
+			continue
+		}
+		cert, certErr := fs.ReadFile(fsys, dir+name+".crt")
+		key, keyErr := fs.ReadFile(fsys, dir+name+".key")
+		if errors.Is(certErr, fs.ErrNotExist) || errors.Is(keyErr, fs.ErrNotExist) {
+			continue
+		}
+		if certErr != nil {
+			return nil, certErr
+		}
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		metaJSON, err := fs.ReadFile(fsys, metaPath)
+		if err != nil {
+			return nil, err
+		}
+		var meta struct {
+			SANs []string `json:"sans"`
+		}
+		if err := json.Unmarshal(metaJSON, &meta); err != nil {
+			return nil, &fs.PathError{Op: "parse", Path: metaPath, Err: err}
+		}
+		identity, err := managedIdentity(cert, key)
+		if err != nil {
+			return nil, &fs.PathError{Op: "parse", Path: dir, Err: err}
+		}
+		ret = append(ret, ManagedCertificate{
+			Domains:  meta.SANs,
+			Issuer:   path.Base(path.Dir(path.Clean(dir))),
+			Identity: identity,
+		})
+	}
+	return ret, nil
+}
@@ -0,0 +1,72 @@
+package betterpem
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadTraefikACME(t *testing.T) {
+	c := newTestChain(t)
+	certB64 := base64.StdEncoding.EncodeToString(c.pem(t, c.leaf, c.intermediate))
+	keyB64 := base64.StdEncoding.EncodeToString(c.pem(t, c.leafKey))
+	entry := fmt.Sprintf(`{"domain":{"main":"leaf.example.com","sans":["*.leaf.example.com"]},"certificate":%q,"key":%q,"Store":"default"}`, certB64, keyB64)
+
+	for _, tc := range []struct {
+		data, issuer string
+	}{
+		{`{"letsencrypt":{"Account":{"Email":"admin@example.com"},"Certificates":[` + entry + `]},"empty":{"Certificates":null}}`, "letsencrypt"},
+		{fmt.Sprintf(`{"Account":{},"Certificates":[{"Domain":{"Main":"leaf.example.com","SANs":["*.leaf.example.com"]},"Certificate":%q,"Key":%q}]}`, certB64, keyB64), ""},
+	} {
+		certs, err := LoadTraefikACME([]byte(tc.data))
+		if err != nil {
+			t.Fatalf("unexpected error loading acme.json %#v", err)
+		}
+		if len(certs) != 1 {
+			t.Fatalf("expected 1 certificate, got %d", len(certs))
+		}
+		got := certs[0]
+		if got.Issuer != tc.issuer || len(got.Domains) != 2 || got.Domains[1] != "*.leaf.example.com" {
+			t.Errorf("unexpected certificate %q from %q", got.Domains, got.Issuer)
+		}
+		if !got.Identity.Leaf.Equal(c.leaf) || len(got.Identity.Intermediates) != 1 {
+			t.Error("expected the leaf and its intermediate")
+		}
+	}
+
+	if _, err := LoadTraefikACME([]byte(`[]`)); err == nil {
+		t.Error("expected an error for a non-object acme.json")
+	}
+}
+
+func TestLoadCaddyStorage(t *testing.T) {
+	c := newTestChain(t)
+	dir := "certificates/acme-v02.api.letsencrypt.org-directory/leaf.example.com/"
+	fsys := fstest.MapFS{
+		dir + "leaf.example.com.crt":  {Data: c.pem(t, c.leaf, c.intermediate)},
+		dir + "leaf.example.com.key":  {Data: c.pem(t, c.leafKey)},
+		dir + "leaf.example.com.json": {Data: []byte(`{"sans":["leaf.example.com"],"issuer_data":{"url":"https://acme-v02.api.letsencrypt.org/acme/cert/1"}}`)},
+		// still being written
+		"certificates/local/partial.example.com/partial.example.com.json": {Data: []byte(`{"sans":["partial.example.com"]}`)},
+	}
+	certs, err := LoadCaddyStorage(fsys)
+	if err != nil {
+		t.Fatalf("unexpected error loading storage %#v", err)
+	}
+	if len(certs) != 1 {
+		t.Fatalf("expected 1 certificate, got %d", len(certs))
+	}
+	got := certs[0]
+	if got.Issuer != "acme-v02.api.letsencrypt.org-directory" || len(got.Domains) != 1 || got.Domains[0] != "leaf.example.com" {
+		t.Errorf("unexpected certificate %q from %q", got.Domains, got.Issuer)
+	}
+	if !got.Identity.Leaf.Equal(c.leaf) || len(got.Identity.Intermediates) != 1 {
+		t.Error("expected the leaf and its intermediate")
+	}
+
+	fsys[dir+"leaf.example.com.key"] = &fstest.MapFile{Data: c.pem(t, c.rootKey)}
+	if _, err := LoadCaddyStorage(fsys); err == nil {
+		t.Error("expected an error for a key which doesn't match")
+	}
+}
@@ -0,0 +1,43 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/x509"
+)
+
+// A private key and a certificate for it
+type KeyCertPair struct {
+	Key         crypto.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// Return every certificate for a private key, in the order given.
+//
+// RSA, ECDSA, and Ed25519 keys are supported.  There may be more than one
+// match, such as when a renewed certificate kept its key.
+func MatchKeyCert(key crypto.PrivateKey, certs []*x509.Certificate) []*x509.Certificate {
+	ret := []*x509.Certificate{}
+	for _, c := range certs {
+		if certMatchesKey(c, key) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Pair each private key remaining to be consumed with each certificate
+// for it.
+//
+// Pairs are in the order of the keys, then of the certificates.  Keys
+// without a certificate, and certificates without a key, are left out.
+// The parsed PEMs are not consumed.
+func (p *ParsedPEMs) MatchedPairs() []KeyCertPair {
+	certs := p.certificates()
+	ret := []KeyCertPair{}
+	for _, key := range p.privateKeys() {
+		for _, c := range MatchKeyCert(key, certs) {
+			ret = append(ret, KeyCertPair{key, c})
+		}
+	}
+	return ret
+}
@@ -0,0 +1,55 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"testing"
+)
+
+func TestMatchedPairs(t *testing.T) {
+	pems := bytes.Join([][]byte{
+		test_rsacert,
+		test_eckey,
+		test_ca,
+		test_rsakey,
+		test_cakey,
+		test_eccert,
+	}, []byte{'\n'})
+	objs, err := ParsePEMs(pems)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	pairs := objs.MatchedPairs()
+	if len(pairs) != 3 {
+		t.Fatalf("expected 3 pairs, got %d", len(pairs))
+	}
+	rsacert := objs.MustCertificate()
+	eckey := objs.MustECPrivateKey()
+	cacert := objs.MustCertificate()
+	rsakey := objs.MustRSAPrivateKey()
+	cakey := objs.MustRSAPrivateKey()
+	eccert := objs.MustCertificate()
+	for i, want := range []KeyCertPair{{eckey, eccert}, {rsakey, rsacert}, {cakey, cacert}} {
+		if pairs[i].Key != want.Key || pairs[i].Certificate != want.Certificate {
+			t.Errorf("pair %d is wrong", i)
+		}
+	}
+
+	_, edpem, err := GenerateEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edobjs, err := ParsePEMs(edpem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed := edobjs.Interface()
+	ss, err := SelfSigned(SelfSignedOptions{CommonName: "ed25519", Key: ed.(crypto.Signer)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := MatchKeyCert(ed, []*x509.Certificate{rsacert, ss.Certificate}); len(m) != 1 || m[0] != ss.Certificate {
+		t.Errorf("ed25519 key did not match its certificate")
+	}
+}
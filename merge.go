@@ -0,0 +1,77 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"time"
+)
+
+// How MergeBundles cleans up the bundle it produces
+type MergePolicy struct {
+	// Drop objects which encode to the same bytes as an earlier one
+	Dedupe bool
+	// Keep only the certificate which expires last of those with the same
+	// subject and public key, such as a CA and its renewals
+	PreferNewest bool
+	// Drop certificates which have expired
+	ExcludeExpired bool
+	// The time expiry is checked at.  When zero, it is the time from the
+	// first bundle's clock, which is time.Now unless WithClock set one.
+	Now time.Time
+}
+
+// A string which is the same for certificates PreferNewest treats as
+// versions of each other
+func renewalIdentityOf(cert *x509.Certificate) string {
+	return string(cert.RawSubject) + "\x00" + string(cert.RawSubjectPublicKeyInfo)
+}
+
+// Combine the parsed PEMs remaining to be consumed in several bundles,
+// such as a vendor's CA bundle and an internal CA, into one bundle
+// cleaned up according to the policy.
+//
+// Objects keep the order they had, bundle by bundle.  When PreferNewest
+// replaces a certificate with a newer version, the newer one takes the
+// older one's place.  The result has the first bundle's clock.  None of
+// the bundles' parsed PEMs are consumed.
+func MergeBundles(policy MergePolicy, bundles ...ParsedPEMs) ParsedPEMs {
+	merged := ParsedPEMs{}
+	if len(bundles) > 0 {
+		merged.clock = bundles[0].clock
+	}
+	now := policy.Now
+	if now.IsZero() {
+		now = merged.now()
+	}
+	seen := map[string]bool{}
+	// where each certificate PreferNewest compares is in merged
+	newest := map[string]int{}
+	for _, bundle := range bundles {
+		bundle := bundle.parsed()
+		for i, obj := range bundle.objs {
+			cert, isCert := obj.(*x509.Certificate)
+			if isCert && policy.ExcludeExpired && now.After(cert.NotAfter) {
+				continue
+			}
+			if policy.Dedupe {
+				id := identityOf(obj, bundle.blocks[i])
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+			}
+			if isCert && policy.PreferNewest {
+				id := renewalIdentityOf(cert)
+				if at, ok := newest[id]; ok {
+					if cert.NotAfter.After(merged.objs[at].(*x509.Certificate).NotAfter) {
+						merged.objs[at] = cert
+						merged.blocks[at] = bundle.blocks[i]
+					}
+					continue
+				}
+				newest[id] = merged.Length()
+			}
+			merged.add(obj, bundle.blocks[i])
+		}
+	}
+	return merged
+}
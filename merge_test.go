@@ -0,0 +1,70 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+)
+
+func TestMergeBundles(t *testing.T) {
+	vendor := newTestChain(t)
+	internal := newTestChain(t)
+	expired, _ := issueTestCert(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "expired"},
+		NotBefore: time.Now().Add(-48 * time.Hour),
+		NotAfter:  time.Now().Add(-24 * time.Hour),
+	}, nil, nil)
+	renewedRoot := issueTestCertForKey(t, &x509.Certificate{
+		Subject:               internal.root.Subject,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+	}, nil, nil, internal.rootKey)
+
+	parse := func(objs ...interface{}) ParsedPEMs {
+		pems, err := ParsePEMs(vendor.pem(t, objs...))
+		if err != nil {
+			t.Fatalf("unexpected error parsing pem %#v", err)
+		}
+		return pems
+	}
+	a := parse(vendor.root, internal.root, expired)
+	b := parse(vendor.root, renewedRoot)
+
+	if all := MergeBundles(MergePolicy{}, a, b); all.Length() != 5 {
+		t.Errorf("expected every object without a policy, got %d", all.Length())
+	}
+	if a.Length() != 3 || b.Length() != 2 {
+		t.Error("merging consumed the parsed PEMs")
+	}
+
+	merged := MergeBundles(MergePolicy{Dedupe: true, PreferNewest: true, ExcludeExpired: true}, a, b)
+	if merged.Length() != 2 {
+		t.Fatalf("expected 2 certificates, got %d", merged.Length())
+	}
+	if !merged.MustCertificate().Equal(vendor.root) {
+		t.Error("the vendor root should come first")
+	}
+	if !merged.MustCertificate().Equal(renewedRoot) {
+		t.Error("the internal root should have been replaced by its renewal")
+	}
+
+	later := MergeBundles(MergePolicy{ExcludeExpired: true, Now: time.Now().Add(-36 * time.Hour)}, a)
+	if later.Length() != 3 {
+		t.Errorf("expiry should be checked at the policy's time, got %d", later.Length())
+	}
+
+	past := time.Now().Add(-36 * time.Hour)
+	clocked, err := ParsePEMs(vendor.pem(t, vendor.root, expired), WithClock(func() time.Time { return past }))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	asOf := MergeBundles(MergePolicy{ExcludeExpired: true}, clocked, b)
+	if asOf.Length() != 4 {
+		t.Errorf("expiry should be checked at the first bundle's clock, got %d", asOf.Length())
+	}
+	if !asOf.now().Equal(past) {
+		t.Error("the merged bundle should keep the first bundle's clock")
+	}
+}
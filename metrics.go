@@ -0,0 +1,200 @@
+package betterpem
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A bundle watched by an ExpiryCollector
+type expirySource struct {
+	name string
+	// the bundle as it was added, or nil to reparse path each time
+	pems *ParsedPEMs
+	path string
+}
+
+// Exposes the expiry of certificates as metrics, for monitoring with
+// Prometheus or expvar.
+//
+// Bundles are added with AddBundle, and files with AddFile, which are
+// reread every time metrics are collected so renewals are picked up.  An
+// ExpiryCollector is an http.Handler serving the Prometheus text
+// exposition format, and an expvar.Var for expvar.Publish.  It is safe
+// for concurrent use.
+type ExpiryCollector struct {
+	mu      sync.Mutex
+	sources []expirySource
+	opts    []Option
+}
+
+// The metrics for one certificate
+type CertificateMetrics struct {
+	// The name of the bundle or file the certificate is in
+	Source string `json:"source"`
+	// The subject and issuer as x509's pkix.Name String gives them
+	Subject string `json:"subject"`
+	Issuer  string `json:"issuer"`
+	// The serial number in hex
+	Serial string `json:"serial"`
+	// Until the certificate expires, negative once it has
+	ExpirySeconds float64   `json:"expiry_seconds"`
+	NotBefore     time.Time `json:"not_before"`
+	NotAfter      time.Time `json:"not_after"`
+}
+
+// Whether a source could be loaded when metrics were collected
+type SourceMetrics struct {
+	Source string `json:"source"`
+	Up     bool   `json:"up"`
+	// Why the source couldn't be loaded
+	Error string `json:"error,omitempty"`
+}
+
+// Make an ExpiryCollector.  Files added to it are parsed with opts, so
+// WithClock changes the time their expiry is measured from.
+func NewExpiryCollector(opts ...Option) *ExpiryCollector {
+	return &ExpiryCollector{opts: opts}
+}
+
+// Report the certificates remaining to be consumed in pems under the
+// name source.  The parsed PEMs are not consumed.
+func (c *ExpiryCollector) AddBundle(source string, pems ParsedPEMs) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources = append(c.sources, expirySource{name: source, pems: &pems})
+}
+
+// Report the certificates in the file at path, under its path, rereading
+// it each time metrics are collected.  A file which can't be read or
+// parsed is reported as down.
+func (c *ExpiryCollector) AddFile(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources = append(c.sources, expirySource{name: path, path: path})
+}
+
+// Collect the current metrics of every certificate, in the order their
+// sources were added, and of every source
+func (c *ExpiryCollector) Collect() ([]CertificateMetrics, []SourceMetrics) {
+	c.mu.Lock()
+	sources := append([]expirySource{}, c.sources...)
+	c.mu.Unlock()
+
+	certs := []CertificateMetrics{}
+	states := []SourceMetrics{}
+	for _, source := range sources {
+		pems := source.pems
+		if pems == nil {
+			loaded, err := c.load(source.path)
+			if err != nil {
+				states = append(states, SourceMetrics{Source: source.name, Error: err.Error()})
+				continue
+			}
+			pems = &loaded
+		}
+		states = append(states, SourceMetrics{Source: source.name, Up: true})
+		for _, e := range pems.ExpiryReport(time.Time{}, 0).Certificates {
+			certs = append(certs, CertificateMetrics{
+				Source:        source.name,
+				Subject:       e.Certificate.Subject.String(),
+				Issuer:        e.Certificate.Issuer.String(),
+				Serial:        e.Certificate.SerialNumber.Text(16),
+				ExpirySeconds: e.Remaining.Seconds(),
+				NotBefore:     e.NotBefore,
+				NotAfter:      e.NotAfter,
+			})
+		}
+	}
+	return certs, states
+}
+
+func (c *ExpiryCollector) load(path string) (ParsedPEMs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ParsedPEMs{}, err
+	}
+	return ParsePEMs(data, c.opts...)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatMetricValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Write the metrics in the Prometheus text exposition format.
+//
+// Each certificate has betterpem_certificate_expiry_seconds,
+// betterpem_certificate_not_after_timestamp_seconds, and
+// betterpem_certificate_not_before_timestamp_seconds gauges, labelled
+// with its source, subject, issuer, and serial.  Each source has a
+// betterpem_source_up gauge, which is 0 when it couldn't be loaded.  A
+// certificate found more than once in a source is only reported once.
+func (c *ExpiryCollector) WriteMetrics(w io.Writer) error {
+	certs, states := c.Collect()
+	labels := make([]string, 0, len(certs))
+	unique := make([]CertificateMetrics, 0, len(certs))
+	seen := map[string]bool{}
+	for _, m := range certs {
+		l := fmt.Sprintf(`source="%s",subject="%s",issuer="%s",serial="%s"`,
+			labelEscaper.Replace(m.Source), labelEscaper.Replace(m.Subject), labelEscaper.Replace(m.Issuer), m.Serial)
+		if seen[l] {
+			continue
+		}
+		seen[l] = true
+		labels = append(labels, l)
+		unique = append(unique, m)
+	}
+
+	bw := bufio.NewWriter(w)
+	gauge := func(name, help string, value func(CertificateMetrics) float64) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for i, m := range unique {
+			fmt.Fprintf(bw, "%s{%s} %s\n", name, labels[i], formatMetricValue(value(m)))
+		}
+	}
+	gauge("betterpem_certificate_expiry_seconds", "Seconds until the certificate expires, negative once it has.",
+		func(m CertificateMetrics) float64 { return m.ExpirySeconds })
+	gauge("betterpem_certificate_not_after_timestamp_seconds", "When the certificate expires, in seconds since the epoch.",
+		func(m CertificateMetrics) float64 { return float64(m.NotAfter.Unix()) })
+	gauge("betterpem_certificate_not_before_timestamp_seconds", "When the certificate becomes valid, in seconds since the epoch.",
+		func(m CertificateMetrics) float64 { return float64(m.NotBefore.Unix()) })
+	fmt.Fprint(bw, "# HELP betterpem_source_up Whether the certificates could be loaded.\n# TYPE betterpem_source_up gauge\n")
+	for _, s := range states {
+		up := 0
+		if s.Up {
+			up = 1
+		}
+		fmt.Fprintf(bw, "betterpem_source_up{source=\"%s\"} %d\n", labelEscaper.Replace(s.Source), up)
+	}
+	return bw.Flush()
+}
+
+// Serve the metrics in the Prometheus text exposition format, as a
+// /metrics endpoint
+func (c *ExpiryCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteMetrics(w)
+}
+
+// Give the metrics as JSON, so the collector can be given to
+// expvar.Publish
+func (c *ExpiryCollector) String() string {
+	certs, states := c.Collect()
+	b, err := json.Marshal(struct {
+		Certificates []CertificateMetrics `json:"certificates"`
+		Sources      []SourceMetrics      `json:"sources"`
+	}{certs, states})
+	if err != nil {
+		return "null"
+	}
+	return string(b)
+}
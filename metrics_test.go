@@ -0,0 +1,69 @@
+package betterpem
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiryCollector(t *testing.T) {
+	c := newTestChain(t)
+	now := c.leaf.NotAfter.Add(-time.Hour)
+	clock := WithClock(func() time.Time { return now })
+	bundle, err := ParsePEMs(c.pem(t, c.leaf, c.leaf), clock)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(path, c.pem(t, c.root), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	collector := NewExpiryCollector(clock)
+	collector.AddBundle("leaf", bundle)
+	collector.AddFile(path)
+	collector.AddFile(filepath.Join(dir, "missing.pem"))
+
+	rec := httptest.NewRecorder()
+	collector.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	leafLabels := fmt.Sprintf(`source="leaf",subject="CN=leaf.example.com",issuer="CN=Test Intermediate",serial="%x"`, c.leaf.SerialNumber)
+	for _, want := range []string{
+		"# TYPE betterpem_certificate_expiry_seconds gauge\n",
+		"betterpem_certificate_expiry_seconds{" + leafLabels + "} 3600\n",
+		fmt.Sprintf("betterpem_certificate_not_after_timestamp_seconds{%s} %d\n", leafLabels, c.leaf.NotAfter.Unix()),
+		fmt.Sprintf("betterpem_certificate_not_before_timestamp_seconds{%s} %d\n", leafLabels, c.leaf.NotBefore.Unix()),
+		fmt.Sprintf(`betterpem_certificate_expiry_seconds{source="%s",subject="CN=Test Root"`, path),
+		fmt.Sprintf("betterpem_source_up{source=\"%s\"} 1\n", path),
+		`missing.pem"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in metrics:\n%s", want, body)
+		}
+	}
+	if n := strings.Count(body, "betterpem_certificate_expiry_seconds{source=\"leaf\""); n != 1 {
+		t.Errorf("expected the repeated leaf once, got %d series", n)
+	}
+
+	var v expvar.Var = collector
+	var decoded struct {
+		Certificates []CertificateMetrics
+		Sources      []SourceMetrics
+	}
+	if err := json.Unmarshal([]byte(v.String()), &decoded); err != nil {
+		t.Fatalf("unexpected error decoding expvar %#v", err)
+	}
+	if len(decoded.Certificates) != 3 || len(decoded.Sources) != 3 || decoded.Sources[2].Up || decoded.Sources[2].Error == "" {
+		t.Errorf("unexpected expvar %s", v.String())
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package betterpem
+
+import "os"
+
+// Parse a PEM file by mapping it into memory rather than reading it, so
+// aggregated bundles of hundreds of megabytes can be parsed without
+// holding all of their text on the heap.
+//
+// This system has no mmap, so the file is read instead.
+func ParsePEMFileMmap(path string, opts ...Option) (ParsedPEMs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ParsedPEMs{}, err
+	}
+	return newParseOptions(opts).parse(data, false)
+}
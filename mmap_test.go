@@ -0,0 +1,39 @@
+package betterpem
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePEMFileMmap(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bundle.pem")
+	if err := os.WriteFile(path, bytes.Join([][]byte{test_ca, test_rsakey, test_eccert}, nil), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pems, err := ParsePEMFileMmap(path)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if pems.Length() != 3 {
+		t.Fatalf("expected 3 objects, got %d", pems.Length())
+	}
+	// the objects outlive the mapping
+	want, _ := ParsePEMs(test_ca)
+	if !pems.MustCertificate().Equal(want.MustCertificate()) {
+		t.Errorf("certificate changed after the file was unmapped")
+	}
+
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParsePEMFileMmap(empty); err != ErrNoPEMFound {
+		t.Errorf("expected ErrNoPEMFound for an empty file, got %#v", err)
+	}
+	if _, err := ParsePEMFileMmap(filepath.Join(dir, "missing.pem")); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %#v", err)
+	}
+}
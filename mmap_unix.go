@@ -0,0 +1,38 @@
+//go:build unix
+
+package betterpem
+
+import (
+	"os"
+	"syscall"
+)
+
+// Parse a PEM file by mapping it into memory rather than reading it, so
+// aggregated bundles of hundreds of megabytes can be parsed without
+// holding all of their text on the heap.
+//
+// Only the decoded blocks are copied out of the mapping, which is
+// removed before returning.  Options are the same as for ParsePEMs,
+// except that WithInPlaceDecoding is ignored.  On systems without mmap
+// the file is read instead.
+func ParsePEMFileMmap(path string, opts ...Option) (ParsedPEMs, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ParsedPEMs{}, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return ParsedPEMs{}, err
+	}
+	if fi.Size() == 0 {
+		// there's nothing to map
+		return newParseOptions(opts).parse(nil, false)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return ParsedPEMs{}, &os.PathError{Op: "mmap", Path: path, Err: err}
+	}
+	defer syscall.Munmap(data)
+	return newParseOptions(opts).parse(data, false)
+}
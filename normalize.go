@@ -0,0 +1,75 @@
+package betterpem
+
+import (
+	"encoding/pem"
+)
+
+// Labels seen in the wild mapped to the label RFC 7468 says to use
+var labelAliases = map[string]string{
+	"X509 CERTIFICATE":        "CERTIFICATE",
+	"X.509 CERTIFICATE":       "CERTIFICATE",
+	"NEW CERTIFICATE REQUEST": "CERTIFICATE REQUEST",
+	"CRL":                     "X509 CRL",
+	"CERTIFICATE CHAIN":       "PKCS7",
+}
+
+// Labels canonical PEM may contain: those in RFC 7468 and the common
+// legacy OpenSSL and OpenSSH ones
+var knownLabels = map[string]bool{
+	"CERTIFICATE":           true,
+	"TRUSTED CERTIFICATE":   true,
+	"ATTRIBUTE CERTIFICATE": true,
+	"X509 CRL":              true,
+	"CERTIFICATE REQUEST":   true,
+	"PKCS7":                 true,
+	"CMS":                   true,
+	"PRIVATE KEY":           true,
+	"ENCRYPTED PRIVATE KEY": true,
+	"PUBLIC KEY":            true,
+	"RSA PRIVATE KEY":       true,
+	"RSA PUBLIC KEY":        true,
+	"EC PRIVATE KEY":        true,
+	"EC PARAMETERS":         true,
+	"DH PARAMETERS":         true,
+	"OPENSSH PRIVATE KEY":   true,
+}
+
+// Re-emit PEM data in the canonical form described by RFC 7468.
+//
+// Every block with a recognized label is written in order with 64
+// character lines and LF line endings.  Label aliases such as
+// "X509 CERTIFICATE" are replaced by their standard names, and headers
+// are removed unless the block is encrypted the legacy OpenSSL way and
+// needs them to be decrypted.  Blocks with unrecognized labels and
+// anything between blocks are dropped.
+//
+// Normalizing the same material twice always gives the same bytes, which
+// makes the result suitable for hashing or diffing.
+func NormalizePEM(pemInt interface{}) ([]byte, error) {
+	input, err := intoBytes(pemInt)
+	if err != nil {
+		return nil, err
+	}
+	blocks := []*pem.Block{}
+	scan := newScanner(input, false)
+	for {
+		found, ok := scan.next()
+		if !ok {
+			break
+		}
+		block := found.Block
+		label := block.Type
+		if alias, ok := labelAliases[label]; ok {
+			label = alias
+		}
+		if !knownLabels[label] {
+			continue
+		}
+		var headers map[string]string
+		if _, ok := block.Headers["Proc-Type"]; ok {
+			headers = block.Headers
+		}
+		blocks = append(blocks, &pem.Block{Type: label, Headers: headers, Bytes: block.Bytes})
+	}
+	return encodeBlocks(blocks, nil)
+}
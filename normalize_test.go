@@ -0,0 +1,38 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/pem"
+	"testing"
+)
+
+func TestNormalizePEM(t *testing.T) {
+	objs, err := ParsePEMs(test_eccert)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	cert := objs.MustCertificate()
+	messy, err := EncodePEM(cert, map[string]string{"friendlyName": "x"}, WithLineWidth(76), WithCRLF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	messy = bytes.Replace(messy, []byte("CERTIFICATE"), []byte("X509 CERTIFICATE"), -1)
+	unknown := pem.EncodeToMemory(&pem.Block{Type: "MY APP TOKEN", Bytes: []byte("hi")})
+	input := bytes.Join([][]byte{[]byte("junk before\n"), messy, []byte("junk between\n"), unknown}, nil)
+
+	out, err := NormalizePEM(input)
+	if err != nil {
+		t.Fatalf("unexpected error normalizing %#v", err)
+	}
+	want := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if !bytes.Equal(out, want) {
+		t.Errorf("unexpected normalized pem:\n%s", out)
+	}
+	again, err := NormalizePEM(out)
+	if err != nil {
+		t.Fatalf("unexpected error normalizing %#v", err)
+	}
+	if !bytes.Equal(out, again) {
+		t.Error("normalizing is not idempotent")
+	}
+}
@@ -0,0 +1,68 @@
+package betterpem
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+var ErrNoOCSPServer = errors.New("certificate does not name an OCSP responder")
+
+// The most an OCSP response may be, to guard against broken responders
+const maxOCSPResponse = 1 << 20
+
+func queryOCSP(ctx context.Context, server string, req []byte, leaf, issuer *x509.Certificate) (*ocsp.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/ocsp-request")
+	httpReq.Header.Set("Accept", "application/ocsp-response")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder %s returned %s", server, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOCSPResponse))
+	if err != nil {
+		return nil, err
+	}
+	return ocsp.ParseResponseForCert(body, leaf, issuer)
+}
+
+// Ask the OCSP responder named in a certificate whether it has been
+// revoked.
+//
+// The request is sent to each responder in the certificate's authority
+// information access extension in turn until one answers.  The response
+// is checked against the issuer, so issuer must be the certificate which
+// signed leaf.  The response must be about leaf's serial number.  Look
+// at the response's Status to see whether leaf is good, revoked, or
+// unknown to the responder.
+func OCSPStatus(ctx context.Context, leaf, issuer *x509.Certificate) (*ocsp.Response, error) {
+	if len(leaf.OCSPServer) == 0 {
+		return nil, ErrNoOCSPServer
+	}
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, server := range leaf.OCSPServer {
+		resp, err := queryOCSP(ctx, server, req, leaf, issuer)
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
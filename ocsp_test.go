@@ -0,0 +1,104 @@
+package betterpem
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestOCSPStatus(t *testing.T) {
+	c := newTestChain(t)
+	var revoked *x509.Certificate
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil || r.Header.Get("Content-Type") != "application/ocsp-request" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		status := ocsp.Good
+		if req.SerialNumber.Cmp(revoked.SerialNumber) == 0 {
+			status = ocsp.Revoked
+		}
+		resp, err := ocsp.CreateResponse(c.intermediate, c.intermediate, ocsp.Response{
+			Status:           status,
+			SerialNumber:     req.SerialNumber,
+			ThisUpdate:       time.Now().Add(-time.Minute),
+			NextUpdate:       time.Now().Add(time.Hour),
+			RevokedAt:        time.Now().Add(-time.Minute),
+			RevocationReason: ocsp.KeyCompromise,
+		}, c.intermediateKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(resp)
+	}))
+	defer responder.Close()
+
+	issue := func() *x509.Certificate {
+		cert, _ := issueTestCert(t, &x509.Certificate{
+			Subject:    pkix.Name{CommonName: "ocsp.example.com"},
+			OCSPServer: []string{"http://127.0.0.1:1/unreachable", responder.URL},
+		}, c.intermediate, c.intermediateKey)
+		return cert
+	}
+	good := issue()
+	revoked = issue()
+
+	resp, err := OCSPStatus(context.Background(), good, c.intermediate)
+	if err != nil {
+		t.Fatalf("unexpected error querying OCSP %#v", err)
+	}
+	if resp.Status != ocsp.Good {
+		t.Errorf("expected good, got %d", resp.Status)
+	}
+	resp, err = OCSPStatus(context.Background(), revoked, c.intermediate)
+	if err != nil {
+		t.Fatalf("unexpected error querying OCSP %#v", err)
+	}
+	if resp.Status != ocsp.Revoked || resp.RevocationReason != ocsp.KeyCompromise {
+		t.Errorf("expected revoked, got %d", resp.Status)
+	}
+
+	if _, err := OCSPStatus(context.Background(), c.leaf, c.intermediate); err != ErrNoOCSPServer {
+		t.Errorf("expected ErrNoOCSPServer, got %#v", err)
+	}
+}
+
+func TestOCSPStatusOtherSerial(t *testing.T) {
+	c := newTestChain(t)
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a good response the issuer signed, but for another certificate
+		resp, err := ocsp.CreateResponse(c.intermediate, c.intermediate, ocsp.Response{
+			Status:       ocsp.Good,
+			SerialNumber: big.NewInt(1),
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+		}, c.intermediateKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(resp)
+	}))
+	defer responder.Close()
+
+	cert, _ := issueTestCert(t, &x509.Certificate{
+		Subject:    pkix.Name{CommonName: "ocsp.example.com"},
+		OCSPServer: []string{responder.URL},
+	}, c.intermediate, c.intermediateKey)
+	if resp, err := OCSPStatus(context.Background(), cert, c.intermediate); err == nil {
+		t.Errorf("expected an error for a response about another serial, got status %d", resp.Status)
+	}
+}
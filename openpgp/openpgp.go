@@ -0,0 +1,572 @@
+// Package openpgp extracts the key material from OpenPGP keys, such as
+// those gpg exports in "PGP PUBLIC KEY BLOCK" and "PGP PRIVATE KEY BLOCK"
+// armor, as the crypto keys betterpem parses from PEM, so identities
+// rooted in PGP can be reused for TLS, JWT, or SSH.
+//
+// Only keys are read.  Signatures, including the self signatures binding
+// user IDs and subkeys, are not checked.
+package openpgp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jamesandariese/betterpem"
+)
+
+var ErrMalformed = errors.New("malformed OpenPGP data")
+var ErrBadChecksum = errors.New("OpenPGP armor checksum does not match")
+var ErrUnsupportedKey = errors.New("OpenPGP key uses an unsupported version or algorithm")
+var ErrNoKeys = errors.New("no OpenPGP keys were found")
+
+// An OpenPGP primary key or subkey
+type Key struct {
+	// *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, or
+	// *ecdh.PublicKey for encryption keys on Curve25519 or the NIST
+	// curves
+	PublicKey crypto.PublicKey
+	// The private half of PublicKey, from a secret key not protected by
+	// a passphrase, else nil
+	PrivateKey crypto.PrivateKey
+	// Set for a secret key protected by a passphrase, which this package
+	// does not decrypt
+	Encrypted bool
+
+	Fingerprint []byte
+	KeyID       uint64
+	Created     time.Time
+	IsSubkey    bool
+	// The user IDs of a primary key, such as "Name <name@example.com>"
+	UserIDs []string
+}
+
+const (
+	tagSecretKey    = 5
+	tagPublicKey    = 6
+	tagSecretSubkey = 7
+	tagUserID       = 13
+	tagPublicSubkey = 14
+)
+
+const (
+	algoRSA        = 1
+	algoRSAEncrypt = 2
+	algoRSASign    = 3
+	algoECDH       = 18
+	algoECDSA      = 19
+	algoEdDSA      = 22
+	algoX25519     = 25
+	algoEd25519    = 27
+)
+
+var (
+	oidP256       = []byte{0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}
+	oidP384       = []byte{0x2b, 0x81, 0x04, 0x00, 0x22}
+	oidP521       = []byte{0x2b, 0x81, 0x04, 0x00, 0x23}
+	oidEd25519    = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0xda, 0x47, 0x0f, 0x01}
+	oidCurve25519 = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x97, 0x55, 0x01, 0x05, 0x01}
+)
+
+// The NIST curve named by an OID, else nil
+func nistCurve(oid []byte) elliptic.Curve {
+	switch {
+	case bytes.Equal(oid, oidP256):
+		return elliptic.P256()
+	case bytes.Equal(oid, oidP384):
+		return elliptic.P384()
+	case bytes.Equal(oid, oidP521):
+		return elliptic.P521()
+	}
+	return nil
+}
+
+func ecdhCurve(curve elliptic.Curve) ecdh.Curve {
+	switch curve {
+	case elliptic.P256():
+		return ecdh.P256()
+	case elliptic.P384():
+		return ecdh.P384()
+	}
+	return ecdh.P521()
+}
+
+// Reads the fields of a packet, remembering whether it ran short
+type reader struct {
+	b   []byte
+	bad bool
+}
+
+func (r *reader) take(n int) []byte {
+	if n < 0 || n > len(r.b) {
+		r.bad = true
+		r.b = nil
+		return nil
+	}
+	ret := r.b[:n]
+	r.b = r.b[n:]
+	return ret
+}
+
+func (r *reader) byte() byte {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() int {
+	if b := r.take(2); b != nil {
+		return int(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *reader) uint32() int {
+	if b := r.take(4); b != nil {
+		return int(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+// A multiprecision integer: a bit count, then the big endian value
+func (r *reader) mpi() []byte {
+	bits := r.uint16()
+	if r.bad {
+		return nil
+	}
+	return r.take((bits + 7) / 8)
+}
+
+// A curve OID without its DER tag and length
+func (r *reader) oid() []byte {
+	return r.take(int(r.byte()))
+}
+
+func leftPad(b []byte, size int) ([]byte, bool) {
+	if len(b) > size {
+		return nil, false
+	}
+	return append(make([]byte, size-len(b)), b...), true
+}
+
+func reversed(b []byte) []byte {
+	ret := make([]byte, len(b))
+	for i, c := range b {
+		ret[len(b)-1-i] = c
+	}
+	return ret
+}
+
+// Split the next packet off data
+func nextPacket(data []byte) (tag byte, body, rest []byte, err error) {
+	r := &reader{b: data}
+	first := r.byte()
+	if first&0x80 == 0 {
+		return 0, nil, nil, fmt.Errorf("%w: bad packet header", ErrMalformed)
+	}
+	length := 0
+	if first&0x40 != 0 {
+		tag = first & 0x3f
+		switch b := int(r.byte()); {
+		case b < 192:
+			length = b
+		case b < 224:
+			length = (b-192)<<8 + int(r.byte()) + 192
+		case b == 255:
+			length = r.uint32()
+		default:
+			// keys, user IDs, and signatures can't use partial lengths
+			return 0, nil, nil, fmt.Errorf("%w: unexpected partial length packet", ErrMalformed)
+		}
+	} else {
+		tag = first >> 2 & 0xf
+		switch first & 3 {
+		case 0:
+			length = int(r.byte())
+		case 1:
+			length = r.uint16()
+		case 2:
+			length = r.uint32()
+		default:
+			length = len(r.b)
+		}
+	}
+	body = r.take(length)
+	if r.bad {
+		return 0, nil, nil, fmt.Errorf("%w: truncated packet", ErrMalformed)
+	}
+	return tag, body, r.b, nil
+}
+
+// Read the public key material for an algorithm
+func publicKey(algo byte, r *reader) (crypto.PublicKey, error) {
+	switch algo {
+	case algoRSA, algoRSAEncrypt, algoRSASign:
+		n, e := r.mpi(), r.mpi()
+		if r.bad || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: bad RSA key", ErrMalformed)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case algoECDSA, algoECDH, algoEdDSA:
+		oid, point := r.oid(), r.mpi()
+		if algo == algoECDH {
+			// the KDF parameters
+			r.take(int(r.byte()))
+		}
+		if r.bad {
+			return nil, fmt.Errorf("%w: bad EC key", ErrMalformed)
+		}
+		if curve := nistCurve(oid); curve != nil && algo != algoEdDSA {
+			pub, err := ecdhCurve(curve).NewPublicKey(point)
+			if err != nil {
+				return nil, fmt.Errorf("%w: point is not on the curve", ErrMalformed)
+			}
+			if algo == algoECDH {
+				return pub, nil
+			}
+			size := (curve.Params().BitSize + 7) / 8
+			return &ecdsa.PublicKey{
+				Curve: curve,
+				X:     new(big.Int).SetBytes(point[1 : 1+size]),
+				Y:     new(big.Int).SetBytes(point[1+size:]),
+			}, nil
+		}
+		// Curve25519 points are a 0x40 prefix and the native encoding
+		if len(point) != 33 || point[0] != 0x40 {
+			break
+		}
+		if algo == algoEdDSA && bytes.Equal(oid, oidEd25519) {
+			return ed25519.PublicKey(bytes.Clone(point[1:])), nil
+		}
+		if algo == algoECDH && bytes.Equal(oid, oidCurve25519) {
+			pub, err := ecdh.X25519().NewPublicKey(point[1:])
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad X25519 key", ErrMalformed)
+			}
+			return pub, nil
+		}
+	case algoEd25519:
+		if b := r.take(ed25519.PublicKeySize); b != nil {
+			return ed25519.PublicKey(bytes.Clone(b)), nil
+		}
+		return nil, fmt.Errorf("%w: bad Ed25519 key", ErrMalformed)
+	case algoX25519:
+		if pub, err := ecdh.X25519().NewPublicKey(r.take(32)); err == nil {
+			return pub, nil
+		}
+		return nil, fmt.Errorf("%w: bad X25519 key", ErrMalformed)
+	}
+	return nil, fmt.Errorf("%w: algorithm %d", ErrUnsupportedKey, algo)
+}
+
+// Read the unencrypted secret key material for pub
+func privateKey(algo byte, pub crypto.PublicKey, r *reader) (crypto.PrivateKey, error) {
+	mismatch := fmt.Errorf("%w: private key does not match its public key", ErrMalformed)
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		d, p, q := r.mpi(), r.mpi(), r.mpi()
+		// u, which Precompute computes rather than trusts
+		r.mpi()
+		if r.bad {
+			return nil, fmt.Errorf("%w: bad RSA private key", ErrMalformed)
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: *pub,
+			D:         new(big.Int).SetBytes(d),
+			Primes:    []*big.Int{new(big.Int).SetBytes(p), new(big.Int).SetBytes(q)},
+		}
+		if key.Validate() != nil {
+			return nil, mismatch
+		}
+		key.Precompute()
+		return key, nil
+	case *ecdsa.PublicKey:
+		d, ok := leftPad(r.mpi(), (pub.Curve.Params().BitSize+7)/8)
+		if !ok {
+			return nil, mismatch
+		}
+		priv, err := ecdhCurve(pub.Curve).NewPrivateKey(d)
+		if err != nil {
+			return nil, mismatch
+		}
+		check, err := pub.ECDH()
+		if err != nil || !priv.PublicKey().Equal(check) {
+			return nil, mismatch
+		}
+		return &ecdsa.PrivateKey{PublicKey: *pub, D: new(big.Int).SetBytes(d)}, nil
+	case ed25519.PublicKey:
+		var seed []byte
+		if algo == algoEd25519 {
+			seed = r.take(ed25519.SeedSize)
+		} else {
+			seed, _ = leftPad(r.mpi(), ed25519.SeedSize)
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, mismatch
+		}
+		key := ed25519.NewKeyFromSeed(seed)
+		if !pub.Equal(key.Public()) {
+			return nil, mismatch
+		}
+		return key, nil
+	case *ecdh.PublicKey:
+		var d []byte
+		switch {
+		case algo == algoX25519:
+			d = r.take(32)
+		case pub.Curve() == ecdh.X25519():
+			// stored as a big endian integer rather than natively
+			if b, ok := leftPad(r.mpi(), 32); ok {
+				d = reversed(b)
+			}
+		default:
+			d, _ = leftPad(r.mpi(), (len(pub.Bytes())-1)/2)
+		}
+		priv, err := pub.Curve().NewPrivateKey(d)
+		if err != nil || !priv.PublicKey().Equal(pub) {
+			return nil, mismatch
+		}
+		return priv, nil
+	}
+	return nil, fmt.Errorf("%w: unexpected key type %T", ErrUnsupportedKey, pub)
+}
+
+// Parse a public key, public subkey, secret key, or secret subkey packet
+func parseKey(tag byte, body []byte) (*Key, error) {
+	r := &reader{b: body}
+	version := r.byte()
+	created := r.uint32()
+	algo := r.byte()
+	if r.bad {
+		return nil, fmt.Errorf("%w: truncated key", ErrMalformed)
+	}
+	key := &Key{
+		Created:  time.Unix(int64(created), 0),
+		IsSubkey: tag == tagPublicSubkey || tag == tagSecretSubkey,
+	}
+	var material *reader
+	switch version {
+	case 4:
+		material = r
+	case 6:
+		// the key material is prefixed with its length
+		material = &reader{b: r.take(r.uint32())}
+		if r.bad {
+			return nil, fmt.Errorf("%w: truncated key", ErrMalformed)
+		}
+	default:
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedKey, version)
+	}
+	pub, err := publicKey(algo, material)
+	if err != nil {
+		return nil, err
+	}
+	key.PublicKey = pub
+
+	// the fingerprint covers the public part, however the packet is tagged
+	public := body[:len(body)-len(r.b)]
+	if version == 4 {
+		sum := sha1.Sum(append([]byte{0x99, byte(len(public) >> 8), byte(len(public))}, public...))
+		key.Fingerprint = sum[:]
+		key.KeyID = binary.BigEndian.Uint64(sum[12:])
+	} else {
+		h := sha256.New()
+		h.Write([]byte{0x9b})
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(public))))
+		h.Write(public)
+		key.Fingerprint = h.Sum(nil)
+		key.KeyID = binary.BigEndian.Uint64(key.Fingerprint)
+	}
+
+	if tag != tagSecretKey && tag != tagSecretSubkey {
+		return key, nil
+	}
+	if usage := r.byte(); usage != 0 {
+		key.Encrypted = true
+		return key, nil
+	}
+	secret := r.b
+	if key.PrivateKey, err = privateKey(algo, pub, r); err != nil {
+		return nil, err
+	}
+	if version == 4 {
+		// a checksum of the secret key material follows it
+		var sum uint16
+		for _, c := range secret[:len(secret)-len(r.b)] {
+			sum += uint16(c)
+		}
+		if check := r.uint16(); r.bad || check != int(sum) {
+			return nil, fmt.Errorf("%w: bad secret key checksum", ErrMalformed)
+		}
+	}
+	return key, nil
+}
+
+// Extract the keys from binary OpenPGP packets, such as the output of
+// gpg --export without --armor, in order.
+//
+// Each user ID is added to the primary key it follows.  Keys using
+// algorithms besides RSA, ECDSA, EdDSA, and ECDH on the NIST curves or
+// Curve25519 are skipped, unless there are no others, when an error
+// matching ErrUnsupportedKey is returned.  Packets which are not keys or
+// user IDs are ignored.
+func ParseKeys(packets []byte) ([]*Key, error) {
+	ret := []*Key{}
+	var primary *Key
+	var unsupported error
+	for len(packets) > 0 {
+		tag, body, rest, err := nextPacket(packets)
+		if err != nil {
+			return nil, err
+		}
+		packets = rest
+		switch tag {
+		case tagPublicKey, tagSecretKey, tagPublicSubkey, tagSecretSubkey:
+			key, err := parseKey(tag, body)
+			if errors.Is(err, ErrUnsupportedKey) {
+				unsupported = err
+				if tag == tagPublicKey || tag == tagSecretKey {
+					// its user IDs aren't for the previous key
+					primary = nil
+				}
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !key.IsSubkey {
+				primary = key
+			}
+			ret = append(ret, key)
+		case tagUserID:
+			if primary != nil {
+				primary.UserIDs = append(primary.UserIDs, string(body))
+			}
+		}
+	}
+	if len(ret) == 0 {
+		if unsupported != nil {
+			return nil, unsupported
+		}
+		return nil, ErrNoKeys
+	}
+	return ret, nil
+}
+
+// The CRC-24 of RFC 4880 section 6.1, which armor ends with
+func crc24(data []byte) uint32 {
+	crc := uint32(0xb704ce)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864cfb
+			}
+		}
+	}
+	return crc & 0xffffff
+}
+
+// Decode the body of an armored block, its lines after any armor
+// headers, checking the "=" checksum line if there is one.  PEM has no
+// checksum line, so betterpem can't decode armor which has one.
+func decodeArmor(lines []string) ([]byte, error) {
+	// armor headers end at a blank line
+	for i, line := range lines {
+		if line == "" {
+			lines = lines[i+1:]
+			break
+		}
+		if !strings.Contains(line, ": ") {
+			break
+		}
+	}
+	var checksum string
+	if n := len(lines); n > 0 && strings.HasPrefix(lines[n-1], "=") {
+		checksum, lines = lines[n-1][1:], lines[:n-1]
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(lines, ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if checksum != "" {
+		want, err := base64.StdEncoding.DecodeString(checksum)
+		if err != nil || len(want) != 3 || uint32(want[0])<<16|uint32(want[1])<<8|uint32(want[2]) != crc24(data) {
+			return nil, ErrBadChecksum
+		}
+	}
+	return data, nil
+}
+
+// Extract the keys from every "PGP PUBLIC KEY BLOCK" and "PGP PRIVATE
+// KEY BLOCK" in armored data, such as the output of gpg --export --armor,
+// as ParseKeys does for binary packets.
+//
+// Other armored blocks, like signatures and messages, and anything
+// between blocks, are skipped.  If there are no key blocks, ErrNoKeys is
+// returned.
+func ParseArmored(armored []byte) ([]*Key, error) {
+	ret := []*Key{}
+	var body []string
+	label := ""
+	for _, line := range strings.Split(strings.ReplaceAll(string(armored), "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if t, ok := strings.CutPrefix(line, "-----BEGIN "); ok && strings.HasSuffix(t, "-----") {
+			label, body = strings.TrimSuffix(t, "-----"), []string{}
+			continue
+		}
+		if line != "-----END "+label+"-----" || body == nil {
+			body = append(body, line)
+			continue
+		}
+		if label == "PGP PUBLIC KEY BLOCK" || label == "PGP PRIVATE KEY BLOCK" {
+			data, err := decodeArmor(body)
+			if err != nil {
+				return nil, err
+			}
+			keys, err := ParseKeys(data)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, keys...)
+		}
+		label, body = "", nil
+	}
+	if len(ret) == 0 {
+		return nil, ErrNoKeys
+	}
+	return ret, nil
+}
+
+// Parse a "PGP PUBLIC KEY BLOCK" or "PGP PRIVATE KEY BLOCK" betterpem has
+// decoded into the []*Key from ParseKeys.  It is a betterpem.BlockParser,
+// for use with betterpem.WithBlockType.
+//
+// Armor ending with a checksum line doesn't reach a BlockParser, as it
+// isn't valid PEM; ParseArmored reads it.
+func ParseBlock(block *pem.Block) (interface{}, error) {
+	return ParseKeys(block.Bytes)
+}
+
+// Register ParseBlock for PGP key blocks with betterpem.RegisterBlockType,
+// so every betterpem.ParsePEMs call returns their keys as []*Key.
+func Register() {
+	betterpem.RegisterBlockType("PGP PUBLIC KEY BLOCK", ParseBlock)
+	betterpem.RegisterBlockType("PGP PRIVATE KEY BLOCK", ParseBlock)
+}
@@ -0,0 +1,193 @@
+package betterpem
+
+import (
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Changes how ParsePEMs parses.  Options are passed after the input, so
+// ParsePEMs(input) keeps working as it always has:
+//
+//	pems, err := ParsePEMs(input, WithSkipInvalid(), WithLogger(logger))
+//
+// Later options override earlier ones.
+type Option func(*parseOptions)
+
+type parseOptions struct {
+	skipInvalid    bool
+	errorOnUnknown bool
+	partialResults bool
+	logger         *slog.Logger
+	errorOnGarbage bool
+	parsers        map[string]BlockParser
+	// only these types are parsed when not nil
+	types      map[string]bool
+	without    map[string]bool
+	passphrase PassphraseFunc
+	clock      func() time.Time
+	maxBlocks  int
+	lazy       bool
+	inPlace    bool
+	workers    int
+}
+
+// Whether a block of a type should be parsed at all
+func (o *parseOptions) wanted(typeLabel string) bool {
+	if o.types != nil && !o.types[typeLabel] {
+		return false
+	}
+	return !o.without[typeLabel]
+}
+
+func newParseOptions(opts []Option) *parseOptions {
+	o := &parseOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Skip blocks which fail to parse rather than failing, so what does parse
+// can still be used.
+//
+// The errors for the skipped blocks are kept; see ParsedPEMs.Invalid and
+// ParsedPEMs.InvalidErr.  If no block parses, ParsePEMs fails with every
+// block's error joined together.
+func WithSkipInvalid() Option {
+	return func(o *parseOptions) {
+		o.skipInvalid = true
+	}
+}
+
+// Fail on blocks of types ParsePEMs doesn't support instead of ignoring
+// them, for configuration which should hold nothing unexpected.
+//
+// The error is a *BlockError wrapping an *ErrUnsupportedBlockType, and is
+// returned even with WithSkipInvalid.
+func WithErrorOnUnknown() Option {
+	return func(o *parseOptions) {
+		o.errorOnUnknown = true
+	}
+}
+
+// When a block fails to parse, return the objects parsed from the blocks
+// before it along with the error, so the caller can decide whether the
+// failure matters.
+func WithPartialResults() Option {
+	return func(o *parseOptions) {
+		o.partialResults = true
+	}
+}
+
+// Log a debug event to logger for each block found, giving its index,
+// type, size, and whether it was parsed, skipped, or invalid, to help
+// find out why something didn't load.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *parseOptions) {
+		o.logger = logger
+	}
+}
+
+func (o *parseOptions) logBlock(index int, der *pem.Block, outcome string, err error) {
+	if o.logger == nil {
+		return
+	}
+	attrs := []interface{}{"index", index, "type", der.Type, "size", len(der.Bytes), "outcome", outcome}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	o.logger.Debug("pem block", attrs...)
+}
+
+// Fail when there is anything but whitespace before, between, or after
+// the blocks, which pem.Decode would otherwise silently ignore, so
+// corrupted bundles are caught rather than partly accepted.
+//
+// The error is a *GarbageError saying where the first such content was.
+func WithErrorOnGarbage() Option {
+	return func(o *parseOptions) {
+		o.errorOnGarbage = true
+	}
+}
+
+// Parse only blocks with these type labels, such as just the
+// "CERTIFICATE" blocks of a large bundle, so no time is spent on the
+// rest.  Using it more than once adds to the types parsed.
+//
+// Other blocks are ignored and listed by ParsedPEMs.Skipped as filtered,
+// even with WithErrorOnUnknown.  If every block is filtered out,
+// ParsePEMs fails with an *ErrOnlyUnsupportedBlocks just as if their
+// types weren't supported.
+func WithTypes(typeLabels ...string) Option {
+	return func(o *parseOptions) {
+		if o.types == nil {
+			o.types = map[string]bool{}
+		}
+		for _, t := range typeLabels {
+			o.types[t] = true
+		}
+	}
+}
+
+// Don't parse blocks with these type labels.  Using it more than once
+// adds to the types not parsed.
+//
+// The blocks are ignored and listed by ParsedPEMs.Skipped as filtered,
+// even with WithErrorOnUnknown.
+func WithoutTypes(typeLabels ...string) Option {
+	return func(o *parseOptions) {
+		if o.without == nil {
+			o.without = map[string]bool{}
+		}
+		for _, t := range typeLabels {
+			o.without[t] = true
+		}
+	}
+}
+
+// Supplies the passphrase for an encrypted block
+type PassphraseFunc func(block BlockInfo) ([]byte, error)
+
+// Decrypt encrypted private keys with passphrases from passphrase, which
+// is only called when an encrypted block is found, so interactive tools
+// can prompt for it and secret managers can be asked for it lazily.
+//
+// Both "ENCRYPTED PRIVATE KEY" blocks using PBES2 with AES, as modern
+// openssl writes them, and legacy blocks with Proc-Type and DEK-Info
+// headers are decrypted.  An error from passphrase, or a wrong
+// passphrase, fails the block like any other parse error.
+func WithPassphraseFunc(passphrase PassphraseFunc) Option {
+	return func(o *parseOptions) {
+		o.passphrase = passphrase
+	}
+}
+
+// Use clock instead of time.Now wherever the parsed PEMs are checked
+// against the current time: warnings, expiry reports, audits, chain
+// verification, host validation, and revocation checks.  This lets tests
+// and replay tooling look at a bundle as of any time.
+func WithClock(clock func() time.Time) Option {
+	return func(o *parseOptions) {
+		o.clock = clock
+	}
+}
+
+// Returned when there were more blocks than WithMaxBlocks allows
+type TooManyBlocksError struct {
+	Max int
+}
+
+func (e *TooManyBlocksError) Error() string {
+	return fmt.Sprintf("pem data has more than %d blocks", e.Max)
+}
+
+// Stop parsing with a *TooManyBlocksError once more than max blocks are
+// found, counting those of every type, to protect services parsing PEM
+// data from users against huge inputs.
+func WithMaxBlocks(max int) Option {
+	return func(o *parseOptions) {
+		o.maxBlocks = max
+	}
+}
@@ -0,0 +1,245 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithSkipInvalid(t *testing.T) {
+	bad := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")})
+	bundle := bytes.Join([][]byte{bad, test_ca, bad, test_rsakey}, nil)
+	if _, err := ParsePEMs(bundle); !errors.Is(err, ErrBadBlock) {
+		t.Fatalf("expected a bad block without the option, got %#v", err)
+	}
+	objs, err := ParsePEMs(bundle, WithSkipInvalid())
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if objs.Length() != 2 {
+		t.Errorf("expected 2 objects, got %d", objs.Length())
+	}
+	invalid := objs.Invalid()
+	if len(invalid) != 2 || invalid[0].Index != 0 || invalid[1].Index != 2 {
+		t.Errorf("unexpected invalid blocks %v", invalid)
+	}
+	objs.MustCertificate()
+	if len(objs.Invalid()) != 2 {
+		t.Error("consuming an object changed the invalid blocks")
+	}
+
+	joined := objs.InvalidErr()
+	if !errors.Is(joined, ErrBadBlock) || strings.Count(joined.Error(), "\n") != 1 {
+		t.Errorf("expected both errors joined, got %v", joined)
+	}
+
+	_, err = ParsePEMs(bytes.Join([][]byte{bad, bad}, nil), WithSkipInvalid())
+	if !errors.Is(err, ErrBadBlock) || strings.Count(err.Error(), "\n") != 1 {
+		t.Errorf("expected both bad blocks when nothing parsed, got %#v", err)
+	}
+	clean, err := ParsePEMs(test_ca, WithSkipInvalid())
+	if err != nil || clean.InvalidErr() != nil {
+		t.Errorf("unexpected invalid blocks %v", clean.InvalidErr())
+	}
+}
+
+func TestWithErrorOnUnknown(t *testing.T) {
+	pgp := pem.EncodeToMemory(&pem.Block{Type: "PGP PUBLIC KEY BLOCK", Bytes: []byte{1}})
+	bundle := bytes.Join([][]byte{test_ca, pgp}, nil)
+	if objs, err := ParsePEMs(bundle); err != nil || objs.Length() != 1 {
+		t.Fatalf("unknown blocks should be ignored by default, got %#v", err)
+	}
+	for _, opts := range [][]Option{{WithErrorOnUnknown()}, {WithErrorOnUnknown(), WithSkipInvalid()}} {
+		_, err := ParsePEMs(bundle, opts...)
+		var berr *BlockError
+		var uerr *ErrUnsupportedBlockType
+		if !errors.As(err, &berr) || berr.Index != 1 || !errors.As(err, &uerr) || uerr.Type != "PGP PUBLIC KEY BLOCK" {
+			t.Errorf("expected an unsupported block error, got %#v", err)
+		}
+	}
+}
+
+func TestWithPartialResults(t *testing.T) {
+	bad := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")})
+	bundle := bytes.Join([][]byte{test_ca, test_rsakey, bad, test_eccert}, nil)
+	if objs, err := ParsePEMs(bundle); err == nil || objs.Length() != 0 {
+		t.Fatalf("expected nothing without the option, got %d objects", objs.Length())
+	}
+	objs, err := ParsePEMs(bundle, WithPartialResults())
+	var berr *BlockError
+	if !errors.As(err, &berr) || berr.Index != 2 {
+		t.Errorf("expected the third block to fail, got %#v", err)
+	}
+	if objs.Length() != 2 {
+		t.Fatalf("expected the 2 blocks before the failure, got %d", objs.Length())
+	}
+	objs.MustCertificate()
+	objs.MustRSAPrivateKey()
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	pgp := pem.EncodeToMemory(&pem.Block{Type: "PGP PUBLIC KEY BLOCK", Bytes: []byte{1}})
+	if _, err := ParsePEMs(bytes.Join([][]byte{test_ca, pgp}, nil), WithLogger(logger)); err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected an event per block, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], "type=CERTIFICATE") || !strings.Contains(lines[0], "outcome=parsed") {
+		t.Errorf("unexpected event for the certificate %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `type="PGP PUBLIC KEY BLOCK"`) || !strings.Contains(lines[1], "outcome=skipped") || !strings.Contains(lines[1], "size=1") {
+		t.Errorf("unexpected event for the unsupported block %q", lines[1])
+	}
+}
+
+func TestWithErrorOnGarbage(t *testing.T) {
+	c := newTestChain(t)
+	cert, key := c.pem(t, c.root), c.pem(t, c.rootKey)
+	clean := bytes.Join([][]byte{[]byte("\n\t \n"), cert, []byte("\r\n"), key, []byte("\n\n")}, nil)
+	if _, err := ParsePEMs(clean, WithErrorOnGarbage()); err != nil {
+		t.Errorf("unexpected error parsing pem surrounded by whitespace %#v", err)
+	}
+	for _, tc := range []struct {
+		name   string
+		bundle [][]byte
+		offset int
+	}{
+		{"leading", [][]byte{[]byte("subject=foo\n"), cert}, 0},
+		{"between", [][]byte{cert, []byte("  junk\n"), key}, len(cert) + 2},
+		{"trailing", [][]byte{cert, []byte("\nMIIB")}, len(cert) + 1},
+	} {
+		bundle := bytes.Join(tc.bundle, nil)
+		if _, err := ParsePEMs(bundle); err != nil {
+			t.Errorf("%s: garbage should be ignored by default, got %#v", tc.name, err)
+		}
+		_, err := ParsePEMs(bundle, WithErrorOnGarbage())
+		var gerr *GarbageError
+		if !errors.As(err, &gerr) || gerr.Offset != tc.offset || gerr.Line != newScanner(bundle, false).lineOf(tc.offset) {
+			t.Errorf("%s: expected garbage at offset %d, got %#v", tc.name, tc.offset, err)
+		}
+	}
+}
+
+func ExampleOption() {
+	bad := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")})
+	pgp := pem.EncodeToMemory(&pem.Block{Type: "PGP PUBLIC KEY BLOCK", Bytes: []byte{1}})
+	ecKey, _, err := GenerateEC(elliptic.P256())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	key, err := EncodePEM(ecKey, nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	bundle := bytes.Join([][]byte{bad, key, pgp}, nil)
+
+	if _, err := ParsePEMs(bundle); errors.Is(err, ErrBadBlock) {
+		fmt.Println("strict:", err)
+	}
+	pems, err := ParsePEMs(bundle, WithSkipInvalid())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("lenient: %d parsed, %d invalid, %d skipped\n", pems.Length(), len(pems.Invalid()), len(pems.Skipped()))
+	//Output:
+	// strict: block 0 (CERTIFICATE) at line 1, offset 0: x509: malformed certificate
+	// lenient: 1 parsed, 1 invalid, 1 skipped
+}
+
+func TestWithTypes(t *testing.T) {
+	pgp := pem.EncodeToMemory(&pem.Block{Type: "PGP PUBLIC KEY BLOCK", Bytes: []byte{1}})
+	bundle := bytes.Join([][]byte{test_ca, test_rsakey, test_eccert, test_eckey}, nil)
+
+	certs, err := ParsePEMs(bundle, WithTypes("CERTIFICATE"))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if certs.Length() != 2 {
+		t.Errorf("expected only the 2 certificates, got %d objects", certs.Length())
+	}
+	skipped := certs.Skipped()
+	if len(skipped) != 2 || !skipped[0].Filtered || skipped[0].Type != "RSA PRIVATE KEY" {
+		t.Errorf("unexpected skipped blocks %#v", skipped)
+	}
+
+	noRSA, err := ParsePEMs(bundle, WithoutTypes("RSA PRIVATE KEY"), WithoutTypes("EC PRIVATE KEY"))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if noRSA.Length() != 2 {
+		t.Errorf("expected the keys to be left out, got %d objects", noRSA.Length())
+	}
+
+	if _, err := ParsePEMs(bytes.Join([][]byte{test_ca, test_rsakey}, nil), WithTypes("CERTIFICATE"), WithErrorOnUnknown()); err != nil {
+		t.Errorf("filtered blocks should not be unknown, got %#v", err)
+	}
+	if _, err := ParsePEMs(bytes.Join([][]byte{test_ca, pgp}, nil), WithTypes("CERTIFICATE"), WithErrorOnUnknown()); err != nil {
+		t.Errorf("blocks filtered out by WithTypes should not be unknown, got %#v", err)
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	c := newTestChain(t)
+	bundle := c.pem(t, c.leaf, c.intermediate, c.root)
+	later := func() time.Time { return time.Now().Add(48 * time.Hour) }
+	objs, err := ParsePEMs(bundle, WithClock(later))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if kinds := findingKinds(objs.Warnings()); kinds[FindingExpired] != 3 {
+		t.Errorf("expected every certificate to have expired by the clock, got %v", kinds)
+	}
+	if report := objs.ExpiryReport(time.Time{}, 0); !report.Certificates[0].Expired {
+		t.Error("the expiry report did not use the clock")
+	}
+	if kinds := findingKinds(Audit(objs, AuditOptions{})); kinds[FindingExpired] != 3 {
+		t.Errorf("the audit did not use the clock, got %v", kinds)
+	}
+	if _, err := VerifyChain(objs, poolOf(c.root), x509.VerifyOptions{}); err == nil {
+		t.Error("chain verification did not use the clock")
+	}
+	if err := ValidateForHost(objs, "leaf.example.com"); err == nil {
+		t.Error("host validation did not use the clock")
+	}
+	if deduped := objs.Dedupe(false); !deduped.ExpiryReport(time.Time{}, 0).Certificates[0].Expired {
+		t.Error("the clock was not kept by Dedupe")
+	}
+
+	now, err := ParsePEMs(bundle)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if _, err := VerifyChain(now, poolOf(c.root), x509.VerifyOptions{}); err != nil {
+		t.Errorf("unexpected error verifying without a clock %#v", err)
+	}
+}
+
+func TestWithMaxBlocks(t *testing.T) {
+	pgp := pem.EncodeToMemory(&pem.Block{Type: "PGP PUBLIC KEY BLOCK", Bytes: []byte{1}})
+	bundle := bytes.Join([][]byte{test_ca, pgp, test_rsakey}, nil)
+	if _, err := ParsePEMs(bundle, WithMaxBlocks(3)); err != nil {
+		t.Errorf("unexpected error at the limit %#v", err)
+	}
+	_, err := ParsePEMs(bundle, WithMaxBlocks(2))
+	var merr *TooManyBlocksError
+	if !errors.As(err, &merr) || merr.Max != 2 {
+		t.Errorf("expected a *TooManyBlocksError, got %#v", err)
+	}
+	if objs, err := ParsePEMs(bundle, WithMaxBlocks(2), WithPartialResults()); err == nil || objs.Length() != 1 {
+		t.Errorf("expected the certificate before the limit, got %d objects", objs.Length())
+	}
+}
@@ -0,0 +1,63 @@
+package betterpem
+
+import (
+	"encoding/pem"
+	"runtime"
+	"sync"
+)
+
+// Parse blocks on n goroutines at once, for bundles of hundreds of
+// certificates such as an operating system's trust store.  n of zero or
+// less uses one goroutine per CPU.
+//
+// Objects come out in the same order, with the same errors, as they
+// would parsing one block at a time.  Parsers added with WithBlockType
+// or RegisterBlockType, and any PassphraseFunc, must then be safe to call
+// concurrently.
+func WithWorkers(n int) Option {
+	return func(o *parseOptions) {
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		o.workers = n
+	}
+}
+
+// A block found by ParsePEMs and what parsing it gave
+type pendingBlock struct {
+	der   *pem.Block
+	info  BlockInfo
+	parse BlockParser
+	obj   interface{}
+	err   error
+}
+
+// Parse every block, workers at a time
+func parseAll(blocks []*pendingBlock, workers int) {
+	next := make(chan *pendingBlock)
+	var wg sync.WaitGroup
+	for i := 0; i < workers && i < len(blocks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range next {
+				b.obj, b.err = b.parse(b.der)
+			}
+		}()
+	}
+	for _, b := range blocks {
+		next <- b
+	}
+	close(next)
+	wg.Wait()
+}
+
+// The skipped blocks which came before the block at index
+func skippedBefore(skipped []SkippedBlock, index int) []SkippedBlock {
+	for i, b := range skipped {
+		if b.Index > index {
+			return skipped[:i]
+		}
+	}
+	return skipped
+}
@@ -0,0 +1,68 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWithWorkers(t *testing.T) {
+	c := newTestChain(t)
+	blocks := [][]byte{}
+	for i := 0; i < 50; i++ {
+		blocks = append(blocks, c.pem(t, c.root, c.intermediate, c.leaf, c.leafKey))
+	}
+	bundle := bytes.Join(blocks, nil)
+	want, err := ParsePEMs(bundle)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	got, err := ParsePEMs(bundle, WithWorkers(8))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if !reflect.DeepEqual(got.Interface(), want.Interface()) || got.Length() != want.Length() {
+		t.Fatalf("parsing on workers gave different objects")
+	}
+	for got.Length() > 0 {
+		if !reflect.DeepEqual(got.Interface(), want.Interface()) {
+			t.Fatalf("parsing on workers changed the order at %d objects from the end", got.Length())
+		}
+		got.Next()
+		want.Next()
+	}
+}
+
+func TestWithWorkersErrors(t *testing.T) {
+	c := newTestChain(t)
+	bad := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1, 2, 3}})
+	pgp := pem.EncodeToMemory(&pem.Block{Type: "PGP PUBLIC KEY BLOCK", Bytes: []byte{1}})
+	bundle := bytes.Join([][]byte{c.pem(t, c.root), bad, pgp, c.pem(t, c.leaf), bad}, nil)
+
+	_, err := ParsePEMs(bundle, WithWorkers(4))
+	var berr *BlockError
+	if !errors.As(err, &berr) || berr.Index != 1 {
+		t.Fatalf("expected the first bad block to fail, got %#v", err)
+	}
+
+	pems, err := ParsePEMs(bundle, WithWorkers(4), WithPartialResults())
+	if err == nil || pems.Length() != 1 || len(pems.Skipped()) != 0 {
+		t.Errorf("expected only what came before the first bad block, got %d objects and %d skipped", pems.Length(), len(pems.Skipped()))
+	}
+
+	pems, err = ParsePEMs(bundle, WithWorkers(4), WithSkipInvalid())
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if pems.Length() != 2 || len(pems.Invalid()) != 2 || pems.Invalid()[1].Index != 4 || len(pems.Skipped()) != 1 {
+		t.Errorf("unexpected results skipping invalid blocks: %d objects, %d invalid, %d skipped", pems.Length(), len(pems.Invalid()), len(pems.Skipped()))
+	}
+
+	// a scan error after a bad block doesn't hide it
+	_, err = ParsePEMs(append(bytes.Clone(bundle), []byte("-----BEGIN CERTIFICATE-----\nMIIB")...), WithWorkers(4))
+	if !errors.As(err, &berr) || berr.Index != 1 {
+		t.Errorf("expected the first bad block to fail before the truncated one, got %#v", err)
+	}
+}
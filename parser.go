@@ -0,0 +1,71 @@
+package betterpem
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Buffers io.Reader inputs are read into, kept between calls so
+// services parsing many small PEMs don't allocate one each time
+var readBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// Buffers bigger than this are left for the garbage collector rather than
+// kept around after one large input
+const maxPooledBuffer = 1 << 20
+
+// Parses PEM data with a fixed set of options, for services which parse
+// many PEMs, such as webhook receivers.
+//
+// The options are only processed once, and buffers are reused between
+// calls.  A Parser is safe for concurrent use.
+type Parser struct {
+	o *parseOptions
+	// from NewCachingParser, or nil
+	cache *parseCache
+}
+
+// Create a Parser which parses the way ParsePEMs does with the same
+// options
+func NewParser(opts ...Option) *Parser {
+	return &Parser{o: newParseOptions(opts)}
+}
+
+// Parse PEM data exactly like ParsePEMs, with the Parser's options
+func (p *Parser) Parse(pemInt interface{}) (ParsedPEMs, error) {
+	switch v := pemInt.(type) {
+	case []byte:
+		return p.parse(v, p.o.inPlace)
+	case string:
+		pemBytes, _ := intoBytes(v)
+		return p.parse(pemBytes, false)
+	case io.Reader:
+		if p.o.inPlace {
+			// the blocks keep the buffer, so it can't be reused
+			pemBytes, err := intoBytes(v)
+			if err != nil {
+				return ParsedPEMs{}, err
+			}
+			return p.parse(pemBytes, true)
+		}
+		buf := readBuffers.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer func() {
+			if buf.Cap() <= maxPooledBuffer {
+				readBuffers.Put(buf)
+			}
+		}()
+		if _, err := buf.ReadFrom(v); err != nil {
+			return ParsedPEMs{}, err
+		}
+		return p.parse(buf.Bytes(), false)
+	}
+	return ParsedPEMs{}, ErrPemUnderlyingFormatError
+}
+
+func (p *Parser) parse(pemBytes []byte, inPlace bool) (ParsedPEMs, error) {
+	if p.cache == nil {
+		return p.o.parse(pemBytes, inPlace)
+	}
+	return p.cache.parse(pemBytes, p.o)
+}
@@ -0,0 +1,62 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/x509"
+	"sync"
+	"testing"
+)
+
+func TestParser(t *testing.T) {
+	c := newTestChain(t)
+	p := NewParser(WithTypes("CERTIFICATE"))
+	parsed := []*x509.Certificate{}
+	for _, cert := range []*x509.Certificate{c.root, c.intermediate, c.leaf} {
+		// each reader reuses the buffer the last one was read into
+		pems, err := p.Parse(bytes.NewReader(c.pem(t, cert, c.leafKey)))
+		if err != nil {
+			t.Fatalf("unexpected error parsing pem %#v", err)
+		}
+		if pems.Length() != 1 {
+			t.Fatalf("expected the key to be filtered out, got %d objects", pems.Length())
+		}
+		parsed = append(parsed, pems.MustCertificate())
+	}
+	for i, cert := range []*x509.Certificate{c.root, c.intermediate, c.leaf} {
+		if !parsed[i].Equal(cert) {
+			t.Errorf("certificate %d changed when the buffer was reused", i)
+		}
+	}
+
+	if _, err := p.Parse(42); err != ErrPemUnderlyingFormatError {
+		t.Errorf("expected ErrPemUnderlyingFormatError, got %#v", err)
+	}
+}
+
+func TestParserConcurrent(t *testing.T) {
+	p := NewParser()
+	want, err := ParsePEMs(test_rsacert)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	cert := want.MustCertificate()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				pems, err := p.Parse(bytes.NewReader(test_rsacert))
+				if err != nil {
+					t.Errorf("unexpected error parsing pem %#v", err)
+					return
+				}
+				if !pems.MustCertificate().Equal(cert) {
+					t.Errorf("parsed the wrong certificate")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
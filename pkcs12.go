@@ -0,0 +1,50 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/x509"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// Encode a private key, its certificate, and any intermediates as a
+// PKCS#12 (.pfx/.p12) file.
+//
+// The result is encrypted with password using modern algorithms, which
+// current Windows and Java releases both accept.
+func EncodePKCS12(key crypto.PrivateKey, leaf *x509.Certificate, chain []*x509.Certificate, password string) ([]byte, error) {
+	return pkcs12.Modern.Encode(key, leaf, chain, password)
+}
+
+// Decode a PKCS#12 (.pfx/.p12) file into the same shape ParsePEMs gives
+// for PEM: the private key, then its certificate, then the rest of the
+// chain.
+//
+// A file holding only trusted certificates, such as one written by
+// Java's keytool, gives just the certificates.  A wrong password gives
+// pkcs12.ErrIncorrectPassword.  Nothing in the result came from a PEM
+// block, so Encode writes each object in its usual block and Headers is
+// empty.
+func ParsePKCS12(data []byte, password string) (ParsedPEMs, error) {
+	ret := ParsedPEMs{}
+	key, leaf, chain, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		if err == pkcs12.ErrIncorrectPassword {
+			return ParsedPEMs{}, err
+		}
+		certs, terr := pkcs12.DecodeTrustStore(data, password)
+		if terr != nil {
+			return ParsedPEMs{}, err
+		}
+		for _, cert := range certs {
+			ret.add(cert, nil)
+		}
+		return ret, nil
+	}
+	ret.add(key, nil)
+	ret.add(leaf, nil)
+	for _, cert := range chain {
+		ret.add(cert, nil)
+	}
+	return ret, nil
+}
@@ -0,0 +1,76 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/x509"
+	"testing"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestEncodePKCS12(t *testing.T) {
+	objs, err := ParsePEMs(bytes.Join([][]byte{test_rsacert, test_rsakey, test_ca}, []byte{'\n'}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	leaf := objs.MustCertificate()
+	key := objs.MustRSAPrivateKey()
+	ca := objs.MustCertificate()
+
+	pfx, err := EncodePKCS12(key, leaf, []*x509.Certificate{ca}, "hunter2")
+	if err != nil {
+		t.Fatalf("unexpected error encoding pkcs12 %#v", err)
+	}
+	dkey, dleaf, dchain, err := pkcs12.DecodeChain(pfx, "hunter2")
+	if err != nil {
+		t.Fatalf("could not decode our own pkcs12: %v", err)
+	}
+	if !key.Equal(dkey) {
+		t.Error("key did not survive the round trip")
+	}
+	if !leaf.Equal(dleaf) {
+		t.Error("leaf did not survive the round trip")
+	}
+	if len(dchain) != 1 || !ca.Equal(dchain[0]) {
+		t.Error("chain did not survive the round trip")
+	}
+}
+
+func TestParsePKCS12(t *testing.T) {
+	c := newTestChain(t)
+	pfx, err := EncodePKCS12(c.leafKey, c.leaf, []*x509.Certificate{c.intermediate, c.root}, "hunter2")
+	if err != nil {
+		t.Fatalf("unexpected error encoding pkcs12 %#v", err)
+	}
+	pems, err := ParsePKCS12(pfx, "hunter2")
+	if err != nil {
+		t.Fatalf("unexpected error parsing pkcs12 %#v", err)
+	}
+	if pems.Length() != 4 {
+		t.Fatalf("expected a key and 3 certificates, got %d objects", pems.Length())
+	}
+	if !pems.MustECPrivateKey().Equal(c.leafKey) {
+		t.Error("expected the key first")
+	}
+	for _, want := range []*x509.Certificate{c.leaf, c.intermediate, c.root} {
+		if !pems.MustCertificate().Equal(want) {
+			t.Errorf("expected %s next", want.Subject)
+		}
+	}
+
+	if _, err := ParsePKCS12(pfx, "wrong"); err != pkcs12.ErrIncorrectPassword {
+		t.Errorf("expected pkcs12.ErrIncorrectPassword, got %#v", err)
+	}
+
+	store, err := pkcs12.Modern.EncodeTrustStore([]*x509.Certificate{c.root, c.intermediate}, "changeit")
+	if err != nil {
+		t.Fatalf("unexpected error encoding trust store %#v", err)
+	}
+	pems, err = ParsePKCS12(store, "changeit")
+	if err != nil {
+		t.Fatalf("unexpected error parsing trust store %#v", err)
+	}
+	if pems.Length() != 2 || !pems.MustCertificate().Equal(c.root) {
+		t.Errorf("expected the trusted certificates in order")
+	}
+}
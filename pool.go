@@ -0,0 +1,84 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"errors"
+)
+
+var ErrNoCertificates = errors.New("no certificates were found")
+
+// Add every certificate remaining to be consumed to pool.
+//
+// Unlike x509.CertPool.AppendCertsFromPEM, a bundle with no certificates
+// is an error rather than a false, and bad blocks have already been
+// reported by ParsePEMs.  The parsed PEMs are not consumed.
+func (p *ParsedPEMs) AppendTo(pool *x509.CertPool) error {
+	certs := p.certificates()
+	if len(certs) == 0 {
+		return ErrNoCertificates
+	}
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return nil
+}
+
+// Build a new x509.CertPool holding every certificate remaining to be
+// consumed.
+//
+// See AppendTo.
+func (p *ParsedPEMs) CertPool() (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if err := p.AppendTo(pool); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Split the certificates remaining to be consumed into the two pools
+// x509.VerifyOptions wants.
+//
+// Self-signed CA certificates go in roots and other CA certificates in
+// intermediates; see Partition.  Leaves go in neither.  Either pool may
+// be empty, but neither is nil.  The parsed PEMs are not consumed.
+func (p *ParsedPEMs) Pools() (roots *x509.CertPool, intermediates *x509.CertPool) {
+	_, inters, rs := p.Partition()
+	roots = x509.NewCertPool()
+	for _, c := range rs {
+		roots.AddCert(c)
+	}
+	intermediates = x509.NewCertPool()
+	for _, c := range inters {
+		intermediates.AddCert(c)
+	}
+	return roots, intermediates
+}
+
+// Load the system's root pool and add every certificate remaining to be
+// consumed to it, for clients which trust an internal CA as well as the
+// usual public ones.
+//
+// If excludeExpired is true, certificates which have expired are left
+// out.  It is an error for no certificates to be left to add, and for
+// the system pool to be unavailable.  The parsed PEMs are not consumed.
+func SystemPoolPlus(pems ParsedPEMs, excludeExpired bool) (*x509.CertPool, error) {
+	now := pems.now()
+	certs := []*x509.Certificate{}
+	for _, c := range pems.certificates() {
+		if excludeExpired && now.After(c.NotAfter) {
+			continue
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, ErrNoCertificates
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return pool, nil
+}
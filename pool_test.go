@@ -0,0 +1,94 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+)
+
+func TestCertPool(t *testing.T) {
+	objs, err := ParsePEMs(bytes.Join([][]byte{test_ca, test_rsakey}, []byte{'\n'}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	pool, err := objs.CertPool()
+	if err != nil {
+		t.Fatalf("unexpected error building pool %#v", err)
+	}
+	certs, err := ParsePEMs(test_rsacert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := certs.MustCertificate().Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+		t.Errorf("certificate does not verify against the pool: %v", err)
+	}
+
+	keys, err := ParsePEMs(test_rsakey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AppendTo(x509.NewCertPool()); err != ErrNoCertificates {
+		t.Errorf("expected ErrNoCertificates, got %#v", err)
+	}
+}
+
+func TestPools(t *testing.T) {
+	c := newTestChain(t)
+	objs, err := ParsePEMs(c.pem(t, c.intermediate, c.root, c.leaf))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	roots, intermediates := objs.Pools()
+	if !roots.Equal(poolOf(c.root)) || !intermediates.Equal(poolOf(c.intermediate)) {
+		t.Error("pools are not split by self-signedness")
+	}
+	if _, err := c.leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates}); err != nil {
+		t.Errorf("leaf does not verify against the pools: %v", err)
+	}
+}
+
+func poolOf(certs ...*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return pool
+}
+
+func TestSystemPoolPlus(t *testing.T) {
+	c := newTestChain(t)
+	expired, _ := issueTestCert(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "expired root"},
+		NotBefore: time.Now().Add(-48 * time.Hour),
+		NotAfter:  time.Now().Add(-24 * time.Hour),
+	}, nil, nil)
+	if _, err := x509.SystemCertPool(); err != nil {
+		t.Skipf("no system pool to test with: %v", err)
+	}
+
+	objs, err := ParsePEMs(c.pem(t, c.root, expired))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	pool, err := SystemPoolPlus(objs, true)
+	if err != nil {
+		t.Fatalf("unexpected error building pool %#v", err)
+	}
+	intermediates := poolOf(c.intermediate)
+	if _, err := c.leaf.Verify(x509.VerifyOptions{Roots: pool, Intermediates: intermediates}); err != nil {
+		t.Errorf("leaf does not verify against the augmented pool: %v", err)
+	}
+
+	stale, err := ParsePEMs(c.pem(t, expired))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if _, err := SystemPoolPlus(stale, true); err != ErrNoCertificates {
+		t.Errorf("expected ErrNoCertificates, got %#v", err)
+	}
+	if _, err := SystemPoolPlus(stale, false); err != nil {
+		t.Errorf("unexpected error keeping expired certificates %#v", err)
+	}
+}
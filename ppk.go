@@ -0,0 +1,288 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/ssh"
+)
+
+var ErrInvalidPPK = errors.New("invalid PuTTY private key file")
+var ErrUnsupportedPPK = errors.New("PuTTY private key file uses an unsupported format, key type, or encryption")
+
+// The most Argon2 work a PPK file may ask for, so a hostile file can't
+// exhaust memory or CPU.  PuTTYgen writes 8 MiB and tunes the passes to
+// take a fraction of a second, far below these.
+const (
+	// in KiB, as Argon2-Memory is: 1 GiB
+	maxPPKArgon2Memory = 1 << 20
+	maxPPKArgon2Passes = 1000
+)
+
+// The fields of a PPK file, by name
+type ppkFile struct {
+	version int
+	fields  map[string]string
+	public  []byte
+	private []byte
+}
+
+// Split a PPK file into its "Name: value" fields and base64 sections
+func readPPK(data []byte) (*ppkFile, error) {
+	f := &ppkFile{fields: map[string]string{}}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		name, value, ok := strings.Cut(lines[i], ": ")
+		if !ok {
+			if strings.TrimSpace(lines[i]) == "" {
+				continue
+			}
+			return nil, ErrInvalidPPK
+		}
+		if i == 0 {
+			v, ok := strings.CutPrefix(name, "PuTTY-User-Key-File-")
+			if !ok {
+				return nil, ErrInvalidPPK
+			}
+			if v != "2" && v != "3" {
+				return nil, ErrUnsupportedPPK
+			}
+			f.version, _ = strconv.Atoi(v)
+			name = "Algorithm"
+		}
+		f.fields[name] = value
+		if name != "Public-Lines" && name != "Private-Lines" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || i+n >= len(lines) {
+			return nil, ErrInvalidPPK
+		}
+		b, err := base64.StdEncoding.DecodeString(strings.Join(lines[i+1:i+1+n], ""))
+		if err != nil {
+			return nil, ErrInvalidPPK
+		}
+		if name == "Public-Lines" {
+			f.public = b
+		} else {
+			f.private = b
+		}
+		i += n
+	}
+	if f.version == 0 || f.public == nil || f.private == nil {
+		return nil, ErrInvalidPPK
+	}
+	return f, nil
+}
+
+// The keys protecting a PPK file: the AES-256 key and IV, and the key for
+// its MAC
+func (f *ppkFile) keys(passphrase string) (key, iv []byte, mac hash.Hash, err error) {
+	encrypted := f.fields["Encryption"] != "none"
+	if !encrypted {
+		passphrase = ""
+	}
+	if f.version == 2 {
+		macKey := sha1.Sum([]byte("putty-private-key-file-mac-key" + passphrase))
+		a := sha1.Sum(append([]byte{0, 0, 0, 0}, passphrase...))
+		b := sha1.Sum(append([]byte{0, 0, 0, 1}, passphrase...))
+		return append(a[:], b[:12]...), make([]byte, aes.BlockSize), hmac.New(sha1.New, macKey[:]), nil
+	}
+	if !encrypted {
+		return nil, nil, hmac.New(sha256.New, nil), nil
+	}
+	atoi := func(name string) (uint32, error) {
+		n, err := strconv.ParseUint(f.fields[name], 10, 32)
+		if err != nil {
+			return 0, ErrInvalidPPK
+		}
+		return uint32(n), nil
+	}
+	memory, err := atoi("Argon2-Memory")
+	if err != nil || memory == 0 || memory > maxPPKArgon2Memory {
+		return nil, nil, nil, ErrInvalidPPK
+	}
+	passes, err := atoi("Argon2-Passes")
+	if err != nil || passes == 0 || passes > maxPPKArgon2Passes {
+		return nil, nil, nil, ErrInvalidPPK
+	}
+	parallelism, err := atoi("Argon2-Parallelism")
+	if err != nil || parallelism == 0 || parallelism > 255 {
+		return nil, nil, nil, ErrInvalidPPK
+	}
+	salt, err := hex.DecodeString(f.fields["Argon2-Salt"])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidPPK
+	}
+	var derived []byte
+	switch f.fields["Key-Derivation"] {
+	case "Argon2id":
+		derived = argon2.IDKey([]byte(passphrase), salt, passes, memory, uint8(parallelism), 80)
+	case "Argon2i":
+		derived = argon2.Key([]byte(passphrase), salt, passes, memory, uint8(parallelism), 80)
+	default:
+		// x/crypto has no Argon2d
+		return nil, nil, nil, ErrUnsupportedPPK
+	}
+	return derived[:32], derived[32:48], hmac.New(sha256.New, derived[48:]), nil
+}
+
+// Build the key from a PPK file's public and private sections
+func ppkKey(algorithm string, public, private []byte) (interface{}, error) {
+	switch algorithm {
+	case ssh.KeyAlgoRSA:
+		var pub struct {
+			Alg  string
+			E, N *big.Int
+		}
+		var priv struct {
+			D, P, Q, Iqmp *big.Int
+			Rest          []byte `ssh:"rest"`
+		}
+		if ssh.Unmarshal(public, &pub) != nil || ssh.Unmarshal(private, &priv) != nil || pub.Alg != algorithm || !pub.E.IsInt64() {
+			return nil, ErrInvalidPPK
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: pub.N, E: int(pub.E.Int64())},
+			D:         priv.D,
+			Primes:    []*big.Int{priv.P, priv.Q},
+		}
+		if key.Validate() != nil {
+			return nil, ErrInvalidPPK
+		}
+		key.Precompute()
+		return key, nil
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		curve := map[string]elliptic.Curve{
+			ssh.KeyAlgoECDSA256: elliptic.P256(),
+			ssh.KeyAlgoECDSA384: elliptic.P384(),
+			ssh.KeyAlgoECDSA521: elliptic.P521(),
+		}[algorithm]
+		pub, err := ssh.ParsePublicKey(public)
+		if err != nil || pub.Type() != algorithm {
+			return nil, ErrInvalidPPK
+		}
+		ecPub, ok := pub.(ssh.CryptoPublicKey).CryptoPublicKey().(*ecdsa.PublicKey)
+		if !ok {
+			return nil, ErrInvalidPPK
+		}
+		var priv struct {
+			D    *big.Int
+			Rest []byte `ssh:"rest"`
+		}
+		if ssh.Unmarshal(private, &priv) != nil {
+			return nil, ErrInvalidPPK
+		}
+		key := &ecdsa.PrivateKey{PublicKey: *ecPub, D: priv.D}
+		// make sure D is the private half of the public key
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, ErrInvalidPPK
+		}
+		check, err := x509.ParseECPrivateKey(der)
+		if err != nil || !check.PublicKey.Equal(&key.PublicKey) || key.Curve != curve {
+			return nil, ErrInvalidPPK
+		}
+		return check, nil
+	case ssh.KeyAlgoED25519:
+		var pub struct {
+			Alg string
+			Key []byte
+		}
+		var priv struct {
+			Seed []byte
+			Rest []byte `ssh:"rest"`
+		}
+		if ssh.Unmarshal(public, &pub) != nil || ssh.Unmarshal(private, &priv) != nil || pub.Alg != algorithm || len(priv.Seed) != ed25519.SeedSize {
+			return nil, ErrInvalidPPK
+		}
+		key := ed25519.NewKeyFromSeed(priv.Seed)
+		if !bytes.Equal(key.Public().(ed25519.PublicKey), pub.Key) {
+			return nil, ErrInvalidPPK
+		}
+		return key, nil
+	}
+	return nil, ErrUnsupportedPPK
+}
+
+// Convert a PuTTY private key (.ppk) file in format 2 or 3, as written
+// by PuTTYgen, into the objects ParsePEMs gives, so it can be encoded as
+// PKCS#8 or with EncodeOpenSSHPrivateKey.
+//
+// RSA, ECDSA, and Ed25519 keys are supported.  The passphrase is ignored
+// for unencrypted files; for encrypted ones, a wrong passphrase gives
+// x509.IncorrectPasswordError.  The key's comment is its "Comment"
+// header.
+func ParsePPK(data []byte, passphrase string) (ParsedPEMs, error) {
+	f, err := readPPK(data)
+	if err != nil {
+		return ParsedPEMs{}, err
+	}
+	encryption := f.fields["Encryption"]
+	if encryption != "none" && encryption != "aes256-cbc" {
+		return ParsedPEMs{}, ErrUnsupportedPPK
+	}
+	key, iv, mac, err := f.keys(passphrase)
+	if err != nil {
+		return ParsedPEMs{}, err
+	}
+	private := f.private
+	if encryption != "none" {
+		if len(private)%aes.BlockSize != 0 {
+			return ParsedPEMs{}, ErrInvalidPPK
+		}
+		block, _ := aes.NewCipher(key)
+		private = make([]byte, len(f.private))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(private, f.private)
+	}
+
+	for _, s := range []string{f.fields["Algorithm"], encryption, f.fields["Comment"], string(f.public), string(private)} {
+		mac.Write(binary.BigEndian.AppendUint32(nil, uint32(len(s))))
+		mac.Write([]byte(s))
+	}
+	want, err := hex.DecodeString(f.fields["Private-MAC"])
+	if err != nil {
+		return ParsedPEMs{}, ErrInvalidPPK
+	}
+	if !hmac.Equal(mac.Sum(nil), want) {
+		if encryption != "none" {
+			return ParsedPEMs{}, x509.IncorrectPasswordError
+		}
+		return ParsedPEMs{}, ErrInvalidPPK
+	}
+
+	obj, err := ppkKey(f.fields["Algorithm"], f.public, private)
+	if err != nil {
+		return ParsedPEMs{}, err
+	}
+	ret := ParsedPEMs{}
+	block, err := blockFor(obj)
+	if err != nil {
+		return ParsedPEMs{}, err
+	}
+	if comment := f.fields["Comment"]; comment != "" {
+		block.Headers = map[string]string{"Comment": comment}
+		ret.add(obj, block)
+	} else {
+		ret.add(obj, nil)
+	}
+	return ret, nil
+}
@@ -0,0 +1,174 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/ssh"
+)
+
+// Write a PPK file the way PuTTYgen would
+func encodeTestPPK(t *testing.T, version int, key crypto.Signer, comment, passphrase string) []byte {
+	return encodeTestPPKAs(t, version, "", key, comment, passphrase)
+}
+
+// Write a PPK file claiming to hold a key of the given algorithm, or the
+// key's own if it is empty, with a valid MAC
+func encodeTestPPKAs(t *testing.T, version int, algorithm string, key crypto.Signer, comment, passphrase string) []byte {
+	pub, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if algorithm == "" {
+		algorithm = pub.Type()
+	}
+	var private []byte
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		private = ssh.Marshal(struct{ Seed []byte }{k.Seed()})
+	case *ecdsa.PrivateKey:
+		private = ssh.Marshal(struct{ D *big.Int }{k.D})
+	case *rsa.PrivateKey:
+		iqmp := new(big.Int).ModInverse(k.Primes[1], k.Primes[0])
+		private = ssh.Marshal(struct{ D, P, Q, Iqmp *big.Int }{k.D, k.Primes[0], k.Primes[1], iqmp})
+	}
+	encryption := "none"
+	var lines []string
+	var cipherKey, iv []byte
+	var mac hash.Hash
+	macPass := ""
+	if passphrase != "" {
+		encryption = "aes256-cbc"
+		macPass = passphrase
+		private = append(private, make([]byte, aes.BlockSize-len(private)%aes.BlockSize)...)
+	}
+	header := []string{
+		fmt.Sprintf("PuTTY-User-Key-File-%d: %s", version, algorithm),
+		"Encryption: " + encryption,
+		"Comment: " + comment,
+	}
+	if version == 2 {
+		a := sha1.Sum(append([]byte{0, 0, 0, 0}, passphrase...))
+		b := sha1.Sum(append([]byte{0, 0, 0, 1}, passphrase...))
+		cipherKey, iv = append(a[:], b[:12]...), make([]byte, aes.BlockSize)
+		macKey := sha1.Sum([]byte("putty-private-key-file-mac-key" + macPass))
+		mac = hmac.New(sha1.New, macKey[:])
+	} else if passphrase == "" {
+		mac = hmac.New(sha256.New, nil)
+	} else {
+		salt := []byte("0123456789abcdef")
+		derived := argon2.IDKey([]byte(passphrase), salt, 2, 64, 1, 80)
+		cipherKey, iv = derived[:32], derived[32:48]
+		mac = hmac.New(sha256.New, derived[48:])
+		lines = []string{"Key-Derivation: Argon2id", "Argon2-Memory: 64", "Argon2-Passes: 2", "Argon2-Parallelism: 1", "Argon2-Salt: " + hex.EncodeToString(salt)}
+	}
+	for _, s := range []string{algorithm, encryption, comment, string(pub.Marshal()), string(private)} {
+		mac.Write(binary.BigEndian.AppendUint32(nil, uint32(len(s))))
+		mac.Write([]byte(s))
+	}
+	if passphrase != "" {
+		block, _ := aes.NewCipher(cipherKey)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(private, private)
+	}
+	section := func(name string, b []byte) []string {
+		s := base64.StdEncoding.EncodeToString(b)
+		ret := []string{}
+		for len(s) > 64 {
+			ret, s = append(ret, s[:64]), s[64:]
+		}
+		ret = append(ret, s)
+		return append([]string{fmt.Sprintf("%s-Lines: %d", name, len(ret))}, ret...)
+	}
+	out := append(header, section("Public", pub.Marshal())...)
+	out = append(out, lines...)
+	out = append(out, section("Private", private)...)
+	out = append(out, "Private-MAC: "+hex.EncodeToString(mac.Sum(nil)))
+	return []byte(strings.Join(out, "\r\n") + "\r\n")
+}
+
+func TestParsePPK(t *testing.T) {
+	pems, err := ParsePEMs(append(append([]byte{}, test_rsakey...), test_eckey...))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	rsaKey := pems.MustRSAPrivateKey()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey := pems.MustECPrivateKey()
+	for _, key := range []crypto.Signer{rsaKey, ecKey, edKey} {
+		for _, version := range []int{2, 3} {
+			for _, passphrase := range []string{"", "hunter2"} {
+				name := fmt.Sprintf("%T v%d %q", key, version, passphrase)
+				ppk := encodeTestPPK(t, version, key, "my key", passphrase)
+				pems, err := ParsePPK(ppk, passphrase)
+				if err != nil {
+					t.Errorf("%s: unexpected error parsing ppk %#v", name, err)
+					continue
+				}
+				if pems.Headers()["Comment"] != "my key" {
+					t.Errorf("%s: expected the comment as a header, got %v", name, pems.Headers())
+				}
+				obj, _ := pems.Next()
+				if !certMatchesKey(&x509.Certificate{PublicKey: key.Public()}, obj) {
+					t.Errorf("%s: parsed a different key", name)
+				}
+				if passphrase != "" {
+					if _, err := ParsePPK(ppk, "wrong"); err != x509.IncorrectPasswordError {
+						t.Errorf("%s: expected x509.IncorrectPasswordError, got %#v", name, err)
+					}
+				}
+			}
+		}
+	}
+
+	tampered := strings.Replace(string(encodeTestPPK(t, 3, edKey, "my key", "")), "my key", "my kez", 1)
+	if _, err := ParsePPK([]byte(tampered), ""); err != ErrInvalidPPK {
+		t.Errorf("expected ErrInvalidPPK for a file which fails its MAC, got %#v", err)
+	}
+	// a valid MAC is easy to forge for unencrypted files
+	for _, forged := range []struct {
+		algorithm string
+		key       crypto.Signer
+	}{
+		{ssh.KeyAlgoECDSA256, rsaKey},
+		{ssh.KeyAlgoECDSA384, ecKey},
+		{ssh.KeyAlgoRSA, edKey},
+		{ssh.KeyAlgoED25519, ecKey},
+	} {
+		ppk := encodeTestPPKAs(t, 3, forged.algorithm, forged.key, "", "")
+		if _, err := ParsePPK(ppk, ""); err != ErrInvalidPPK {
+			t.Errorf("expected ErrInvalidPPK for a %T claiming to be %s, got %#v", forged.key, forged.algorithm, err)
+		}
+	}
+	greedy := strings.Replace(string(encodeTestPPK(t, 3, edKey, "", "hunter2")), "Argon2-Memory: 64", "Argon2-Memory: 4294967295", 1)
+	if _, err := ParsePPK([]byte(greedy), "hunter2"); err != ErrInvalidPPK {
+		t.Errorf("expected ErrInvalidPPK for a file asking for 4 TiB, got %#v", err)
+	}
+	slow := strings.Replace(string(encodeTestPPK(t, 3, edKey, "", "hunter2")), "Argon2-Passes: 2", "Argon2-Passes: 4294967295", 1)
+	if _, err := ParsePPK([]byte(slow), "hunter2"); err != ErrInvalidPPK {
+		t.Errorf("expected ErrInvalidPPK for a file asking for 2^32 passes, got %#v", err)
+	}
+	if _, err := ParsePPK([]byte("PuTTY-User-Key-File-1: ssh-rsa\n"), ""); err != ErrUnsupportedPPK {
+		t.Errorf("expected ErrUnsupportedPPK for a version 1 file, got %#v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/pem"
+	"strings"
+)
+
+func isPrivateKey(obj interface{}) bool {
+	switch obj.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey, *ed25519.PrivateKey, *ecdh.PrivateKey:
+		return true
+	}
+	return false
+}
+
+// Whether a block holds a private key, by its type, such as "EC PRIVATE
+// KEY", "ENCRYPTED PRIVATE KEY", or "PGP PRIVATE KEY BLOCK"
+func isPrivateKeyBlock(block *pem.Block) bool {
+	return block != nil && strings.Contains(block.Type, "PRIVATE KEY")
+}
+
+// Return a copy of the parsed PEMs remaining to be consumed with every
+// private key removed.
+//
+// Objects from blocks of private key types, such as OpenPGP secret keys
+// from a registered parser, are removed too.  Certificates and anything
+// else which isn't a private key are kept in their original order.  The
+// parsed PEMs are not consumed.
+func (p *ParsedPEMs) Redacted() ParsedPEMs {
+	ret := ParsedPEMs{clock: p.clock}
+	v := p.parsed()
+	for i, obj := range v.objs {
+		if !isPrivateKey(obj) && !isPrivateKeyBlock(v.blocks[i]) {
+			ret.add(obj, v.blocks[i])
+		}
+	}
+	return ret
+}
+
+// Remove private key material from PEM data so it can be shared, such as
+// in a bug report.
+//
+// Every block whose type contains "PRIVATE KEY" is removed, including
+// encrypted, OpenSSH, and OpenPGP keys which betterpem can't otherwise
+// parse.  If placeholders is true, each removed block is replaced by an
+// empty "REDACTED PRIVATE KEY" block with an Original-Type header
+// instead.  All other blocks are kept as they are, in order.
+func RedactPEM(pemInt interface{}, placeholders bool) ([]byte, error) {
+	input, err := intoBytes(pemInt)
+	if err != nil {
+		return nil, err
+	}
+	blocks := []*pem.Block{}
+	scan := newScanner(input, false)
+	for {
+		found, ok := scan.next()
+		if !ok {
+			break
+		}
+		block := found.Block
+		if !isPrivateKeyBlock(block) {
+			blocks = append(blocks, block)
+		} else if placeholders {
+			blocks = append(blocks, &pem.Block{
+				Type:    "REDACTED PRIVATE KEY",
+				Headers: map[string]string{"Original-Type": block.Type},
+			})
+		}
+	}
+	return encodeBlocks(blocks, nil)
+}
+
+// Return a copy of the parsed PEMs remaining to be consumed with every
+// private key replaced by its public key.
+//
+// Certificates and anything else which isn't a private key are kept in
+// their original order.  Encoding the result gives PUBLIC KEY blocks in
+// place of the private keys, which ParsePEMs reads back as public keys.  Objects from other blocks of private key
+// types, whose public keys can't be found, are removed.  The parsed PEMs
+// are not consumed.
+func (p *ParsedPEMs) PublicBundle() ParsedPEMs {
+	ret := ParsedPEMs{clock: p.clock}
+	v := p.parsed()
+	for i, obj := range v.objs {
+		if !isPrivateKey(obj) && isPrivateKeyBlock(v.blocks[i]) {
+			continue
+		}
+		if !isPrivateKey(obj) {
+			ret.add(obj, v.blocks[i])
+			continue
+		}
+		if k, ok := obj.(*ecdh.PrivateKey); ok {
+			ret.add(k.PublicKey(), nil)
+		} else {
+			ret.add(obj.(crypto.Signer).Public(), nil)
+		}
+	}
+	return ret
+}
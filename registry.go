@@ -0,0 +1,61 @@
+package betterpem
+
+import (
+	"encoding/pem"
+	"sync"
+)
+
+// Parses the contents of a PEM block into an object
+type BlockParser func(block *pem.Block) (interface{}, error)
+
+var registry = struct {
+	sync.RWMutex
+	parsers map[string]BlockParser
+}{parsers: map[string]BlockParser{}}
+
+// Teach ParsePEMs to parse blocks with a type label, such as an
+// application's own "MY APP TOKEN" blocks, for every call.
+//
+// The objects parse returns come back through Interface and Next like
+// any other.  A registered parser replaces the built in one for its type,
+// and registering nil removes it again.  See WithBlockType to register a
+// parser for a single call.
+func RegisterBlockType(typeLabel string, parse BlockParser) {
+	registry.Lock()
+	defer registry.Unlock()
+	if parse == nil {
+		delete(registry.parsers, typeLabel)
+		return
+	}
+	registry.parsers[typeLabel] = parse
+}
+
+func registeredParser(typeLabel string) BlockParser {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.parsers[typeLabel]
+}
+
+// Parse blocks with a type label using parse for this call, in preference
+// to anything registered with RegisterBlockType and the built in parsers.
+func WithBlockType(typeLabel string, parse BlockParser) Option {
+	return func(o *parseOptions) {
+		if o.parsers == nil {
+			o.parsers = map[string]BlockParser{}
+		}
+		o.parsers[typeLabel] = parse
+	}
+}
+
+// Find the parser for a type label: the one passed to WithBlockType,
+// else the one registered with RegisterBlockType, else the built in one.
+// It is nil when there is none.
+func (o *parseOptions) plainParserFor(typeLabel string) BlockParser {
+	if parse := o.parsers[typeLabel]; parse != nil {
+		return parse
+	}
+	if parse := registeredParser(typeLabel); parse != nil {
+		return parse
+	}
+	return builtinParsers[typeLabel]
+}
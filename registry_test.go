@@ -0,0 +1,69 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+type testToken struct {
+	value string
+}
+
+func parseTestToken(block *pem.Block) (interface{}, error) {
+	if len(block.Bytes) == 0 {
+		return nil, errors.New("empty token")
+	}
+	return &testToken{value: string(block.Bytes)}, nil
+}
+
+func TestRegisterBlockType(t *testing.T) {
+	token := pem.EncodeToMemory(&pem.Block{Type: "TEST APP TOKEN", Bytes: []byte("hello")})
+	bundle := bytes.Join([][]byte{test_ca, token}, nil)
+	if objs, err := ParsePEMs(bundle); err != nil || objs.Length() != 1 {
+		t.Fatalf("unregistered blocks should be skipped, got %#v", err)
+	}
+
+	RegisterBlockType("TEST APP TOKEN", parseTestToken)
+	defer RegisterBlockType("TEST APP TOKEN", nil)
+	objs, err := ParsePEMs(bundle)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	objs.MustCertificate()
+	if tok, ok := objs.Interface().(*testToken); !ok || tok.value != "hello" {
+		t.Errorf("unexpected token %#v", tok)
+	}
+
+	empty := pem.EncodeToMemory(&pem.Block{Type: "TEST APP TOKEN"})
+	if _, err := ParsePEMs(empty); !errors.Is(err, ErrBadBlock) {
+		t.Errorf("expected the parser's error as a bad block, got %#v", err)
+	}
+}
+
+func TestWithBlockType(t *testing.T) {
+	token := pem.EncodeToMemory(&pem.Block{Type: "TEST APP TOKEN", Bytes: []byte("hello")})
+	RegisterBlockType("TEST APP TOKEN", func(block *pem.Block) (interface{}, error) {
+		return "global", nil
+	})
+	defer RegisterBlockType("TEST APP TOKEN", nil)
+	objs, err := ParsePEMs(token, WithBlockType("TEST APP TOKEN", parseTestToken))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if _, ok := objs.Interface().(*testToken); !ok {
+		t.Error("the per-call parser should win over the global one")
+	}
+
+	// replacing a built in parser
+	objs, err = ParsePEMs(test_ca, WithBlockType("CERTIFICATE", func(block *pem.Block) (interface{}, error) {
+		return len(block.Bytes), nil
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if n, ok := objs.Interface().(int); !ok || n == 0 {
+		t.Errorf("the built in parser was not replaced, got %#v", n)
+	}
+}
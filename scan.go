@@ -0,0 +1,292 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/pem"
+)
+
+var endMarker = []byte("-----END ")
+var pemEndOfLine = []byte("-----")
+
+// Decode base64 in place so parsed blocks share the memory of a []byte
+// input instead of each being decoded into a copy.
+//
+// The input is overwritten as it's decoded and can't be used afterwards,
+// and any block kept keeps the whole input from being freed.  String
+// inputs are never decoded in place.  io.Reader inputs are read into a
+// private buffer, which is reused by later calls unless it was decoded
+// in place.
+func WithInPlaceDecoding() Option {
+	return func(o *parseOptions) {
+		o.inPlace = true
+	}
+}
+
+// Split off the first \n or \r\n terminated line with its trailing spaces
+// and tabs removed, the way pem.Decode does
+func pemLine(data []byte) (line, rest []byte, consumed int) {
+	i := bytes.IndexByte(data, '\n')
+	j := i + 1
+	if i < 0 {
+		i = len(data)
+		j = i
+	} else if i > 0 && data[i-1] == '\r' {
+		i--
+	}
+	return bytes.TrimRight(data[:i], " \t"), data[j:], j
+}
+
+// Decode the base64 body of a block, skipping whitespace like pem.Decode.
+//
+// If inPlace is true the result is written over body itself.  Each
+// quantum of four characters decodes to at most three bytes, so what's
+// written never catches up with what's still to be read.
+func decodeBody(body []byte, inPlace bool) ([]byte, error) {
+	if !inPlace && !bytes.ContainsAny(body, " \t") {
+		// the base64 decoder already skips newlines
+		dst := make([]byte, base64.StdEncoding.DecodedLen(len(body)))
+		n, err := base64.StdEncoding.Decode(dst, body)
+		return dst[:n], err
+	}
+	var dst []byte
+	if inPlace {
+		dst = body[:0]
+	} else {
+		dst = make([]byte, 0, base64.StdEncoding.DecodedLen(len(body)))
+	}
+	var quantum [4]byte
+	var out [3]byte
+	n, padded := 0, false
+	for i, c := range body {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		if padded {
+			// nothing may follow padding
+			return nil, base64.CorruptInputError(i)
+		}
+		quantum[n] = c
+		n++
+		if n < len(quantum) {
+			continue
+		}
+		m, err := base64.StdEncoding.Decode(out[:], quantum[:])
+		if err != nil {
+			return nil, base64.CorruptInputError(i)
+		}
+		dst = append(dst, out[:m]...)
+		n, padded = 0, m < len(out)
+	}
+	if n != 0 {
+		return nil, base64.CorruptInputError(len(body))
+	}
+	return dst, nil
+}
+
+// Every label in knownLabels and labelAliases, so the strings for them
+// don't have to be allocated for each block
+var internedLabels = func() map[string]string {
+	ret := map[string]string{}
+	for label := range knownLabels {
+		ret[label] = label
+	}
+	for label := range labelAliases {
+		ret[label] = label
+	}
+	return ret
+}()
+
+// The type label from a BEGIN line
+func labelOf(typeLine []byte) string {
+	if label, ok := internedLabels[string(typeLine)]; ok {
+		return label
+	}
+	return string(typeLine)
+}
+
+// A single pass over PEM data which finds its blocks in turn.
+//
+// pem.Decode searches the rest of the input again on every call, which
+// adds up on inputs with a lot of text between blocks.  The scanner
+// looks at each line once, finding BEGIN and END lines the way
+// pem.Decode does, and counts lines as it goes.
+type scanner struct {
+	data    []byte
+	inPlace bool
+	// leave the base64 undecoded, for ScanPEM
+	indexOnly bool
+	// where the next line to look at starts
+	pos int
+	// the line number at counted, for lineOf
+	counted, line int
+	// the offset of the last END line of each type, for truncatedAt
+	lastEnd map[string]int
+}
+
+// A block found by the scanner
+type scannedBlock struct {
+	*pem.Block
+	// The byte offset of the BEGIN line, counting from 0
+	Offset int
+	// The line number of the BEGIN line, counting from 1
+	Line int
+	// The byte offset just past the END line and its line break
+	End int
+}
+
+// Scan data, decoding base64 over itself if inPlace is true
+func newScanner(data []byte, inPlace bool) *scanner {
+	return &scanner{data: data, inPlace: inPlace, line: 1}
+}
+
+// Return the line number, counting from 1, of the byte at offset.
+// Offsets asked for in increasing order are counted from the last one.
+func (s *scanner) lineOf(offset int) int {
+	if offset < s.counted {
+		s.counted, s.line = 0, 1
+	}
+	s.line += bytes.Count(s.data[s.counted:offset], []byte{'\n'})
+	s.counted = offset
+	return s.line
+}
+
+// Find the next block which decodes, as pem.Decode would, or return
+// false when there are no more.  The last BEGIN line before an END line starts
+// the block ended by it, so BEGIN lines without an END are passed over.
+func (s *scanner) next() (scannedBlock, bool) {
+	begin := -1
+	for s.pos < len(s.data) {
+		start := s.pos
+		line, _, consumed := pemLine(s.data[start:])
+		s.pos += consumed
+		if bytes.HasPrefix(line, beginMarker) {
+			begin = start
+		} else if begin >= 0 && bytes.HasPrefix(line, endMarker) {
+			if block := s.decode(begin, start, line); block != nil {
+				return scannedBlock{Block: block, Offset: begin, Line: s.lineOf(begin), End: s.pos}, true
+			}
+			begin = -1
+		}
+	}
+	return scannedBlock{}, false
+}
+
+// Decode the block from the BEGIN line at begin to endLine, the END line
+// at end, or return nil if it isn't a valid block
+func (s *scanner) decode(begin, end int, endLine []byte) *pem.Block {
+	typeLine, _, consumed := pemLine(s.data[begin+len(beginMarker) : end])
+	if !bytes.HasSuffix(typeLine, pemEndOfLine) {
+		return nil
+	}
+	typeLine = typeLine[:len(typeLine)-len(pemEndOfLine)]
+	if string(endLine) != string(endMarker)+string(typeLine)+string(pemEndOfLine) {
+		return nil
+	}
+	block := &pem.Block{Type: labelOf(typeLine), Headers: map[string]string{}}
+	body := begin + len(beginMarker) + consumed
+	for body < end {
+		line, _, consumed := pemLine(s.data[body:end])
+		key, val, ok := bytes.Cut(line, []byte(":"))
+		if !ok {
+			break
+		}
+		block.Headers[string(bytes.TrimSpace(key))] = string(bytes.TrimSpace(val))
+		body += consumed
+	}
+	// headers must be followed by a line break before the END line
+	if len(block.Headers) > 0 && body >= end {
+		return nil
+	}
+	if s.indexOnly {
+		return block
+	}
+	block.Bytes = []byte{}
+	// the body ends at the line break before the END line
+	if body < end-1 {
+		der, err := decodeBody(s.data[body:end-1], s.inPlace)
+		if err != nil {
+			return nil
+		}
+		block.Bytes = der
+	}
+	return block
+}
+
+// The type of the BEGIN line at offset if no END line for that type
+// comes after it
+func (s *scanner) truncatedAt(offset int) (string, bool) {
+	label := s.data[offset+len(beginMarker):]
+	if eol := bytes.IndexByte(label, '\n'); eol >= 0 {
+		label = label[:eol]
+	}
+	end := bytes.Index(label, pemEndOfLine)
+	if end < 0 {
+		// not really a BEGIN line
+		return "", false
+	}
+	if s.lastEnd == nil {
+		s.lastEnd = map[string]int{}
+		for pos := 0; pos < len(s.data); {
+			line, _, consumed := pemLine(s.data[pos:])
+			if typ, ok := bytes.CutPrefix(line, endMarker); ok && bytes.HasSuffix(typ, pemEndOfLine) {
+				s.lastEnd[string(typ[:len(typ)-len(pemEndOfLine)])] = pos
+			}
+			pos += consumed
+		}
+	}
+	typ := string(label[:end])
+	last, ok := s.lastEnd[typ]
+	return typ, !ok || last < offset
+}
+
+// Return a *TruncatedBlockError for the first BEGIN line in data[from:to]
+// which has no END line for the same type anywhere after it
+func (s *scanner) truncated(from, to int) error {
+	for from < to {
+		i := bytes.Index(s.data[from:to], beginMarker)
+		if i < 0 {
+			return nil
+		}
+		offset := from + i
+		if typ, ok := s.truncatedAt(offset); ok {
+			return &TruncatedBlockError{Type: typ, Offset: offset, Line: s.lineOf(offset)}
+		}
+		from = offset + len(beginMarker)
+	}
+	return nil
+}
+
+// Where a block is in PEM data, as found by ScanPEM
+type BlockIndex struct {
+	// The type label from the BEGIN line
+	Type string
+	// The byte offset of the BEGIN line, counting from 0
+	Offset int
+	// The number of bytes from the BEGIN line to the end of the END line,
+	// including its line break
+	Length  int
+	Headers map[string]string
+}
+
+// Find every block in PEM data without decoding any of them, for tools
+// which only need to count or locate blocks, such as to split a bundle.
+//
+// Blocks are found the same way ParsePEMs finds them, except that their
+// base64 isn't checked, so a block which ParsePEMs would pass over for
+// not decoding is still listed.  input[Offset:Offset+Length] is the text
+// of each block.
+func ScanPEM(pemInt interface{}) ([]BlockIndex, error) {
+	input, err := intoBytes(pemInt)
+	if err != nil {
+		return nil, err
+	}
+	scan := newScanner(input, false)
+	scan.indexOnly = true
+	blocks := []BlockIndex{}
+	for found, ok := scan.next(); ok; found, ok = scan.next() {
+		blocks = append(blocks, BlockIndex{Type: found.Type, Offset: found.Offset, Length: found.End - found.Offset, Headers: found.Headers})
+	}
+	return blocks, nil
+}
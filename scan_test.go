@@ -0,0 +1,124 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestScannerMatchesPemDecode(t *testing.T) {
+	inputs := []string{
+		string(test_cakey) + string(test_rsareq) + string(test_eccert),
+		"",
+		"no pem here\n",
+		"-----BEGIN EMPTY-----\n-----END EMPTY-----\n",
+		"-----BEGIN SPACED-----\n  AQID \t BA==\n-----END SPACED-----\n",
+		"-----BEGIN CRLF-----\r\nAQIDBA==\r\n-----END CRLF-----\r\n",
+		"-----BEGIN HEADERS-----\nProc-Type: 4,ENCRYPTED\nDEK-Info: x\n\nAQID\n-----END HEADERS-----\n",
+		"-----BEGIN BAD-----\n!!!!\n-----END BAD-----\n-----BEGIN GOOD-----\nAQID\n-----END GOOD-----\n",
+		"-----BEGIN PADDED-----\nAQ==AQID\n-----END PADDED-----\n",
+		"-----BEGIN SHORT-----\nAQI\n-----END SHORT-----\n",
+		"-----BEGIN A-----\n-----BEGIN B-----\nAQID\n-----END B-----\n",
+		"-----BEGIN MISMATCHED-----\nAQID\n-----END OTHER-----\n",
+		"-----BEGIN TRAILING-----\nAQID\n-----END TRAILING----- junk\n",
+		"text before\n-----BEGIN LATE-----\nAQID\n-----END LATE-----\ntext after",
+	}
+	for _, input := range inputs {
+		want := []*pem.Block{}
+		for rest := []byte(input); ; {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			want = append(want, block)
+		}
+		for _, inPlace := range []bool{false, true} {
+			data := []byte(input)
+			scan := newScanner(data, inPlace)
+			got := []*pem.Block{}
+			for found, ok := scan.next(); ok; found, ok = scan.next() {
+				if !bytes.HasPrefix([]byte(input[found.Offset:]), []byte("-----BEGIN "+found.Type+"-----")) || found.Line != newScanner(data, false).lineOf(found.Offset) {
+					t.Errorf("scanning %q found a %s block at offset %d, line %d", input, found.Type, found.Offset, found.Line)
+				}
+				got = append(got, found.Block)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("scanning %q (in place %v) gave %#v, want %#v", input, inPlace, got, want)
+			}
+		}
+	}
+}
+
+func TestScannerTruncated(t *testing.T) {
+	input := []byte("-----BEGIN A-----\nAQID\n-----END A-----\nnote\n-----BEGIN A-----\n-----BEGIN B-----\n")
+	scan := newScanner(input, false)
+	for _, ok := scan.next(); ok; _, ok = scan.next() {
+	}
+	var terr *TruncatedBlockError
+	if err := scan.truncated(0, len(input)); !errors.As(err, &terr) || terr.Type != "A" || terr.Line != 5 {
+		t.Errorf("expected the second A block to be truncated, got %#v", err)
+	}
+	if err := scan.truncated(0, 20); err != nil {
+		t.Errorf("unexpected error for a block with an END line %#v", err)
+	}
+}
+
+func TestInPlaceDecoding(t *testing.T) {
+	input := bytes.Clone(test_rsacert)
+	pems, err := ParsePEMs(input, WithInPlaceDecoding())
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	raw := pems.MustCertificate().Raw
+	if !aliases(input, raw) {
+		t.Errorf("certificate wasn't decoded in place")
+	}
+
+	// without the option a []byte input is left alone
+	input = bytes.Clone(test_rsacert)
+	pems, err = ParsePEMs(input)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if aliases(input, pems.MustCertificate().Raw) || !bytes.Equal(input, test_rsacert) {
+		t.Errorf("input was changed without WithInPlaceDecoding")
+	}
+}
+
+// Whether b lies within a's memory
+func aliases(a, b []byte) bool {
+	for i := range a {
+		if &a[i] == &b[0] {
+			return true
+		}
+	}
+	return false
+}
+
+func TestScanPEM(t *testing.T) {
+	broken := []byte("-----BEGIN CERTIFICATE-----\n!!!!\n-----END CERTIFICATE-----\n")
+	input := bytes.Join([][]byte{[]byte("preamble\n"), test_ca, broken, test_eckey}, nil)
+	blocks, err := ScanPEM(input)
+	if err != nil {
+		t.Fatalf("unexpected error scanning pem %#v", err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %#v", blocks)
+	}
+	for i, want := range [][]byte{test_ca, broken, test_eckey} {
+		b := blocks[i]
+		if got := input[b.Offset : b.Offset+b.Length]; !bytes.Equal(got, want) {
+			t.Errorf("block %d is %q, want %q", i, got, want)
+		}
+	}
+	if blocks[0].Type != "CERTIFICATE" || blocks[0].Offset != len("preamble\n") {
+		t.Errorf("unexpected first block %#v", blocks[0])
+	}
+
+	if _, err := ScanPEM(42); err != ErrPemUnderlyingFormatError {
+		t.Errorf("expected ErrPemUnderlyingFormatError, got %#v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+var ErrInvalidSCT = errors.New("invalid signed certificate timestamp")
+var ErrUnknownLog = errors.New("SCT is from a log which is not in the log list")
+var ErrBadSCTSignature = errors.New("SCT signature does not verify")
+
+var (
+	oidSCTList              = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	oidPrecertificatePoison = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 3}
+)
+
+// An RFC 6962 signed certificate timestamp, a log's promise to publish a
+// certificate
+type SCT struct {
+	Version uint8
+	// The SHA-256 of the log's public key
+	LogID      [32]byte
+	Timestamp  time.Time
+	Extensions []byte
+	// The TLS HashAlgorithm and SignatureAlgorithm of Signature, such as
+	// 4 (SHA-256) and 3 (ECDSA)
+	HashAlgorithm      uint8
+	SignatureAlgorithm uint8
+	Signature          []byte
+}
+
+// A Certificate Transparency log
+type CTLog struct {
+	Description string
+	Operator    string
+	URL         string
+	LogID       [32]byte
+	Key         crypto.PublicKey
+}
+
+// The result of checking one SCT
+type SCTResult struct {
+	SCT SCT
+	// The log which signed the SCT, if it is in the log list
+	Log *CTLog
+	// Why the SCT did not verify, or nil if it did
+	Err error
+}
+
+// Whether a certificate is a precertificate, which is submitted to CT logs
+// in exchange for SCTs but never used, as the critical poison extension
+// it carries prevents.
+func IsPrecertificate(cert *x509.Certificate) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidPrecertificatePoison) {
+			return true
+		}
+	}
+	return false
+}
+
+// Read one SCT from its TLS encoding
+func parseSCT(data cryptobyte.String) (SCT, error) {
+	var sct SCT
+	var logID []byte
+	var timestamp uint64
+	var extensions, signature cryptobyte.String
+	if !data.ReadUint8(&sct.Version) || sct.Version != 0 ||
+		!data.ReadBytes(&logID, 32) ||
+		!data.ReadUint64(&timestamp) ||
+		!data.ReadUint16LengthPrefixed(&extensions) ||
+		!data.ReadUint8(&sct.HashAlgorithm) ||
+		!data.ReadUint8(&sct.SignatureAlgorithm) ||
+		!data.ReadUint16LengthPrefixed(&signature) ||
+		!data.Empty() {
+		return SCT{}, ErrInvalidSCT
+	}
+	copy(sct.LogID[:], logID)
+	sct.Timestamp = time.UnixMilli(int64(timestamp))
+	sct.Extensions = append([]byte{}, extensions...)
+	sct.Signature = append([]byte{}, signature...)
+	return sct, nil
+}
+
+// Return the SCTs embedded in a certificate by its CA, in order.
+//
+// A certificate with no SCT list extension has no SCTs, which is not an
+// error.  A malformed list gives ErrInvalidSCT.
+func EmbeddedSCTs(cert *x509.Certificate) ([]SCT, error) {
+	ret := []SCT{}
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSCTList) {
+			continue
+		}
+		// the TLS encoded list is wrapped in an OCTET STRING of its own
+		var list []byte
+		if rest, err := asn1.Unmarshal(ext.Value, &list); err != nil || len(rest) > 0 {
+			return nil, ErrInvalidSCT
+		}
+		input := cryptobyte.String(list)
+		var scts cryptobyte.String
+		if !input.ReadUint16LengthPrefixed(&scts) || !input.Empty() {
+			return nil, ErrInvalidSCT
+		}
+		for !scts.Empty() {
+			var data cryptobyte.String
+			if !scts.ReadUint16LengthPrefixed(&data) {
+				return nil, ErrInvalidSCT
+			}
+			sct, err := parseSCT(data)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, sct)
+		}
+	}
+	return ret, nil
+}
+
+// The TBSCertificate of a certificate without its SCT list extension,
+// which is what the log saw in the precertificate
+func tbsWithoutSCTs(raw []byte) ([]byte, error) {
+	input := cryptobyte.String(raw)
+	var tbs cryptobyte.String
+	if !input.ReadASN1(&tbs, cbasn1.SEQUENCE) {
+		return nil, ErrInvalidSCT
+	}
+	extensionsTag := cbasn1.Tag(3).Constructed().ContextSpecific()
+	var b cryptobyte.Builder
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		for !tbs.Empty() {
+			var element cryptobyte.String
+			var tag cbasn1.Tag
+			if !tbs.ReadAnyASN1Element(&element, &tag) {
+				b.SetError(ErrInvalidSCT)
+				return
+			}
+			if tag != extensionsTag {
+				b.AddBytes(element)
+				continue
+			}
+			var explicit, exts cryptobyte.String
+			if !element.ReadASN1(&explicit, extensionsTag) || !explicit.ReadASN1(&exts, cbasn1.SEQUENCE) {
+				b.SetError(ErrInvalidSCT)
+				return
+			}
+			b.AddASN1(extensionsTag, func(b *cryptobyte.Builder) {
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					for !exts.Empty() {
+						var ext, body cryptobyte.String
+						var id asn1.ObjectIdentifier
+						if !exts.ReadASN1Element(&ext, cbasn1.SEQUENCE) {
+							b.SetError(ErrInvalidSCT)
+							return
+						}
+						check := ext
+						if !check.ReadASN1(&body, cbasn1.SEQUENCE) || !body.ReadASN1ObjectIdentifier(&id) {
+							b.SetError(ErrInvalidSCT)
+							return
+						}
+						if !id.Equal(oidSCTList) {
+							b.AddBytes(ext)
+						}
+					}
+				})
+			})
+		}
+	})
+	return b.Bytes()
+}
+
+// Check one embedded SCT's signature against the log in logs which issued
+// it, returning that log.
+//
+// issuer must be the certificate which signed cert, as the SCT covers its
+// key.  An SCT from a log not in logs gives ErrUnknownLog, and one whose
+// signature is wrong ErrBadSCTSignature.
+func VerifySCT(sct SCT, cert, issuer *x509.Certificate, logs []CTLog) (*CTLog, error) {
+	var log *CTLog
+	for i := range logs {
+		if logs[i].LogID == sct.LogID {
+			log = &logs[i]
+			break
+		}
+	}
+	if log == nil {
+		return nil, ErrUnknownLog
+	}
+	tbs, err := tbsWithoutSCTs(cert.RawTBSCertificate)
+	if err != nil {
+		return nil, err
+	}
+	issuerKeyHash := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+
+	var b cryptobyte.Builder
+	b.AddUint8(sct.Version)
+	// certificate_timestamp
+	b.AddUint8(0)
+	b.AddUint64(uint64(sct.Timestamp.UnixMilli()))
+	// precert_entry
+	b.AddUint16(1)
+	b.AddBytes(issuerKeyHash[:])
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(tbs) })
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sct.Extensions) })
+	signed, err := b.Bytes()
+	if err != nil {
+		return log, err
+	}
+	if sct.HashAlgorithm != 4 {
+		return log, fmt.Errorf("%w: unsupported hash algorithm %d", ErrBadSCTSignature, sct.HashAlgorithm)
+	}
+	digest := sha256.Sum256(signed)
+	ok := false
+	switch key := log.Key.(type) {
+	case *ecdsa.PublicKey:
+		ok = sct.SignatureAlgorithm == 3 && ecdsa.VerifyASN1(key, digest[:], sct.Signature)
+	case *rsa.PublicKey:
+		ok = sct.SignatureAlgorithm == 1 && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sct.Signature) == nil
+	}
+	if !ok {
+		return log, ErrBadSCTSignature
+	}
+	return log, nil
+}
+
+// Check every SCT embedded in a certificate as VerifySCT does, for
+// compliance checks such as a browser's CT policy.
+//
+// The results are in the order of the SCTs.  The error is only for a
+// malformed SCT list; each SCT's own problems are in its result.
+func VerifyEmbeddedSCTs(cert, issuer *x509.Certificate, logs []CTLog) ([]SCTResult, error) {
+	scts, err := EmbeddedSCTs(cert)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]SCTResult, len(scts))
+	for i, sct := range scts {
+		ret[i].SCT = sct
+		ret[i].Log, ret[i].Err = VerifySCT(sct, cert, issuer, logs)
+	}
+	return ret, nil
+}
+
+// The parts of a log in a log list betterpem uses
+type ctLogJSON struct {
+	Description string `json:"description"`
+	LogID       string `json:"log_id"`
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+	// a static CT API log's submission URL
+	SubmissionURL string `json:"submission_url"`
+}
+
+// Parse a CT log list in the v3 JSON schema, like the one Chrome
+// publishes at https://www.gstatic.com/ct/log_list/v3/log_list.json, into
+// the logs VerifySCT takes.
+//
+// Every log is returned, whatever its state, as an SCT is checked
+// against the log which signed it at the time.  Filtering by state is
+// left to policy.
+func ParseCTLogList(data []byte) ([]CTLog, error) {
+	var list struct {
+		Operators []struct {
+			Name      string      `json:"name"`
+			Logs      []ctLogJSON `json:"logs"`
+			TiledLogs []ctLogJSON `json:"tiled_logs"`
+		} `json:"operators"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	ret := []CTLog{}
+	for _, op := range list.Operators {
+		for _, l := range append(op.Logs, op.TiledLogs...) {
+			der, err := base64.StdEncoding.DecodeString(l.Key)
+			if err != nil {
+				return nil, fmt.Errorf("log %q: bad key: %w", l.Description, err)
+			}
+			key, err := x509.ParsePKIXPublicKey(der)
+			if err != nil {
+				return nil, fmt.Errorf("log %q: %w", l.Description, err)
+			}
+			log := CTLog{Description: l.Description, Operator: op.Name, URL: l.URL, LogID: sha256.Sum256(der), Key: key}
+			if log.URL == "" {
+				log.URL = l.SubmissionURL
+			}
+			if id, err := base64.StdEncoding.DecodeString(l.LogID); l.LogID != "" && (err != nil || string(id) != string(log.LogID[:])) {
+				return nil, fmt.Errorf("log %q: log_id is not the hash of its key", l.Description)
+			}
+			ret = append(ret, log)
+		}
+	}
+	return ret, nil
+}
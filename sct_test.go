@@ -0,0 +1,128 @@
+package betterpem
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Issue a leaf certificate with an SCT from a test log embedded, the way
+// a CA would after logging its precertificate
+func issueTestSCTCert(t *testing.T, c *testChain, logKey *ecdsa.PrivateKey, logSPKI []byte) (*x509.Certificate, time.Time) {
+	template := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "ct.example.com"},
+		DNSNames: []string{"ct.example.com"},
+	}
+	key := c.leafKey.(*ecdsa.PrivateKey)
+	precert := issueTestCertForKey(t, template, c.intermediate, c.intermediateKey, key)
+
+	timestamp := time.UnixMilli(time.Now().UnixMilli())
+	issuerKeyHash := sha256.Sum256(c.intermediate.RawSubjectPublicKeyInfo)
+	tbs := precert.RawTBSCertificate
+	signed := []byte{0, 0}
+	signed = binary.BigEndian.AppendUint64(signed, uint64(timestamp.UnixMilli()))
+	signed = append(signed, 0, 1)
+	signed = append(signed, issuerKeyHash[:]...)
+	signed = append(signed, byte(len(tbs)>>16), byte(len(tbs)>>8), byte(len(tbs)))
+	signed = append(signed, tbs...)
+	signed = append(signed, 0, 0)
+	digest := sha256.Sum256(signed)
+	sig, err := ecdsa.SignASN1(rand.Reader, logKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logID := sha256.Sum256(logSPKI)
+	sct := append([]byte{0}, logID[:]...)
+	sct = binary.BigEndian.AppendUint64(sct, uint64(timestamp.UnixMilli()))
+	sct = append(sct, 0, 0, 4, 3)
+	sct = binary.BigEndian.AppendUint16(sct, uint16(len(sig)))
+	sct = append(sct, sig...)
+	list := binary.BigEndian.AppendUint16(nil, uint16(len(sct)+2))
+	list = binary.BigEndian.AppendUint16(list, uint16(len(sct)))
+	list = append(list, sct...)
+	value, err := asn1.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.ExtraExtensions = []pkix.Extension{{Id: oidSCTList, Value: value}}
+	return issueTestCertForKey(t, template, c.intermediate, c.intermediateKey, key), timestamp
+}
+
+func TestVerifyEmbeddedSCTs(t *testing.T) {
+	c := newTestChain(t)
+	logKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logSPKI, err := x509.MarshalPKIXPublicKey(&logKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, timestamp := issueTestSCTCert(t, c, logKey, logSPKI)
+
+	scts, err := EmbeddedSCTs(cert)
+	if err != nil {
+		t.Fatalf("unexpected error reading SCTs %#v", err)
+	}
+	if len(scts) != 1 || scts[0].LogID != sha256.Sum256(logSPKI) || !scts[0].Timestamp.Equal(timestamp) {
+		t.Fatalf("unexpected SCTs %#v", scts)
+	}
+	if none, err := EmbeddedSCTs(c.leaf); err != nil || len(none) != 0 {
+		t.Errorf("expected no SCTs without the extension, got %d and %#v", len(none), err)
+	}
+
+	logID := sha256.Sum256(logSPKI)
+	logList := fmt.Sprintf(`{"operators": [{"name": "Test Operator", "logs": [{"description": "Test Log", "log_id": %q, "key": %q, "url": "https://ct.example.com/"}]}]}`,
+		base64.StdEncoding.EncodeToString(logID[:]), base64.StdEncoding.EncodeToString(logSPKI))
+	logs, err := ParseCTLogList([]byte(logList))
+	if err != nil {
+		t.Fatalf("unexpected error parsing log list %#v", err)
+	}
+	if len(logs) != 1 || logs[0].Operator != "Test Operator" || logs[0].LogID != logID {
+		t.Fatalf("unexpected logs %#v", logs)
+	}
+
+	results, err := VerifyEmbeddedSCTs(cert, c.intermediate, logs)
+	if err != nil {
+		t.Fatalf("unexpected error verifying SCTs %#v", err)
+	}
+	if len(results) != 1 || results[0].Err != nil || results[0].Log == nil || results[0].Log.Description != "Test Log" {
+		t.Fatalf("expected the SCT to verify, got %#v", results)
+	}
+	if _, err := VerifySCT(scts[0], cert, c.root, logs); err != ErrBadSCTSignature {
+		t.Errorf("expected ErrBadSCTSignature for the wrong issuer, got %#v", err)
+	}
+	if _, err := VerifySCT(scts[0], cert, c.intermediate, nil); err != ErrUnknownLog {
+		t.Errorf("expected ErrUnknownLog, got %#v", err)
+	}
+
+	mismatched := fmt.Sprintf(`{"operators": [{"name": "Test Operator", "logs": [{"description": "Test Log", "log_id": "AAAA", "key": %q}]}]}`,
+		base64.StdEncoding.EncodeToString(logSPKI))
+	if _, err := ParseCTLogList([]byte(mismatched)); err == nil {
+		t.Error("expected an error for a log_id which is not the hash of the key")
+	}
+}
+
+func TestIsPrecertificate(t *testing.T) {
+	c := newTestChain(t)
+	if IsPrecertificate(c.leaf) {
+		t.Error("expected an ordinary certificate not to be a precertificate")
+	}
+	precert, _ := issueTestCert(t, &x509.Certificate{
+		Subject:         pkix.Name{CommonName: "precert.example.com"},
+		ExtraExtensions: []pkix.Extension{{Id: oidPrecertificatePoison, Critical: true, Value: asn1.NullBytes}},
+	}, c.intermediate, c.intermediateKey)
+	if !IsPrecertificate(precert) {
+		t.Error("expected the poisoned certificate to be a precertificate")
+	}
+}
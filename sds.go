@@ -0,0 +1,85 @@
+package betterpem
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+)
+
+// The type URL of an Envoy TLS secret, for the Any an SDS response holds
+const SDSSecretTypeURL = "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret"
+
+// An Envoy envoy.extensions.transport_sockets.tls.v3.Secret holding a
+// TLS certificate inline, as an SDS (secret discovery service) server
+// sends it
+type SDSSecret struct {
+	Name string
+	// The leaf and intermediates in PEM
+	CertificateChain []byte
+	// The private key in PEM
+	PrivateKey []byte
+}
+
+// Build the Envoy TLS secret for an identity, named name for the
+// listeners and clusters which refer to it.
+//
+// The certificate chain is the leaf followed by the intermediates, and
+// the key is encoded as EncodePEM would.  Marshal the secret with
+// json.Marshal for a static or file based config, or with MarshalProto
+// for an SDS response.
+func ToSDSSecret(name string, identity Identity) (*SDSSecret, error) {
+	if identity.Leaf == nil {
+		return nil, ErrNoLeaf
+	}
+	if identity.Key == nil {
+		return nil, ErrNoPrivateKey
+	}
+	key, err := EncodePEM(identity.Key, nil)
+	if err != nil {
+		return nil, err
+	}
+	chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: identity.Leaf.Raw})
+	for _, c := range identity.Intermediates {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return &SDSSecret{Name: name, CertificateChain: chain, PrivateKey: key}, nil
+}
+
+type sdsDataSource struct {
+	InlineBytes []byte `json:"inline_bytes"`
+}
+
+// Write the secret in Envoy's JSON form, with its "@type", as it appears
+// in a config's secrets or a file watched by path based SDS.
+func (s *SDSSecret) MarshalJSON() ([]byte, error) {
+	type tlsCertificate struct {
+		CertificateChain sdsDataSource `json:"certificate_chain"`
+		PrivateKey       sdsDataSource `json:"private_key"`
+	}
+	return json.Marshal(struct {
+		Type           string         `json:"@type"`
+		Name           string         `json:"name"`
+		TLSCertificate tlsCertificate `json:"tls_certificate"`
+	}{SDSSecretTypeURL, s.Name, tlsCertificate{sdsDataSource{s.CertificateChain}, sdsDataSource{s.PrivateKey}}})
+}
+
+// Append a length delimited protobuf field
+func appendProtoBytes(b []byte, field uint64, value []byte) []byte {
+	// wire type 2
+	b = binary.AppendUvarint(b, field<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// Encode the secret as a protobuf message, which unmarshals into
+// go-control-plane's tlsv3.Secret for a DiscoveryResponse.
+func (s *SDSSecret) MarshalProto() []byte {
+	// config.core.v3.DataSource, with the data as inline_bytes
+	dataSource := func(data []byte) []byte {
+		return appendProtoBytes(nil, 2, data)
+	}
+	cert := appendProtoBytes(nil, 1, dataSource(s.CertificateChain))
+	cert = appendProtoBytes(cert, 2, dataSource(s.PrivateKey))
+	ret := appendProtoBytes(nil, 1, []byte(s.Name))
+	return appendProtoBytes(ret, 2, cert)
+}
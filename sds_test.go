@@ -0,0 +1,93 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+// Split a protobuf message into its length delimited fields
+func protoFields(t *testing.T, b []byte) map[uint64][]byte {
+	ret := map[uint64][]byte{}
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 || tag&7 != 2 {
+			t.Fatalf("unexpected protobuf tag %x", tag)
+		}
+		length, m := binary.Uvarint(b[n:])
+		if m <= 0 || uint64(len(b[n+m:])) < length {
+			t.Fatal("truncated protobuf field")
+		}
+		ret[tag>>3] = b[n+m : n+m+int(length)]
+		b = b[n+m+int(length):]
+	}
+	return ret
+}
+
+func TestToSDSSecret(t *testing.T) {
+	c := newTestChain(t)
+	pems, err := ParsePEMs(c.pem(t, c.leafKey, c.leaf, c.intermediate, c.root))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	identity, err := pems.ExtractIdentity()
+	if err != nil {
+		t.Fatalf("unexpected error extracting identity %#v", err)
+	}
+	secret, err := ToSDSSecret("server_cert", identity)
+	if err != nil {
+		t.Fatalf("unexpected error building secret %#v", err)
+	}
+	chain, err := ParsePEMs(secret.CertificateChain)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if chain.Length() != 2 || !chain.MustCertificate().Equal(c.leaf) || !chain.MustCertificate().Equal(c.intermediate) {
+		t.Error("expected the leaf and intermediate in the chain")
+	}
+	key, err := ParsePEMs(secret.PrivateKey)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if !key.MustECPrivateKey().Equal(c.leafKey) {
+		t.Error("expected the leaf's key")
+	}
+
+	encoded, err := json.Marshal(secret)
+	if err != nil {
+		t.Fatalf("unexpected error marshaling secret %#v", err)
+	}
+	var decoded struct {
+		Type           string `json:"@type"`
+		Name           string `json:"name"`
+		TLSCertificate struct {
+			CertificateChain struct {
+				InlineBytes []byte `json:"inline_bytes"`
+			} `json:"certificate_chain"`
+			PrivateKey struct {
+				InlineBytes []byte `json:"inline_bytes"`
+			} `json:"private_key"`
+		} `json:"tls_certificate"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("unexpected error unmarshaling secret %#v", err)
+	}
+	if decoded.Type != SDSSecretTypeURL || decoded.Name != "server_cert" ||
+		!bytes.Equal(decoded.TLSCertificate.CertificateChain.InlineBytes, secret.CertificateChain) ||
+		!bytes.Equal(decoded.TLSCertificate.PrivateKey.InlineBytes, secret.PrivateKey) {
+		t.Errorf("unexpected JSON %s", encoded)
+	}
+
+	fields := protoFields(t, secret.MarshalProto())
+	tlsCert := protoFields(t, fields[2])
+	if string(fields[1]) != "server_cert" ||
+		!bytes.Equal(protoFields(t, tlsCert[1])[2], secret.CertificateChain) ||
+		!bytes.Equal(protoFields(t, tlsCert[2])[2], secret.PrivateKey) {
+		t.Error("unexpected protobuf encoding")
+	}
+
+	if _, err := ToSDSSecret("empty", Identity{}); err != ErrNoLeaf {
+		t.Errorf("expected ErrNoLeaf, got %#v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package betterpem
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Read a PEM input for ListenAndServeTLSPEM: anything ParsePEMs takes,
+// except that a string without any PEM in it is the path of a file
+func readPEMInput(input interface{}) ([]byte, error) {
+	if path, ok := input.(string); ok && !strings.Contains(path, string(beginMarker)) {
+		return os.ReadFile(path)
+	}
+	return intoBytes(input)
+}
+
+// Build the certificate ListenAndServeTLSPEM serves
+func tlsCertificateFromInputs(certInput, keyInput interface{}) (tls.Certificate, error) {
+	var all []byte
+	for _, input := range []interface{}{certInput, keyInput} {
+		if input == nil {
+			continue
+		}
+		data, err := readPEMInput(input)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		all = append(append(all, data...), '\n')
+	}
+	pems, err := ParsePEMs(all)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return pems.TLSCertificate()
+}
+
+// Serve HTTPS on addr like http.ListenAndServeTLS, but with the
+// certificate and key given as any input ParsePEMs takes, so they can
+// come from secrets held in memory rather than files.
+//
+// A string holding no PEM is taken to be a path, as net/http takes it.
+// The certificate input may be followed by its intermediates, and
+// keyInput may be nil if the key is in certInput.  Like
+// http.ListenAndServeTLS, it always returns a non-nil error.
+func ListenAndServeTLSPEM(addr string, handler http.Handler, certInput, keyInput interface{}) error {
+	cert, err := tlsCertificateFromInputs(certInput, keyInput)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Addr:    addr,
+		Handler: handler,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		},
+	}
+	return server.ListenAndServeTLS("", "")
+}
@@ -0,0 +1,49 @@
+package betterpem
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTLSCertificateFromInputs(t *testing.T) {
+	c := newTestChain(t)
+	certPEM := c.pem(t, c.leaf, c.intermediate)
+	keyPEM := c.pem(t, c.leafKey)
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, inputs := range [][2]interface{}{
+		{certPEM, keyPEM},
+		{string(certPEM), keyPath},
+		{bytes.NewReader(certPEM), bytes.NewReader(keyPEM)},
+		{append(keyPEM, certPEM...), nil},
+	} {
+		cert, err := tlsCertificateFromInputs(inputs[0], inputs[1])
+		if err != nil {
+			t.Fatalf("unexpected error building certificate from %T and %T %#v", inputs[0], inputs[1], err)
+		}
+		if !cert.Leaf.Equal(c.leaf) || len(cert.Certificate) != 2 {
+			t.Errorf("expected the leaf and intermediate from %T and %T", inputs[0], inputs[1])
+		}
+	}
+
+	if _, err := tlsCertificateFromInputs(certPEM, nil); err != ErrNoPrivateKey {
+		t.Errorf("expected ErrNoPrivateKey, got %#v", err)
+	}
+}
+
+func TestListenAndServeTLSPEM(t *testing.T) {
+	c := newTestChain(t)
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	err := ListenAndServeTLSPEM("127.0.0.1:0", http.NotFoundHandler(), c.pem(t, c.leaf), missing)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing key file, got %#v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"time"
+)
+
+var ErrNotCA = errors.New("certificate is not a CA certificate")
+
+// How Sign should issue a certificate
+type IssueOptions struct {
+	// Defaults to now
+	NotBefore time.Time
+	// Defaults to one year.  The certificate never outlives the CA's.
+	Validity time.Duration
+	// Defaults to server and client authentication, for non-CA
+	// certificates
+	ExtKeyUsage []x509.ExtKeyUsage
+	// Issue an intermediate CA certificate instead of an end-entity one
+	IsCA bool
+}
+
+// Issue a certificate for a certificate signing request.
+//
+// The request's signature is checked first, so only someone holding the
+// requested key can get a certificate for it.  The subject and subject
+// alternative names are copied from the request; anything else the
+// request asks for is ignored in favor of opts.  The certificate is
+// returned parsed and as a CERTIFICATE block.
+func Sign(csr *x509.CertificateRequest, caCert *x509.Certificate, caKey crypto.Signer, opts IssueOptions) (*x509.Certificate, []byte, error) {
+	if err := csr.CheckSignature(); err != nil {
+		return nil, nil, err
+	}
+	if !caCert.IsCA {
+		return nil, nil, ErrNotCA
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	notBefore := opts.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now()
+	}
+	validity := opts.Validity
+	if validity == 0 {
+		validity = 365 * 24 * time.Hour
+	}
+	notAfter := notBefore.Add(validity)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               csr.Subject,
+		DNSNames:              csr.DNSNames,
+		IPAddresses:           csr.IPAddresses,
+		EmailAddresses:        csr.EmailAddresses,
+		URIs:                  csr.URIs,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsageFor(csr.PublicKey, opts.IsCA),
+		ExtKeyUsage:           opts.ExtKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  opts.IsCA,
+	}
+	if template.ExtKeyUsage == nil && !opts.IsCA {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, csr.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM, err := EncodePEM(cert, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, certPEM, nil
+}
@@ -0,0 +1,58 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	c := newTestChain(t)
+	key, _, err := GenerateRSA(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, _, err := CreateCSR(key, CSRRequest{CommonName: "signed.example.com", SANs: []string{"signed.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, certPEM, err := Sign(csr, c.intermediate, c.intermediateKey, IssueOptions{Validity: 1000 * time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error signing %#v", err)
+	}
+	objs, err := ParsePEMs(c.pem(t, c.root, c.intermediate, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := ParsePEMs(certPEM)
+	if err != nil {
+		t.Fatalf("unexpected error parsing signed certificate %#v", err)
+	}
+	if !signed.MustCertificate().Equal(cert) {
+		t.Error("parsed certificate and pem differ")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(c.root)
+	inters := x509.NewCertPool()
+	inters.AddCert(c.intermediate)
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, Intermediates: inters, DNSName: "signed.example.com"}); err != nil {
+		t.Errorf("signed certificate does not verify: %v", err)
+	}
+	if cert.NotAfter.After(c.intermediate.NotAfter) {
+		t.Error("certificate outlives its CA")
+	}
+	if cert.KeyUsage&x509.KeyUsageKeyEncipherment == 0 {
+		t.Error("expected key encipherment for an RSA key")
+	}
+	if len(MatchKeyCert(objs.privateKeys()[0], []*x509.Certificate{cert})) != 1 {
+		t.Error("certificate is not for the requested key")
+	}
+
+	if _, _, err := Sign(csr, c.leaf, c.leafKey, IssueOptions{}); err != ErrNotCA {
+		t.Errorf("expected ErrNotCA, got %#v", err)
+	}
+	csr.Signature[0] ^= 0xff
+	if _, _, err := Sign(csr, c.intermediate, c.intermediateKey, IssueOptions{}); err == nil {
+		t.Error("expected an error for a bad csr signature")
+	}
+}
@@ -0,0 +1,124 @@
+package betterpem
+
+import (
+	"crypto/tls"
+	"errors"
+	"sort"
+	"strings"
+)
+
+var ErrNoCertificateForName = errors.New("no certificate is configured for the requested server name")
+
+type sniSelector struct {
+	exact map[string]*tls.Certificate
+	// keyed by the domain the wildcard is for, so "*.example.com" is
+	// under "example.com"
+	wildcard map[string]*tls.Certificate
+	fallback *tls.Certificate
+}
+
+func newSNISelector() *sniSelector {
+	return &sniSelector{exact: map[string]*tls.Certificate{}, wildcard: map[string]*tls.Certificate{}}
+}
+
+func normalizeServerName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// Register a name or wildcard pattern, keeping the first certificate
+// registered for it
+func (s *sniSelector) add(name string, cert *tls.Certificate) {
+	name = normalizeServerName(name)
+	m := s.exact
+	if strings.HasPrefix(name, "*.") {
+		m = s.wildcard
+		name = name[2:]
+	}
+	if _, ok := m[name]; !ok {
+		m[name] = cert
+	}
+}
+
+func (s *sniSelector) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := normalizeServerName(hello.ServerName)
+	if name != "" {
+		if c, ok := s.exact[name]; ok {
+			return c, nil
+		}
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			if c, ok := s.wildcard[name[i+1:]]; ok {
+				return c, nil
+			}
+		}
+	}
+	if s.fallback != nil {
+		return s.fallback, nil
+	}
+	return nil, ErrNoCertificateForName
+}
+
+// Build a tls.Config.GetCertificate callback which picks an identity by
+// the server name the client asks for.
+//
+// Each entry maps a server name, or a wildcard such as "*.example.com"
+// covering one label, to a bundle holding a private key and its
+// certificates.  Exact names are preferred over wildcards.  The entry
+// for "" is used for clients which ask for a name that isn't configured
+// or don't send one; without it they get ErrNoCertificateForName.
+// Names are compared without case or a trailing dot, and when two
+// entries are the same name compared that way, the one which sorts
+// first as given wins, so "Example.com" is used over "example.com".
+// The parsed PEMs are not consumed.
+func NewSNISelector(identities map[string]ParsedPEMs) (func(*tls.ClientHelloInfo) (*tls.Certificate, error), error) {
+	s := newSNISelector()
+	names := make([]string, 0, len(identities))
+	for name := range identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pems := identities[name]
+		cert, err := pems.TLSCertificate()
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			s.fallback = &cert
+		} else {
+			s.add(name, &cert)
+		}
+	}
+	return s.getCertificate, nil
+}
+
+// Build a tls.Config.GetCertificate callback from a bundle holding
+// several identities.
+//
+// Every private key in the bundle is paired with its certificate and
+// chain, and served for the DNS names in that certificate, wildcards
+// included.  When two certificates claim a name, the first in the bundle
+// wins.  Clients which ask for a name that isn't covered, or don't send
+// one, get the first identity.  The parsed PEMs are not consumed.
+func NewSNISelectorFromBundle(pems ParsedPEMs) (func(*tls.ClientHelloInfo) (*tls.Certificate, error), error) {
+	s := newSNISelector()
+	certs := pems.certificates()
+	for _, key := range pems.privateKeys() {
+		cert, ok := tlsCertificateFor(key, certs)
+		if !ok {
+			continue
+		}
+		for _, name := range cert.Leaf.DNSNames {
+			s.add(name, &cert)
+		}
+		if s.fallback == nil {
+			s.fallback = &cert
+		}
+	}
+	if s.fallback == nil {
+		if len(pems.privateKeys()) == 0 {
+			return nil, ErrNoPrivateKey
+		}
+		return nil, ErrNoMatchingCertificate
+	}
+	return s.getCertificate, nil
+}
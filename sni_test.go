@@ -0,0 +1,99 @@
+package betterpem
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+)
+
+func TestSNISelector(t *testing.T) {
+	c := newTestChain(t)
+	other, otherKey := issueTestCert(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "other.example.com"},
+		DNSNames: []string{"other.example.com"},
+	}, c.intermediate, c.intermediateKey)
+
+	bundle, err := ParsePEMs(c.pem(t, c.leaf, c.leafKey, c.intermediate, other, otherKey))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	fromBundle, err := NewSNISelectorFromBundle(bundle)
+	if err != nil {
+		t.Fatalf("unexpected error building selector %#v", err)
+	}
+
+	leafOnly, err := ParsePEMs(c.pem(t, c.leaf, c.leafKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherOnly, err := ParsePEMs(c.pem(t, other, otherKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromMap, err := NewSNISelector(map[string]ParsedPEMs{
+		"leaf.example.com":   leafOnly,
+		"*.leaf.example.com": leafOnly,
+		"OTHER.example.com.": otherOnly,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error building selector %#v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		want *x509.Certificate
+	}{
+		{"leaf.example.com", c.leaf},
+		{"www.leaf.example.com", c.leaf},
+		{"Other.Example.com", other},
+	} {
+		for i, get := range []func(*tls.ClientHelloInfo) (*tls.Certificate, error){fromBundle, fromMap} {
+			got, err := get(&tls.ClientHelloInfo{ServerName: tc.name})
+			if err != nil {
+				t.Errorf("selector %d: unexpected error for %s: %v", i, tc.name, err)
+				continue
+			}
+			if !got.Leaf.Equal(tc.want) {
+				t.Errorf("selector %d: wrong certificate for %s: %s", i, tc.name, got.Leaf.Subject)
+			}
+		}
+	}
+
+	if got, err := fromBundle(&tls.ClientHelloInfo{ServerName: "a.b.leaf.example.com"}); err != nil || !got.Leaf.Equal(c.leaf) {
+		t.Error("expected the first identity for an unknown name")
+	}
+	if _, err := fromMap(&tls.ClientHelloInfo{ServerName: "unknown.example.com"}); err != ErrNoCertificateForName {
+		t.Errorf("expected ErrNoCertificateForName, got %#v", err)
+	}
+}
+
+func TestSNISelectorCollisions(t *testing.T) {
+	c := newTestChain(t)
+	other, otherKey := issueTestCert(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "other.example.com"},
+		DNSNames: []string{"other.example.com"},
+	}, c.intermediate, c.intermediateKey)
+	leafOnly, err := ParsePEMs(c.pem(t, c.leaf, c.leafKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherOnly, err := ParsePEMs(c.pem(t, other, otherKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// map order is random, so build the selector a few times
+	for i := 0; i < 10; i++ {
+		get, err := NewSNISelector(map[string]ParsedPEMs{
+			"example.com":  leafOnly,
+			"Example.com":  otherOnly,
+			"example.com.": leafOnly,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error building selector %#v", err)
+		}
+		if got, err := get(&tls.ClientHelloInfo{ServerName: "example.com"}); err != nil || !got.Leaf.Equal(other) {
+			t.Fatalf("expected the name which sorts first to win, got %v", err)
+		}
+	}
+}
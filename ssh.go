@@ -0,0 +1,87 @@
+package betterpem
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var ErrNoPublicKey = errors.New("object does not contain a public key")
+
+// Find the public key held by (or derived from) an object.
+//
+// Certificates give their subject public key, private keys give their
+// public half, and public keys are returned as they are.  Anything else,
+// such as a CRL or DH parameters, gives ErrNoPublicKey.
+func publicKeyOf(obj interface{}) (crypto.PublicKey, error) {
+	switch v := obj.(type) {
+	case *x509.Certificate:
+		return v.PublicKey, nil
+	case *x509.CertificateRequest:
+		return v.PublicKey, nil
+	case ssh.PublicKey:
+		cpk, ok := v.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, ErrNoPublicKey
+		}
+		return cpk.CryptoPublicKey(), nil
+	// not crypto.Signer, which *ecdh.PrivateKey isn't
+	case interface{ Public() crypto.PublicKey }:
+		return v.Public(), nil
+	// every public key type in the standard library has this method
+	case interface{ Equal(crypto.PublicKey) bool }:
+		return v, nil
+	}
+	return nil, ErrNoPublicKey
+}
+
+// Render a public key as an OpenSSH authorized_keys line.
+//
+// The key may be a public key, a private key, an *x509.Certificate, or
+// anything else a public key can be derived from.  The comment is
+// optional and is appended after the key when it is not empty.
+//
+// The returned line ends with a newline so several of them may be
+// concatenated into an authorized_keys file.
+func ToAuthorizedKey(pub interface{}, comment string) (string, error) {
+	sshpub, ok := pub.(ssh.PublicKey)
+	if !ok {
+		cpub, err := publicKeyOf(pub)
+		if err != nil {
+			return "", err
+		}
+		sshpub, err = ssh.NewPublicKey(cpub)
+		if err != nil {
+			return "", err
+		}
+	}
+	line := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(sshpub)), "\n")
+	if comment != "" {
+		line += " " + comment
+	}
+	return line + "\n", nil
+}
+
+// Encode a private key in the "OPENSSH PRIVATE KEY" format used by
+// ssh-keygen and friends.
+//
+// The key may be any RSA, ECDSA, or Ed25519 private key, such as one
+// parsed from a PKCS#8 block.  If passphrase is empty, the key is written
+// unencrypted; otherwise it is encrypted the same way ssh-keygen does.
+func EncodeOpenSSHPrivateKey(key crypto.PrivateKey, comment string, passphrase []byte) ([]byte, error) {
+	var block *pem.Block
+	var err error
+	if len(passphrase) == 0 {
+		block, err = ssh.MarshalPrivateKey(key, comment)
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, comment, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(block), nil
+}
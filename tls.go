@@ -0,0 +1,184 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+)
+
+var ErrNoPrivateKey = errors.New("no private key was found")
+var ErrNoMatchingCertificate = errors.New("no certificate matches the private key")
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	ae, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && ae.Equal(b)
+}
+
+// Whether a certificate is for a private key
+func certMatchesKey(cert *x509.Certificate, key crypto.PrivateKey) bool {
+	pub, err := publicKeyOf(key)
+	if err != nil {
+		return false
+	}
+	return publicKeysEqual(pub, cert.PublicKey)
+}
+
+// Whether parent issued child: its subject is child's issuer, its key
+// identifier is child's authority key identifier when both have one, and
+// its key made child's signature.
+func issuedBy(child, parent *x509.Certificate) bool {
+	if !bytes.Equal(child.RawIssuer, parent.RawSubject) {
+		return false
+	}
+	if len(child.AuthorityKeyId) > 0 && len(parent.SubjectKeyId) > 0 && !bytes.Equal(child.AuthorityKeyId, parent.SubjectKeyId) {
+		return false
+	}
+	return child.CheckSignatureFrom(parent) == nil
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return issuedBy(cert, cert)
+}
+
+// Follow issuers from a leaf through a pile of certificates, returning
+// the intermediates in order.  Roots are not included.
+func chainFrom(leaf *x509.Certificate, certs []*x509.Certificate) []*x509.Certificate {
+	chain := []*x509.Certificate{}
+	used := map[*x509.Certificate]bool{leaf: true}
+	cur := leaf
+	for !isSelfSigned(cur) {
+		var next *x509.Certificate
+		for _, c := range certs {
+			if !used[c] && issuedBy(cur, c) {
+				next = c
+				break
+			}
+		}
+		if next == nil || isSelfSigned(next) {
+			break
+		}
+		used[next] = true
+		chain = append(chain, next)
+		cur = next
+	}
+	return chain
+}
+
+func (p *ParsedPEMs) certificates() []*x509.Certificate {
+	ret := []*x509.Certificate{}
+	for _, obj := range p.all() {
+		if c, ok := obj.(*x509.Certificate); ok {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func (p *ParsedPEMs) privateKeys() []crypto.PrivateKey {
+	ret := []crypto.PrivateKey{}
+	for _, obj := range p.all() {
+		if isPrivateKey(obj) {
+			ret = append(ret, obj)
+		}
+	}
+	return ret
+}
+
+// Build a tls.Certificate from the parsed PEMs remaining to be consumed.
+//
+// The first private key with a matching certificate is used, along with
+// that certificate as the leaf and whichever other certificates chain
+// from it, in order.  Roots are left out, as TLS servers shouldn't send
+// them.  The order the objects were in doesn't matter.  The parsed PEMs
+// are not consumed.
+func (p *ParsedPEMs) TLSCertificate() (tls.Certificate, error) {
+	keys := p.privateKeys()
+	if len(keys) == 0 {
+		return tls.Certificate{}, ErrNoPrivateKey
+	}
+	certs := p.certificates()
+	for _, key := range keys {
+		if ret, ok := tlsCertificateFor(key, certs); ok {
+			return ret, nil
+		}
+	}
+	return tls.Certificate{}, ErrNoMatchingCertificate
+}
+
+func tlsCertificateFor(key crypto.PrivateKey, certs []*x509.Certificate) (tls.Certificate, bool) {
+	for _, leaf := range certs {
+		if !certMatchesKey(leaf, key) {
+			continue
+		}
+		ret := tls.Certificate{
+			Certificate: [][]byte{leaf.Raw},
+			PrivateKey:  key,
+			Leaf:        leaf,
+		}
+		for _, c := range chainFrom(leaf, certs) {
+			ret.Certificate = append(ret.Certificate, c.Raw)
+		}
+		return ret, true
+	}
+	return tls.Certificate{}, false
+}
+
+// What BuildTLSConfig should put in a tls.Config.  Every field is
+// optional.
+type TLSConfigOptions struct {
+	// A private key with its certificate and chain, presented to the
+	// other side.  Servers need one; clients only need one for mTLS.
+	Identity *ParsedPEMs
+	// CAs which client certificates must chain to.  When set, servers
+	// require and verify a client certificate.
+	ClientCAs *ParsedPEMs
+	// CAs which server certificates must chain to, in place of the
+	// system roots.
+	RootCAs *ParsedPEMs
+	// Defaults to TLS 1.2
+	MinVersion uint16
+	// The name clients check the server's certificate against, if it
+	// isn't the host being dialed
+	ServerName string
+}
+
+// Assemble a tls.Config from parsed PEMs.
+//
+// A server sets Identity and, for mTLS, ClientCAs.  A client sets RootCAs
+// if it uses a private CA and, for mTLS, Identity.  The same config can
+// be used for both ends when all three are set.  The parsed PEMs are not
+// consumed.
+func BuildTLSConfig(opts TLSConfigOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: opts.MinVersion,
+		ServerName: opts.ServerName,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if opts.Identity != nil {
+		cert, err := opts.Identity.TLSCertificate()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if opts.ClientCAs != nil {
+		pool, err := opts.ClientCAs.CertPool()
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if opts.RootCAs != nil {
+		pool, err := opts.RootCAs.CertPool()
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
@@ -0,0 +1,195 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// A root, intermediate, and leaf certificate with their keys
+type testChain struct {
+	root, intermediate, leaf          *x509.Certificate
+	rootKey, intermediateKey, leafKey crypto.Signer
+}
+
+func (c *testChain) pem(t *testing.T, objs ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, obj := range objs {
+		b, err := EncodePEM(obj, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(b)
+	}
+	return buf.Bytes()
+}
+
+func issueTestCert(t *testing.T, template, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	key, _, err := GenerateEC(elliptic.P256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issueTestCertForKey(t, template, parent, parentKey, key), key
+}
+
+// Issue a certificate for an existing key.  A nil parent makes it
+// self-signed.
+func issueTestCertForKey(t *testing.T, template, parent *x509.Certificate, parentKey, key crypto.Signer) *x509.Certificate {
+	if template.SerialNumber == nil {
+		template.SerialNumber, _ = rand.Int(rand.Reader, big.NewInt(1<<62))
+	}
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(24 * time.Hour)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func newTestChain(t *testing.T) *testChain {
+	c := &testChain{}
+	c.root, c.rootKey = issueTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test Root"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}, nil, nil)
+	c.intermediate, c.intermediateKey = issueTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test Intermediate"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}, c.root, c.rootKey)
+	c.leaf, c.leafKey = issueTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "leaf.example.com"},
+		DNSNames:    []string{"leaf.example.com", "*.leaf.example.com"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, c.intermediate, c.intermediateKey)
+	return c
+}
+
+func TestTLSCertificate(t *testing.T) {
+	c := newTestChain(t)
+	// deliberately jumbled
+	objs, err := ParsePEMs(c.pem(t, c.root, c.intermediate, c.leafKey, c.leaf))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	tc, err := objs.TLSCertificate()
+	if err != nil {
+		t.Fatalf("unexpected error building tls certificate %#v", err)
+	}
+	if tc.Leaf == nil || !tc.Leaf.Equal(c.leaf) {
+		t.Error("wrong leaf")
+	}
+	if len(tc.Certificate) != 2 || !bytes.Equal(tc.Certificate[0], c.leaf.Raw) || !bytes.Equal(tc.Certificate[1], c.intermediate.Raw) {
+		t.Errorf("wrong chain: %d certificates", len(tc.Certificate))
+	}
+	if !certMatchesKey(c.leaf, tc.PrivateKey) {
+		t.Error("wrong key")
+	}
+
+	objs, err = ParsePEMs(c.pem(t, c.root, c.leaf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := objs.TLSCertificate(); err != ErrNoPrivateKey {
+		t.Errorf("expected ErrNoPrivateKey, got %#v", err)
+	}
+	objs, err = ParsePEMs(c.pem(t, c.root, c.rootKey, c.leaf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs.advance()
+	if _, err := objs.TLSCertificate(); err != ErrNoMatchingCertificate {
+		t.Errorf("expected ErrNoMatchingCertificate, got %#v", err)
+	}
+}
+
+// Issue a client certificate from the chain's intermediate
+func (c *testChain) client(t *testing.T) (*x509.Certificate, crypto.Signer) {
+	return issueTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, c.intermediate, c.intermediateKey)
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	c := newTestChain(t)
+	clientCert, clientKey := c.client(t)
+	parse := func(objs ...interface{}) *ParsedPEMs {
+		p, err := ParsePEMs(c.pem(t, objs...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &p
+	}
+
+	serverCfg, err := BuildTLSConfig(TLSConfigOptions{
+		Identity:  parse(c.leaf, c.leafKey, c.intermediate),
+		ClientCAs: parse(c.root, c.intermediate),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error building server config %#v", err)
+	}
+	if serverCfg.MinVersion != tls.VersionTLS12 || serverCfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("unexpected server config %#v", serverCfg)
+	}
+	clientCfg, err := BuildTLSConfig(TLSConfigOptions{
+		Identity:   parse(clientCert, clientKey, c.intermediate),
+		RootCAs:    parse(c.root),
+		ServerName: "leaf.example.com",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error building client config %#v", err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("hi"))
+	}()
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientCfg)
+	if err != nil {
+		t.Fatalf("mTLS handshake failed: %v", err)
+	}
+	defer conn.Close()
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hi" {
+		t.Errorf("unexpected read %q %v", buf, err)
+	}
+
+	if _, err := BuildTLSConfig(TLSConfigOptions{Identity: parse(c.leaf)}); err != ErrNoPrivateKey {
+		t.Errorf("expected ErrNoPrivateKey, got %#v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package betterpem
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidTLSAParameter = errors.New("invalid TLSA usage, selector or matching type")
+
+// The certificate usage field of a TLSA record (RFC 6698 and RFC 7218)
+type TLSAUsage uint8
+
+const (
+	TLSAUsagePKIXTA TLSAUsage = 0
+	TLSAUsagePKIXEE TLSAUsage = 1
+	TLSAUsageDANETA TLSAUsage = 2
+	TLSAUsageDANEEE TLSAUsage = 3
+)
+
+// The selector field of a TLSA record: which part of the certificate is
+// matched
+type TLSASelector uint8
+
+const (
+	TLSASelectorCert TLSASelector = 0
+	TLSASelectorSPKI TLSASelector = 1
+)
+
+// The matching type field of a TLSA record: how the selected data is
+// presented
+type TLSAMatching uint8
+
+const (
+	TLSAMatchingFull   TLSAMatching = 0
+	TLSAMatchingSHA256 TLSAMatching = 1
+	TLSAMatchingSHA512 TLSAMatching = 2
+)
+
+// Build the data of a DNS TLSA record for a certificate.
+//
+// The result is in zone file presentation form, such as
+// "3 1 1 0d6fce33...", ready to follow "_443._tcp.example.com. IN TLSA".
+// "3 1 1" (DANE-EE, SPKI, SHA-256) is the usual choice for a server's own
+// certificate and "2 1 1" for the CA which issued it.
+func TLSARecord(cert *x509.Certificate, usage TLSAUsage, selector TLSASelector, matching TLSAMatching) (string, error) {
+	if usage > TLSAUsageDANEEE {
+		return "", ErrInvalidTLSAParameter
+	}
+	var data []byte
+	switch selector {
+	case TLSASelectorCert:
+		data = cert.Raw
+	case TLSASelectorSPKI:
+		data = cert.RawSubjectPublicKeyInfo
+	default:
+		return "", ErrInvalidTLSAParameter
+	}
+	switch matching {
+	case TLSAMatchingFull:
+	case TLSAMatchingSHA256:
+		sum := sha256.Sum256(data)
+		data = sum[:]
+	case TLSAMatchingSHA512:
+		sum := sha512.Sum512(data)
+		data = sum[:]
+	default:
+		return "", ErrInvalidTLSAParameter
+	}
+	return fmt.Sprintf("%d %d %d %s", usage, selector, matching, hex.EncodeToString(data)), nil
+}
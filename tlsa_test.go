@@ -0,0 +1,36 @@
+package betterpem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestTLSARecord(t *testing.T) {
+	objs, err := ParsePEMs(test_rsacert)
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	cert := objs.MustCertificate()
+	rec, err := TLSARecord(cert, TLSAUsageDANEEE, TLSASelectorSPKI, TLSAMatchingSHA256)
+	if err != nil {
+		t.Fatalf("unexpected error building record %#v", err)
+	}
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	if rec != "3 1 1 "+hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected record %s", rec)
+	}
+	full, err := TLSARecord(cert, TLSAUsageDANETA, TLSASelectorCert, TLSAMatchingFull)
+	if err != nil {
+		t.Fatalf("unexpected error building record %#v", err)
+	}
+	if full != "2 0 0 "+hex.EncodeToString(cert.Raw) {
+		t.Errorf("unexpected record %s", full)
+	}
+	if _, err := TLSARecord(cert, TLSAUsageDANEEE, 7, TLSAMatchingSHA256); err != ErrInvalidTLSAParameter {
+		t.Errorf("expected ErrInvalidTLSAParameter, got %#v", err)
+	}
+	if _, err := TLSARecord(cert, 4, TLSASelectorSPKI, TLSAMatchingSHA256); err != ErrInvalidTLSAParameter {
+		t.Errorf("expected ErrInvalidTLSAParameter, got %#v", err)
+	}
+}
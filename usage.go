@@ -0,0 +1,90 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// Uses of a certificate which CheckUsage can check for, combined with |
+type Expectation uint
+
+const (
+	ExpectServerAuth Expectation = 1 << iota
+	ExpectClientAuth
+	ExpectCodeSigning
+	ExpectEmailProtection
+	ExpectTimeStamping
+	ExpectOCSPSigning
+	ExpectCertSign
+)
+
+var expectations = []struct {
+	expect Expectation
+	name   string
+	eku    x509.ExtKeyUsage
+	// any one of these is enough
+	ku x509.KeyUsage
+}{
+	{ExpectServerAuth, "server authentication", x509.ExtKeyUsageServerAuth, x509.KeyUsageDigitalSignature},
+	{ExpectClientAuth, "client authentication", x509.ExtKeyUsageClientAuth, x509.KeyUsageDigitalSignature},
+	{ExpectCodeSigning, "code signing", x509.ExtKeyUsageCodeSigning, x509.KeyUsageDigitalSignature},
+	{ExpectEmailProtection, "email protection", x509.ExtKeyUsageEmailProtection, x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment},
+	{ExpectTimeStamping, "time stamping", x509.ExtKeyUsageTimeStamping, x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment},
+	{ExpectOCSPSigning, "OCSP signing", x509.ExtKeyUsageOCSPSigning, x509.KeyUsageDigitalSignature},
+	{ExpectCertSign, "certificate signing", x509.ExtKeyUsageAny, x509.KeyUsageCertSign},
+}
+
+// The uses CheckUsage found a certificate isn't allowed
+type UsageError struct {
+	Certificate *x509.Certificate
+	// Each problem, such as "extended key usage lacks server
+	// authentication"
+	Missing []string
+}
+
+func (e *UsageError) Error() string {
+	return fmt.Sprintf("%s cannot be used as expected: %s", e.Certificate.Subject, strings.Join(e.Missing, "; "))
+}
+
+func hasExtKeyUsage(cert *x509.Certificate, eku x509.ExtKeyUsage) bool {
+	if len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 {
+		// no extension means no restriction
+		return true
+	}
+	for _, u := range cert.ExtKeyUsage {
+		if u == eku || u == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	return false
+}
+
+// Check that a certificate's key usage and extended key usage allow the
+// expected uses, so certificates which would fail at handshake time can
+// be rejected up front.
+//
+// Certificates lacking a key usage or extended key usage extension are
+// unrestricted by it.  ExpectCertSign also requires the certificate to be
+// a CA.  Every problem found is returned in a *UsageError.
+func CheckUsage(cert *x509.Certificate, expect Expectation) error {
+	uerr := &UsageError{Certificate: cert}
+	for _, e := range expectations {
+		if expect&e.expect == 0 {
+			continue
+		}
+		if e.eku != x509.ExtKeyUsageAny && !hasExtKeyUsage(cert, e.eku) {
+			uerr.Missing = append(uerr.Missing, "extended key usage lacks "+e.name)
+		}
+		if cert.KeyUsage != 0 && cert.KeyUsage&e.ku == 0 {
+			uerr.Missing = append(uerr.Missing, fmt.Sprintf("key usage lacks %s for %s", strings.Join(describeKeyUsage(e.ku), " or "), e.name))
+		}
+		if e.expect == ExpectCertSign && !(cert.BasicConstraintsValid && cert.IsCA) {
+			uerr.Missing = append(uerr.Missing, "basic constraints do not mark it as a CA")
+		}
+	}
+	if len(uerr.Missing) > 0 {
+		return uerr
+	}
+	return nil
+}
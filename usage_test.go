@@ -0,0 +1,31 @@
+package betterpem
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckUsage(t *testing.T) {
+	c := newTestChain(t)
+	if err := CheckUsage(c.leaf, ExpectServerAuth); err != nil {
+		t.Errorf("unexpected error for a server certificate %v", err)
+	}
+	if err := CheckUsage(c.intermediate, ExpectCertSign); err != nil {
+		t.Errorf("unexpected error for a CA certificate %v", err)
+	}
+	// with no extended key usage, the CA is only restricted by its key usage
+	if err := CheckUsage(c.intermediate, ExpectServerAuth); err == nil || len(err.(*UsageError).Missing) != 1 {
+		t.Errorf("expected only the key usage to be missing, got %v", err)
+	}
+
+	err := CheckUsage(c.leaf, ExpectClientAuth|ExpectCertSign)
+	var uerr *UsageError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("expected a UsageError, got %#v", err)
+	}
+	// client auth EKU, cert sign key usage, and CA
+	if len(uerr.Missing) != 3 {
+		t.Errorf("unexpected problems %v", uerr)
+	}
+	t.Log(uerr)
+}
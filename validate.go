@@ -0,0 +1,162 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Where a PEM block is and what it holds, without its contents
+type BlockInfo struct {
+	// The block's position among all the blocks found, counting from 0
+	Index int
+	// The type label of the block, such as "CERTIFICATE"
+	Type string
+	// The byte offset of the block's BEGIN line, counting from 0
+	Offset int
+	// The line number of the block's BEGIN line, counting from 1
+	Line int
+	// The size of the block's decoded contents in bytes
+	Size    int
+	Headers map[string]string
+}
+
+// Something wrong with the structure of PEM data, found by ValidatePEM
+type ValidationProblem struct {
+	// The byte offset the problem starts at, counting from 0
+	Offset int
+	// The line number the problem starts on, counting from 1
+	Line    int
+	Message string
+	// The error behind the problem, such as a *TruncatedBlockError for a
+	// block with no END line, if there is one
+	Err error
+}
+
+func (p *ValidationProblem) Error() string {
+	return fmt.Sprintf("line %d, offset %d: %s", p.Line, p.Offset, p.Message)
+}
+
+func (p *ValidationProblem) Unwrap() error {
+	return p.Err
+}
+
+// What ValidatePEM found
+type ValidationReport struct {
+	// Every block which decoded, in order
+	Blocks []BlockInfo
+	// Count of blocks by type label
+	Types    map[string]int
+	Problems []*ValidationProblem
+}
+
+var beginMarker = []byte("-----BEGIN ")
+
+// A BEGIN line with no END line after it, usually from a bad copy and
+// paste
+type TruncatedBlockError struct {
+	// The type label on the BEGIN line
+	Type string
+	// The byte offset of the BEGIN line, counting from 0
+	Offset int
+	// The line number of the BEGIN line, counting from 1
+	Line int
+}
+
+func (e *TruncatedBlockError) Error() string {
+	return fmt.Sprintf("%s block starting at line %d, offset %d has no END line", e.Type, e.Line, e.Offset)
+}
+
+// Whether a block should hold a DER value: it has a known label other
+// than OpenSSH's own format, and isn't encrypted
+func holdsDER(block *pem.Block) bool {
+	label := block.Type
+	if alias, ok := labelAliases[label]; ok {
+		label = alias
+	}
+	if _, ok := block.Headers["Proc-Type"]; ok {
+		return false
+	}
+	return knownLabels[label] && label != "OPENSSH PRIVATE KEY"
+}
+
+// Check the structure of PEM data without parsing what the blocks hold,
+// as a cheap check of uploads before anything is done with them.
+//
+// Every block is listed in the report.  BEGIN lines which don't start a
+// block that decodes are problems, as are blocks of the usual DER types
+// whose contents aren't a single complete DER value.  When the report
+// has problems, they are also returned joined together as the error.
+// Input with no blocks at all gives ErrNoPEMFound.
+func ValidatePEM(pemInt interface{}) (ValidationReport, error) {
+	report := ValidationReport{Blocks: []BlockInfo{}, Types: map[string]int{}, Problems: []*ValidationProblem{}}
+	input, err := intoBytes(pemInt)
+	if err != nil {
+		return report, err
+	}
+	scan := newScanner(input, false)
+	problem := func(offset int, err error, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, &ValidationProblem{
+			Offset:  offset,
+			Line:    scan.lineOf(offset),
+			Message: fmt.Sprintf(format, args...),
+			Err:     err,
+		})
+	}
+	// report BEGIN markers in input[from:to] which didn't start a block
+	undecoded := func(from, to int) {
+		for from < to {
+			i := bytes.Index(input[from:to], beginMarker)
+			if i < 0 {
+				return
+			}
+			if typ, ok := scan.truncatedAt(from + i); ok {
+				problem(from+i, &TruncatedBlockError{Type: typ, Offset: from + i, Line: scan.lineOf(from + i)}, "%s block has no END line", typ)
+			} else {
+				problem(from+i, nil, "BEGIN line does not start a block which decodes")
+			}
+			from += i + len(beginMarker)
+		}
+	}
+	for index := 0; ; index++ {
+		from := scan.pos
+		found, ok := scan.next()
+		if !ok {
+			undecoded(from, len(input))
+			break
+		}
+		der, offset := found.Block, found.Offset
+		undecoded(from, offset)
+		report.Blocks = append(report.Blocks, BlockInfo{
+			Index:   index,
+			Type:    der.Type,
+			Offset:  offset,
+			Line:    found.Line,
+			Size:    len(der.Bytes),
+			Headers: der.Headers,
+		})
+		report.Types[der.Type]++
+		if !holdsDER(der) {
+			continue
+		}
+		var raw asn1.RawValue
+		if trailing, err := asn1.Unmarshal(der.Bytes, &raw); err != nil {
+			problem(offset, err, "%s block does not hold valid DER: %v", der.Type, err)
+		} else if len(trailing) > 0 {
+			problem(offset, nil, "%s block has %d bytes after its DER", der.Type, len(trailing))
+		}
+	}
+	if len(report.Problems) > 0 {
+		errs := []error{}
+		for _, p := range report.Problems {
+			errs = append(errs, p)
+		}
+		return report, errors.Join(errs...)
+	}
+	if len(report.Blocks) == 0 {
+		return report, ErrNoPEMFound
+	}
+	return report, nil
+}
@@ -0,0 +1,52 @@
+package betterpem
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidatePEM(t *testing.T) {
+	pgp := pem.EncodeToMemory(&pem.Block{Type: "PGP PUBLIC KEY BLOCK", Bytes: []byte("not der")})
+	report, err := ValidatePEM(bytes.Join([][]byte{test_ca, test_rsakey, pgp}, nil))
+	if err != nil {
+		t.Fatalf("unexpected error validating pem %#v", err)
+	}
+	if len(report.Blocks) != 3 || report.Blocks[2].Type != "PGP PUBLIC KEY BLOCK" || report.Blocks[2].Size != 7 {
+		t.Errorf("unexpected blocks %#v", report.Blocks)
+	}
+	if report.Types["CERTIFICATE"] != 1 || report.Types["RSA PRIVATE KEY"] != 1 {
+		t.Errorf("unexpected types %v", report.Types)
+	}
+	if report.Blocks[1].Offset != len(test_ca) || report.Blocks[1].Line != newScanner(test_ca, false).lineOf(len(test_ca)) {
+		t.Errorf("unexpected position of the key %#v", report.Blocks[1])
+	}
+
+	// a truncated certificate, then a block which won't decode
+	der := mustDecode(t, test_ca)
+	short := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der[:len(der)-10]})
+	broken := []byte("-----BEGIN CERTIFICATE-----\n!!!!\n-----END CERTIFICATE-----\n")
+	report, err = ValidatePEM(bytes.Join([][]byte{short, broken}, nil))
+	if len(report.Problems) != 2 || report.Problems[0].Offset != 0 || report.Problems[1].Offset != len(short) {
+		t.Errorf("unexpected problems %v", report.Problems)
+	}
+	var problem *ValidationProblem
+	if !errors.As(err, &problem) {
+		t.Errorf("expected the problems as the error, got %#v", err)
+	}
+
+	report, err = ValidatePEM(append(bytes.Clone(test_ca), "-----BEGIN CERTIFICATE-----\nMIIB\n"...))
+	if len(report.Problems) != 1 || !strings.Contains(report.Problems[0].Message, "no END line") {
+		t.Errorf("expected a problem for the block with no END line, got %v", report.Problems)
+	}
+	var terr *TruncatedBlockError
+	if !errors.As(err, &terr) || terr.Offset != len(test_ca) {
+		t.Errorf("expected a *TruncatedBlockError, got %#v", err)
+	}
+
+	if _, err := ValidatePEM("nothing here"); err != ErrNoPEMFound {
+		t.Errorf("expected ErrNoPEMFound, got %#v", err)
+	}
+}
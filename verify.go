@@ -0,0 +1,132 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"errors"
+)
+
+// What happened when VerifyChain verified a bundle
+type VerifyResult struct {
+	// The certificate which was verified
+	Leaf *x509.Certificate
+	// Every chain from the leaf to a trusted root, if verification
+	// succeeded
+	Chains [][]*x509.Certificate
+	// The certificate which verification failed on, if it is known
+	FailingCert *x509.Certificate
+	// Why verification failed, in words
+	Reason string
+	// The error from x509, if verification failed
+	Err error
+}
+
+var invalidReasons = map[x509.InvalidReason]string{
+	x509.NotAuthorizedToSign:           "certificate is not authorized to sign other certificates",
+	x509.Expired:                       "certificate has expired or is not yet valid",
+	x509.CANotAuthorizedForThisName:    "issuer's name constraints do not permit this name",
+	x509.TooManyIntermediates:          "too many intermediates for the issuer's path length constraint",
+	x509.IncompatibleUsage:             "certificate's key usage does not permit the requested use",
+	x509.NameMismatch:                  "issuer name does not match subject of the issuing certificate",
+	x509.NameConstraintsWithoutSANs:    "issuer has name constraints but the certificate has no SANs",
+	x509.UnconstrainedName:             "certificate has a name the issuer's name constraints cannot check",
+	x509.TooManyConstraints:            "name constraint checks would take too long",
+	x509.CANotAuthorizedForExtKeyUsage: "issuer is not authorized for the certificate's extended key usage",
+}
+
+// Fill in the failing certificate and reason from an x509 error
+func (r *VerifyResult) explain(err error) {
+	r.Err = err
+	r.Reason = err.Error()
+	var invalid x509.CertificateInvalidError
+	var unknown x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	switch {
+	case errors.As(err, &invalid):
+		r.FailingCert = invalid.Cert
+		if reason, ok := invalidReasons[invalid.Reason]; ok {
+			r.Reason = reason
+		}
+	case errors.As(err, &unknown):
+		r.FailingCert = unknown.Cert
+		r.Reason = "certificate is signed by an unknown authority"
+	case errors.As(err, &hostname):
+		r.FailingCert = hostname.Certificate
+		r.Reason = "certificate is not valid for " + hostname.Host
+	}
+}
+
+// Verify the leaf certificate in a bundle against trusted roots.
+//
+// The leaf is found the same way OrderChain finds it, and every other
+// certificate in the bundle is offered as an intermediate.  If roots is
+// nil, opts.Roots is used, and if that is nil too, the system roots are.
+// If opts.CurrentTime is zero, the parsed PEMs' clock is used; see
+// WithClock.  The rest of opts is passed to x509.Certificate.Verify as is.
+//
+// The result says which certificate failed and why, instead of leaving
+// that to be dug out of the error.  The error is the same as the result's
+// Err, except when there is no leaf to verify, in which case the result
+// is nil.  The parsed PEMs are not consumed.
+func VerifyChain(pems ParsedPEMs, roots *x509.CertPool, opts x509.VerifyOptions) (*VerifyResult, error) {
+	certs := pems.certificates()
+	leaf, err := soleLeaf(certs)
+	if err != nil {
+		return nil, err
+	}
+	if roots != nil {
+		opts.Roots = roots
+	}
+	intermediates := x509.NewCertPool()
+	if opts.Intermediates != nil {
+		intermediates = opts.Intermediates.Clone()
+	}
+	for _, c := range certs {
+		if c != leaf {
+			intermediates.AddCert(c)
+		}
+	}
+	opts.Intermediates = intermediates
+	if opts.CurrentTime.IsZero() {
+		opts.CurrentTime = pems.now()
+	}
+
+	result := &VerifyResult{Leaf: leaf}
+	result.Chains, err = leaf.Verify(opts)
+	if err != nil {
+		result.explain(err)
+		return result, err
+	}
+	return result, nil
+}
+
+// Find every verified chain from a bundle's leaf to a root.
+//
+// The leaf is found the same way OrderChain finds it and the rest of the
+// bundle is split into roots and intermediates by Pools.  If systemRoots
+// is true, the system's roots are trusted as well as the bundle's.  Any
+// extended key usage is accepted.  There is more than one chain when an
+// intermediate has been cross-signed.  The parsed PEMs are not consumed.
+func BuildChains(pems ParsedPEMs, systemRoots bool) ([][]*x509.Certificate, error) {
+	leaf, err := soleLeaf(pems.certificates())
+	if err != nil {
+		return nil, err
+	}
+	roots, intermediates := pems.Pools()
+	if systemRoots {
+		system, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, err
+		}
+		_, _, bundleRoots := pems.Partition()
+		for _, c := range bundleRoots {
+			system.AddCert(c)
+		}
+		roots = system
+	}
+	return leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		CurrentTime:   pems.now(),
+	})
+}
@@ -0,0 +1,78 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestVerifyChain(t *testing.T) {
+	c := newTestChain(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(c.root)
+
+	pems, err := ParsePEMs(c.pem(t, c.intermediate, c.leaf))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	result, err := VerifyChain(pems, roots, x509.VerifyOptions{DNSName: "leaf.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error verifying %#v", err)
+	}
+	if result.Leaf != pems.certificates()[1] || len(result.Chains) != 1 || len(result.Chains[0]) != 3 {
+		t.Errorf("unexpected result %#v", result)
+	}
+
+	result, err = VerifyChain(pems, roots, x509.VerifyOptions{DNSName: "wrong.example.com"})
+	if err == nil || result.FailingCert == nil || !result.FailingCert.Equal(c.leaf) {
+		t.Errorf("expected a hostname failure on the leaf, got %#v", result)
+	}
+
+	result, err = VerifyChain(pems, roots, x509.VerifyOptions{CurrentTime: time.Now().Add(48 * time.Hour)})
+	if err == nil || result.Reason != "certificate has expired or is not yet valid" {
+		t.Errorf("expected an expiry failure, got %#v", result)
+	}
+
+	other := newTestChain(t)
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(other.root)
+	result, err = VerifyChain(pems, otherRoots, x509.VerifyOptions{})
+	if err == nil || result.Reason != "certificate is signed by an unknown authority" {
+		t.Errorf("expected an unknown authority failure, got %#v", result)
+	}
+}
+
+func TestBuildChains(t *testing.T) {
+	c := newTestChain(t)
+	other := newTestChain(t)
+	cross := issueTestCertForKey(t, &x509.Certificate{
+		Subject:               c.intermediate.Subject,
+		SubjectKeyId:          c.intermediate.SubjectKeyId,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, other.root, other.rootKey, c.intermediateKey)
+
+	pems, err := ParsePEMs(c.pem(t, c.leaf, c.intermediate, cross, c.root, other.root))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	chains, err := BuildChains(pems, false)
+	if err != nil {
+		t.Fatalf("unexpected error building chains %#v", err)
+	}
+	if len(chains) != 2 {
+		t.Errorf("expected a chain through each root, got %d", len(chains))
+	}
+	if _, err := BuildChains(pems, true); err != nil {
+		t.Errorf("adding system roots should not break the chains: %v", err)
+	}
+
+	orphan, err := ParsePEMs(c.pem(t, c.leaf, c.intermediate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuildChains(orphan, false); err == nil {
+		t.Error("expected an error without a root")
+	}
+}
@@ -0,0 +1,78 @@
+package betterpem
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+const (
+	FindingDuplicateBlock     FindingKind = "duplicate-block"
+	FindingUnmatchedKey       FindingKind = "unmatched-key"
+	FindingDeprecatedEncoding FindingKind = "deprecated-encoding"
+)
+
+// Labels which are still parsed but shouldn't be written any more, with
+// what to use instead
+var deprecatedLabels = map[string]string{
+	"NEW CERTIFICATE REQUEST": "CERTIFICATE REQUEST",
+}
+
+// Find the soft problems with what ParsePEMs parsed.  indices holds the
+// index of the block each object came from.
+func warningsFor(objs []interface{}, blocks []*pem.Block, indices []int, now time.Time) []Finding {
+	warnings := []Finding{}
+	add := func(kind FindingKind, i int, format string, args ...interface{}) {
+		warnings = append(warnings, Finding{
+			Kind:    kind,
+			Object:  objs[i],
+			Message: fmt.Sprintf("block %d: %s ", indices[i], describeObject(objs[i])) + fmt.Sprintf(format, args...),
+		})
+	}
+	certs := []*x509.Certificate{}
+	for _, obj := range objs {
+		if c, ok := obj.(*x509.Certificate); ok {
+			certs = append(certs, c)
+		}
+	}
+	seen := map[string]int{}
+	for i, obj := range objs {
+		id := identityOf(obj, blocks[i])
+		if first, ok := seen[id]; ok {
+			add(FindingDuplicateBlock, i, "duplicates block %d", indices[first])
+		} else {
+			seen[id] = i
+		}
+		if instead, ok := deprecatedLabels[blocks[i].Type]; ok {
+			add(FindingDeprecatedEncoding, i, "is labelled %q rather than %q", blocks[i].Type, instead)
+		}
+		if _, ok := blocks[i].Headers["Proc-Type"]; ok {
+			add(FindingDeprecatedEncoding, i, "uses RFC 1421 encryption headers")
+		}
+		if c, ok := obj.(*x509.Certificate); ok && now.After(c.NotAfter) {
+			add(FindingExpired, i, "expired at %s", c.NotAfter.UTC().Format(time.RFC3339))
+		}
+		if isPrivateKey(obj) {
+			matched := false
+			for _, c := range certs {
+				matched = matched || certMatchesKey(c, obj)
+			}
+			if !matched && len(certs) > 0 {
+				add(FindingUnmatchedKey, i, "has no matching certificate")
+			}
+		}
+	}
+	return warnings
+}
+
+// Return the soft problems found while parsing, in order: expired
+// certificates, duplicate blocks, private keys without a matching
+// certificate, and deprecated encodings.  None of them stop an object
+// from being used.
+//
+// Private keys are only unmatched when there are certificates to match
+// them with.  The warnings are unaffected by consuming objects.
+func (p *ParsedPEMs) Warnings() []Finding {
+	return p.warnings
+}
@@ -0,0 +1,49 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestWarnings(t *testing.T) {
+	c := newTestChain(t)
+	expired, _ := issueTestCert(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "expired"},
+		NotBefore: time.Now().Add(-48 * time.Hour),
+		NotAfter:  time.Now().Add(-24 * time.Hour),
+	}, c.intermediate, c.intermediateKey)
+	_, strayKey := issueTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "stray"}}, nil, nil)
+	csr := pem.EncodeToMemory(&pem.Block{Type: "NEW CERTIFICATE REQUEST", Bytes: mustDecode(t, test_rsareq)})
+
+	clean, err := ParsePEMs(c.pem(t, c.leaf, c.intermediate, c.leafKey))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	if w := clean.Warnings(); len(w) != 0 {
+		t.Errorf("unexpected warnings %v", w)
+	}
+
+	objs, err := ParsePEMs(bytes.Join([][]byte{c.pem(t, c.leaf, expired, c.leaf, strayKey), csr}, nil))
+	if err != nil {
+		t.Fatalf("unexpected error parsing pem %#v", err)
+	}
+	kinds := findingKinds(objs.Warnings())
+	want := map[FindingKind]int{FindingExpired: 1, FindingDuplicateBlock: 1, FindingUnmatchedKey: 1, FindingDeprecatedEncoding: 1}
+	for kind, n := range want {
+		if kinds[kind] != n {
+			t.Errorf("expected %d %s warnings, got %d", n, kind, kinds[kind])
+		}
+	}
+}
+
+func mustDecode(t *testing.T, pemBytes []byte) []byte {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		t.Fatal("no pem block found")
+	}
+	return block.Bytes
+}
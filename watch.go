@@ -0,0 +1,125 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// How often a fileWatcher looks at its files, at most
+const watchInterval = time.Second
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+type fileWatcher struct {
+	paths    []string
+	interval time.Duration
+	keyPair  KeyPair
+
+	// held while checking the files so only one handshake does it
+	mu        sync.Mutex
+	lastCheck time.Time
+	stamps    []fileStamp
+}
+
+func (w *fileWatcher) stat() ([]fileStamp, error) {
+	ret := make([]fileStamp, len(w.paths))
+	for i, path := range w.paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = fileStamp{fi.ModTime(), fi.Size()}
+	}
+	return ret, nil
+}
+
+func (w *fileWatcher) load() error {
+	stamps, err := w.stat()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, path := range w.paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	if err := w.keyPair.ReloadFrom(buf.Bytes()); err != nil {
+		return err
+	}
+	w.stamps = stamps
+	return nil
+}
+
+// Reload the files if it's been long enough since they were last looked
+// at and they've changed.  Failed reloads keep the old certificate and
+// are retried at the next check.
+func (w *fileWatcher) maybeReload() {
+	if !w.mu.TryLock() {
+		return
+	}
+	defer w.mu.Unlock()
+	now := time.Now()
+	if now.Sub(w.lastCheck) < w.interval {
+		return
+	}
+	w.lastCheck = now
+	stamps, err := w.stat()
+	if err != nil {
+		return
+	}
+	for i := range stamps {
+		if stamps[i] != w.stamps[i] {
+			w.load()
+			return
+		}
+	}
+}
+
+func (w *fileWatcher) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	w.maybeReload()
+	return w.keyPair.Load(), nil
+}
+
+func newFileWatcher(certPath, keyPath string, interval time.Duration) (*fileWatcher, error) {
+	w := &fileWatcher{paths: []string{certPath}, interval: interval}
+	if keyPath != "" && keyPath != certPath {
+		w.paths = append(w.paths, keyPath)
+	}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	w.lastCheck = time.Now()
+	return w, nil
+}
+
+// Build a tls.Config.GetCertificate callback which serves the identity in
+// certPath and keyPath, picking up changes to the files without a
+// restart.
+//
+// The files are parsed together, so the key may be in either of them and
+// keyPath may be empty or the same as certPath.  See
+// ParsedPEMs.TLSCertificate for how the identity is found.
+//
+// The files are checked for changes at most once a second, during
+// handshakes, and reparsed when their size or modification time changes.
+// The new certificate is swapped in atomically.  If a reload fails, such
+// as when the certificate has been written but not yet its key, the old
+// certificate is kept and the reload is retried at the next check.  An
+// error is only returned if the files can't be loaded to begin with.
+func WatchFiles(certPath, keyPath string) (func(*tls.ClientHelloInfo) (*tls.Certificate, error), error) {
+	w, err := newFileWatcher(certPath, keyPath, watchInterval)
+	if err != nil {
+		return nil, err
+	}
+	return w.getCertificate, nil
+}
@@ -0,0 +1,56 @@
+package betterpem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+	c := newTestChain(t)
+	if err := os.WriteFile(certPath, c.pem(t, c.leaf, c.intermediate), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, c.pem(t, c.leafKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := WatchFiles(certPath, keyPath); err != nil {
+		t.Fatalf("unexpected error watching files %#v", err)
+	}
+	w, err := newFileWatcher(certPath, keyPath, 0)
+	if err != nil {
+		t.Fatalf("unexpected error watching files %#v", err)
+	}
+	got, err := w.getCertificate(nil)
+	if err != nil || !got.Leaf.Equal(c.leaf) {
+		t.Fatalf("unexpected certificate %v", err)
+	}
+
+	// a half-finished renewal keeps the old certificate
+	renewed := newTestChain(t)
+	if err := os.WriteFile(certPath, renewed.pem(t, renewed.leaf, renewed.intermediate), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certPath, later, later)
+	if got, _ := w.getCertificate(nil); !got.Leaf.Equal(c.leaf) {
+		t.Error("a mismatched certificate and key replaced the old certificate")
+	}
+
+	if err := os.WriteFile(keyPath, renewed.pem(t, renewed.leafKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(keyPath, later, later)
+	if got, _ := w.getCertificate(nil); !got.Leaf.Equal(renewed.leaf) {
+		t.Error("the renewed certificate was not picked up")
+	}
+
+	if _, err := WatchFiles(filepath.Join(dir, "missing.crt"), keyPath); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
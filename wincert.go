@@ -0,0 +1,189 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+var ErrNotWindowsCert = errors.New("data is not a certificate, PKCS #7 bundle, or serialized certificate store")
+
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+// The magic number of a serialized certificate store (.sst), "CERT"
+const capiStoreMagic = 0x54524543
+
+// CAPI property IDs of the elements holding encoded objects
+const (
+	capiCertPropID = 32
+	capiCRLPropID  = 33
+)
+
+// Add the certificates and CRLs from a DER PKCS #7 SignedData, as in a
+// .p7b file
+func addPKCS7(pems *ParsedPEMs, der []byte) error {
+	input := cryptobyte.String(der)
+	var contentInfo, signedData, certs cryptobyte.String
+	var contentType asn1.ObjectIdentifier
+	if !input.ReadASN1(&contentInfo, cbasn1.SEQUENCE) ||
+		!contentInfo.ReadASN1ObjectIdentifier(&contentType) ||
+		!contentType.Equal(oidSignedData) ||
+		!contentInfo.ReadASN1(&contentInfo, cbasn1.Tag(0).Constructed().ContextSpecific()) ||
+		!contentInfo.ReadASN1(&signedData, cbasn1.SEQUENCE) ||
+		// version, digest algorithms, and the signed content
+		!signedData.SkipASN1(cbasn1.INTEGER) ||
+		!signedData.SkipASN1(cbasn1.SET) ||
+		!signedData.SkipASN1(cbasn1.SEQUENCE) {
+		return ErrNotWindowsCert
+	}
+	var present bool
+	if !signedData.ReadOptionalASN1(&certs, &present, cbasn1.Tag(0).Constructed().ContextSpecific()) {
+		return ErrNotWindowsCert
+	}
+	for !certs.Empty() {
+		var raw cryptobyte.String
+		if !certs.ReadASN1Element(&raw, cbasn1.SEQUENCE) {
+			return ErrNotWindowsCert
+		}
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		pems.add(cert, nil)
+	}
+	var crls cryptobyte.String
+	if !signedData.ReadOptionalASN1(&crls, &present, cbasn1.Tag(1).Constructed().ContextSpecific()) {
+		return ErrNotWindowsCert
+	}
+	for !crls.Empty() {
+		var raw cryptobyte.String
+		if !crls.ReadASN1Element(&raw, cbasn1.SEQUENCE) {
+			return ErrNotWindowsCert
+		}
+		crl, err := x509.ParseRevocationList(raw)
+		if err != nil {
+			return err
+		}
+		pems.add(crl, nil)
+	}
+	return nil
+}
+
+// Add the certificates and CRLs from CAPI serialized store elements, as
+// in a .sst file or a certificate's Blob value in the registry
+func addCAPIElements(pems *ParsedPEMs, data []byte) error {
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return ErrNotWindowsCert
+		}
+		propID := binary.LittleEndian.Uint32(data)
+		length := binary.LittleEndian.Uint32(data[8:])
+		data = data[12:]
+		if uint64(length) > uint64(len(data)) {
+			return ErrNotWindowsCert
+		}
+		value := data[:length]
+		data = data[length:]
+		switch propID {
+		case 0:
+			// the end of a store
+			return nil
+		case capiCertPropID:
+			cert, err := x509.ParseCertificate(value)
+			if err != nil {
+				return err
+			}
+			pems.add(cert, nil)
+		case capiCRLPropID:
+			crl, err := x509.ParseRevocationList(value)
+			if err != nil {
+				return err
+			}
+			pems.add(crl, nil)
+		}
+		// other properties, like friendly names and hashes, are skipped
+	}
+	return nil
+}
+
+// Add the objects from binary data of any of the kinds ImportWindowsCert
+// takes
+func addWindowsDER(pems *ParsedPEMs, data []byte) error {
+	if len(data) >= 8 && binary.LittleEndian.Uint32(data) == 0 && binary.LittleEndian.Uint32(data[4:]) == capiStoreMagic {
+		return addCAPIElements(pems, data[8:])
+	}
+	if len(data) == 0 || data[0] != 0x30 {
+		// DER starts with a SEQUENCE, so this is a bare element list
+		return addCAPIElements(pems, data)
+	}
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		pems.add(cert, nil)
+		return nil
+	}
+	return addPKCS7(pems, data)
+}
+
+// Convert UTF-16 text with a byte order mark, as Windows tools sometimes
+// write, to UTF-8
+func fromUTF16(data []byte) []byte {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		order = binary.BigEndian
+	default:
+		return data
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for i := 2; i+1 < len(data); i += 2 {
+		units = append(units, order.Uint16(data[i:]))
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// Import certificates exported by Windows certificate manager or
+// PowerShell into ParsedPEMs, in the order they appear.
+//
+// The data may be a DER or base64 encoded .cer file, a DER or base64
+// encoded .p7b file, which gives its certificates and any CRLs, or a
+// serialized certificate store (.sst) or registry certificate blob.
+// Base64 files may be PEM, with CRLF line endings or in UTF-16, or bare
+// base64.  Data which is none of these gives ErrNotWindowsCert.
+func ImportWindowsCert(data []byte) (ParsedPEMs, error) {
+	ret := ParsedPEMs{}
+	data = fromUTF16(data)
+	if bytes.Contains(data, beginMarker) {
+		scan := newScanner(data, false)
+		for {
+			found, ok := scan.next()
+			if !ok {
+				break
+			}
+			if err := addWindowsDER(&ret, found.Bytes); err != nil {
+				return ParsedPEMs{}, err
+			}
+		}
+		if ret.Length() == 0 {
+			return ParsedPEMs{}, ErrNotWindowsCert
+		}
+		return ret, nil
+	}
+	if der, err := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(data), nil))); err == nil && len(der) > 0 {
+		data = der
+	}
+	if err := addWindowsDER(&ret, data); err != nil {
+		return ParsedPEMs{}, err
+	}
+	if ret.Length() == 0 {
+		return ParsedPEMs{}, ErrNotWindowsCert
+	}
+	return ret, nil
+}
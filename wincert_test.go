@@ -0,0 +1,103 @@
+package betterpem
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"testing"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// Build a degenerate PKCS #7 SignedData holding only certificates, like
+// a .p7b export
+func encodeTestPKCS7(t *testing.T, certs ...*x509.Certificate) []byte {
+	var b cryptobyte.Builder
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1ObjectIdentifier(oidSignedData)
+		b.AddASN1(cbasn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+			b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1Int64(1)
+				b.AddASN1(cbasn1.SET, func(b *cryptobyte.Builder) {})
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					// id-data
+					b.AddASN1ObjectIdentifier([]int{1, 2, 840, 113549, 1, 7, 1})
+				})
+				b.AddASN1(cbasn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+					for _, c := range certs {
+						b.AddBytes(c.Raw)
+					}
+				})
+				b.AddASN1(cbasn1.SET, func(b *cryptobyte.Builder) {})
+			})
+		})
+	})
+	der, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func capiElement(propID uint32, value []byte) []byte {
+	ret := binary.LittleEndian.AppendUint32(nil, propID)
+	ret = binary.LittleEndian.AppendUint32(ret, 1)
+	ret = binary.LittleEndian.AppendUint32(ret, uint32(len(value)))
+	return append(ret, value...)
+}
+
+func TestImportWindowsCert(t *testing.T) {
+	c := newTestChain(t)
+	p7b := encodeTestPKCS7(t, c.leaf, c.intermediate, c.root)
+	crlf := bytes.ReplaceAll(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.leaf.Raw}), []byte("\n"), []byte("\r\n"))
+	utf16le := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(string(crlf))) {
+		utf16le = binary.LittleEndian.AppendUint16(utf16le, u)
+	}
+	// a friendly name, then the certificate
+	blob := append(capiElement(11, []byte("t\x00e\x00s\x00t\x00\x00\x00")), capiElement(capiCertPropID, c.leaf.Raw)...)
+	sst := binary.LittleEndian.AppendUint32(nil, 0)
+	sst = binary.LittleEndian.AppendUint32(sst, capiStoreMagic)
+	sst = append(sst, capiElement(capiCertPropID, c.leaf.Raw)...)
+	sst = append(sst, capiElement(capiCertPropID, c.root.Raw)...)
+	sst = append(sst, capiElement(0, nil)...)
+
+	for name, test := range map[string]struct {
+		data []byte
+		want []*x509.Certificate
+	}{
+		"der cer":     {c.leaf.Raw, []*x509.Certificate{c.leaf}},
+		"base64 cer":  {crlf, []*x509.Certificate{c.leaf}},
+		"utf-16 cer":  {utf16le, []*x509.Certificate{c.leaf}},
+		"bare base64": {[]byte(base64.StdEncoding.EncodeToString(c.leaf.Raw) + "\r\n"), []*x509.Certificate{c.leaf}},
+		"der p7b":     {p7b, []*x509.Certificate{c.leaf, c.intermediate, c.root}},
+		"base64 p7b":  {pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: p7b}), []*x509.Certificate{c.leaf, c.intermediate, c.root}},
+		"blob":        {blob, []*x509.Certificate{c.leaf}},
+		"sst":         {sst, []*x509.Certificate{c.leaf, c.root}},
+	} {
+		pems, err := ImportWindowsCert(test.data)
+		if err != nil {
+			t.Errorf("%s: unexpected error importing %#v", name, err)
+			continue
+		}
+		if pems.Length() != len(test.want) {
+			t.Errorf("%s: expected %d certificates, got %d objects", name, len(test.want), pems.Length())
+			continue
+		}
+		for _, want := range test.want {
+			if !pems.MustCertificate().Equal(want) {
+				t.Errorf("%s: expected %s next", name, want.Subject)
+			}
+		}
+	}
+
+	for _, bad := range [][]byte{nil, []byte("not a certificate"), {0x30, 0x03, 0x02, 0x01, 0x01}} {
+		if _, err := ImportWindowsCert(bad); err != ErrNotWindowsCert {
+			t.Errorf("expected ErrNotWindowsCert for %q, got %#v", bad, err)
+		}
+	}
+}